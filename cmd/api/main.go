@@ -1,19 +1,157 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata"
 
+	"github.com/King0625/golang-todolist/internal/authz"
 	"github.com/King0625/golang-todolist/internal/db"
 	"github.com/King0625/golang-todolist/internal/dto"
 	"github.com/King0625/golang-todolist/internal/handler"
 	"github.com/King0625/golang-todolist/internal/middleware"
+	"github.com/King0625/golang-todolist/internal/model"
 	"github.com/King0625/golang-todolist/internal/repository"
 	"github.com/King0625/golang-todolist/internal/service"
+	"github.com/King0625/golang-todolist/pkg/jobs"
+	"github.com/King0625/golang-todolist/pkg/mailer"
+	"github.com/King0625/golang-todolist/pkg/oidc"
+	"github.com/King0625/golang-todolist/pkg/utils"
 	"github.com/joho/godotenv"
 )
 
+// newMailer sends real emails with MAIL_DRIVER=smtp and only logs them (or
+// writes them to MAIL_LOG_DIR) otherwise.
+func newMailer() mailer.Mailer {
+	from := os.Getenv("MAIL_FROM")
+	if os.Getenv("MAIL_DRIVER") == "smtp" {
+		return &mailer.SMTPMailer{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     os.Getenv("SMTP_PORT"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     from,
+		}
+	}
+
+	return &mailer.LogMailer{From: from, Dir: os.Getenv("MAIL_LOG_DIR")}
+}
+
+func verificationPolicy() model.VerificationPolicy {
+	graceDays, _ := strconv.Atoi(os.Getenv("UNVERIFIED_GRACE_DAYS"))
+	return model.VerificationPolicy{
+		BlockLogin:  os.Getenv("UNVERIFIED_LOGIN_POLICY") == "block",
+		GracePeriod: time.Duration(graceDays) * 24 * time.Hour,
+	}
+}
+
+// jwtKeySet loads the signing keys from JWT_KEYS_DIR. JWT_SIGNING_KID picks
+// the key new tokens are signed with and may be left out when there is only
+// one private key. Outside production a throwaway key is generated when no
+// directory is set, so tokens do not survive a restart.
+func jwtKeySet(env string) (*utils.JWTKeySet, error) {
+	issuer := os.Getenv("JWT_ISSUER")
+	if issuer == "" {
+		issuer = "golang-todolist"
+	}
+	audience := os.Getenv("JWT_AUDIENCE")
+	if audience == "" {
+		audience = "golang-todolist"
+	}
+
+	dir := os.Getenv("JWT_KEYS_DIR")
+	if dir == "" {
+		if env == "production" {
+			return nil, errors.New("JWT_KEYS_DIR is not set")
+		}
+
+		log.Println("JWT_KEYS_DIR is not set, signing tokens with a temporary key")
+		key, err := utils.GenerateJWTKey("dev")
+		if err != nil {
+			return nil, err
+		}
+		return utils.NewJWTKeySet(issuer, audience, key.ID, key)
+	}
+
+	keys, err := utils.LoadJWTKeys(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	signingKID := os.Getenv("JWT_SIGNING_KID")
+	if signingKID == "" && len(keys) == 1 {
+		signingKID = keys[0].ID
+	}
+
+	return utils.NewJWTKeySet(issuer, audience, signingKID, keys...)
+}
+
+// oidcProviders reads the identity providers listed in OIDC_PROVIDERS, e.g.
+// "google,corp", each configured by OIDC_<NAME>_ISSUER, _CLIENT_ID,
+// _CLIENT_SECRET and optionally _SCOPES.
+func oidcProviders() []*oidc.Provider {
+	redirectURL := os.Getenv("APP_BASE_URL") + "/auth/oidc/callback"
+
+	var providers []*oidc.Provider
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		providers = append(providers, oidc.NewProvider(oidc.Config{
+			Name:         name,
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  redirectURL,
+			Scopes:       strings.Fields(os.Getenv(prefix + "SCOPES")),
+		}, &http.Client{Timeout: 10 * time.Second}, nil))
+	}
+
+	return providers
+}
+
+// loginAttemptStore keeps failed login counters in memory unless
+// LOGIN_ATTEMPT_STORE=mysql, which is needed when running several instances.
+func loginAttemptStore(db *sql.DB) repository.LoginAttemptRepository {
+	if os.Getenv("LOGIN_ATTEMPT_STORE") == "mysql" {
+		return repository.NewLoginAttemptRepository(db)
+	}
+	return repository.NewMemoryLoginAttemptRepository()
+}
+
+// adminEmails are the accounts made admins on startup, listed comma separated
+// in ADMIN_EMAILS. Further admins can then be appointed through the admin API.
+func adminEmails() []string {
+	var emails []string
+	for _, email := range strings.Split(os.Getenv("ADMIN_EMAILS"), ",") {
+		if email = strings.TrimSpace(email); email != "" {
+			emails = append(emails, email)
+		}
+	}
+	return emails
+}
+
+func lockoutPolicy() model.LockoutPolicy {
+	policy := model.DefaultLockoutPolicy
+	if threshold, err := strconv.Atoi(os.Getenv("LOGIN_LOCKOUT_THRESHOLD")); err == nil && threshold > 0 {
+		policy.AccountThreshold = threshold
+	}
+	if minutes, err := strconv.Atoi(os.Getenv("LOGIN_LOCKOUT_MINUTES")); err == nil && minutes > 0 {
+		policy.LockoutDuration = time.Duration(minutes) * time.Minute
+	}
+	return policy
+}
+
 func main() {
 	env := os.Getenv("ENV")
 	if env != "production" {
@@ -34,31 +172,371 @@ func main() {
 	}
 
 	userRepo := repository.NewUserRepository(mysqlInstance)
-	userService := service.NewUserService(userRepo)
-	userHandler := handler.NewUserHandler(userService)
+	userTokenRepo := repository.NewUserTokenRepository(mysqlInstance)
+	tokenRepo := repository.NewTokenRepository(mysqlInstance)
+
+	loginGuard := service.NewLoginGuard(loginAttemptStore(mysqlInstance), lockoutPolicy(), nil)
+
+	mail := newMailer()
+	auditLog := service.NewAuditLog(repository.NewAuditRepository(mysqlInstance))
+	auditHandler := handler.NewAuditHandler(auditLog)
+	userService := service.NewUserService(userRepo, userTokenRepo, tokenRepo, mail, loginGuard, auditLog, service.UserServiceConfig{
+		BaseURL:      os.Getenv("APP_BASE_URL"),
+		Verification: verificationPolicy(),
+	})
+	jwtKeys, err := jwtKeySet(env)
+	if err != nil {
+		log.Fatalf("load jwt keys error: %v", err)
+	}
+	middleware.UseAccessTokenParser(jwtKeys)
+	jwksHandler := handler.NewJWKSHandler(jwtKeys)
+
+	authService := service.NewAuthService(tokenRepo, userRepo, jwtKeys)
+	middleware.UseTokenDenylist(authService)
+	middleware.UseTokenGenerations(userService)
+
+	adminService := service.NewAdminService(userRepo, tokenRepo, loginGuard)
+	middleware.UseRoleResolver(adminService)
+	adminHandler := handler.NewAdminHandler(adminService)
+	if promoted, err := adminService.PromoteAdmins(context.Background(), adminEmails()); err != nil {
+		log.Fatalf("promote admins error: %v", err)
+	} else if promoted > 0 {
+		log.Printf("promoted %d users to admin", promoted)
+	}
+
+	shareRepo := repository.NewShareRepository(mysqlInstance)
+	shareService := service.NewShareService(shareRepo, userRepo, mail, service.ShareServiceConfig{
+		BaseURL: os.Getenv("APP_BASE_URL"),
+	})
+	workspaceRepo := repository.NewWorkspaceRepository(mysqlInstance)
+	workspaceService := service.NewWorkspaceService(workspaceRepo, userRepo)
+	middleware.UseWorkspaceResolver(workspaceService)
+	workspaceHandler := handler.NewWorkspaceHandler(workspaceService)
+	policy := authz.NewWorkspacePolicy(workspaceRepo, authz.NewSharePolicy(shareRepo))
+
+	twoFactorService := service.NewTwoFactorService(userRepo, repository.NewRecoveryCodeRepository(mysqlInstance), userTokenRepo, loginGuard, service.TwoFactorConfig{
+		Issuer: os.Getenv("TOTP_ISSUER"),
+	})
+	userHandler := handler.NewUserHandler(userService, authService, twoFactorService)
+	twoFactorHandler := handler.NewTwoFactorHandler(twoFactorService)
+
+	identityRepo := repository.NewUserIdentityRepository(mysqlInstance)
+	oidcService := service.NewOIDCService(oidcProviders(),
+		repository.NewOIDCStateRepository(mysqlInstance),
+		identityRepo,
+		userRepo,
+	)
+	oidcHandler := handler.NewOIDCHandler(oidcService, authService, twoFactorService)
+
+	patRepo := repository.NewPersonalAccessTokenRepository(mysqlInstance)
+	patService := service.NewPersonalAccessTokenService(patRepo)
+	middleware.UsePersonalAccessTokens(patService)
+	patHandler := handler.NewPersonalAccessTokenHandler(patService, policy)
+
+	projectRepo := repository.NewProjectRepository(mysqlInstance)
+	projectService := service.NewProjectService(projectRepo)
+
+	itemRepo := repository.NewChecklistItemRepository(mysqlInstance)
+	itemService := service.NewChecklistItemService(itemRepo)
 
 	todoRepo := repository.NewTodoRepository(mysqlInstance)
-	todoService := service.NewTodoService(todoRepo)
-	todoHandler := handler.NewTodoHandler(todoService)
+	todoEventRepo := repository.NewTodoEventRepository(mysqlInstance)
+	todoService := service.NewTodoService(todoRepo, workspaceRepo, todoEventRepo, auditLog, model.DonePolicy(os.Getenv("TODO_DONE_POLICY")))
+	todoHandler := handler.NewTodoHandler(todoService, projectService, policy)
+	handler.RequireIfMatch(os.Getenv("REQUIRE_IF_MATCH") == "true")
+	projectHandler := handler.NewProjectHandler(projectService, todoService, policy)
+	itemHandler := handler.NewChecklistItemHandler(itemService, todoService, policy)
+
+	tagRepo := repository.NewTagRepository(mysqlInstance)
+	tagService := service.NewTagService(tagRepo)
+	tagHandler := handler.NewTagHandler(tagService, todoService, policy)
+	shareHandler := handler.NewShareHandler(shareService, todoService, projectService, policy)
+	commentService := service.NewCommentService(repository.NewCommentRepository(mysqlInstance), todoEventRepo, userRepo, policy, mail, service.CommentServiceConfig{
+		BaseURL: os.Getenv("APP_BASE_URL"),
+	})
+	commentHandler := handler.NewCommentHandler(commentService, todoService, policy)
+	revisionService := service.NewRevisionService(repository.NewTodoRevisionRepository(mysqlInstance), todoService)
+	revisionHandler := handler.NewRevisionHandler(revisionService, todoService, policy)
+
+	jobRunner := jobs.NewRunner(2, 100)
+	jobRunner.Start(context.Background())
+	exportService := service.NewDataExportService(repository.NewDataExportRepository(mysqlInstance), service.DataExportSources{
+		Users:      userRepo,
+		Todos:      todoRepo,
+		Items:      itemRepo,
+		Tags:       tagRepo,
+		Projects:   projectRepo,
+		Tokens:     patRepo,
+		Identities: identityRepo,
+	}, jobRunner)
+	exportHandler := handler.NewDataExportHandler(exportService, policy)
+
+	retentionDays, err := strconv.Atoi(os.Getenv("TRASH_RETENTION_DAYS"))
+	if err != nil || retentionDays < 1 {
+		retentionDays = 30
+	}
+	retention := time.Duration(retentionDays) * 24 * time.Hour
+	go service.RunPeriodically(context.Background(), "purge trash", time.Hour, func(ctx context.Context) (int64, error) {
+		return todoService.PurgeTrash(ctx, retention)
+	})
+	revisionDays, err := strconv.Atoi(os.Getenv("REVISION_RETENTION_DAYS"))
+	if err != nil || revisionDays < 1 {
+		revisionDays = 90
+	}
+	revisionRetention := time.Duration(revisionDays) * 24 * time.Hour
+	go service.RunPeriodically(context.Background(), "purge old revisions", time.Hour, func(ctx context.Context) (int64, error) {
+		return revisionService.PurgeRevisions(ctx, revisionRetention)
+	})
+	go service.RunPeriodically(context.Background(), "delete expired tokens", time.Hour, authService.DeleteExpiredTokens)
+	go service.RunPeriodically(context.Background(), "delete expired user tokens", time.Hour, userService.DeleteExpiredTokens)
+	go service.RunPeriodically(context.Background(), "delete expired oidc states", time.Hour, oidcService.DeleteExpiredStates)
+	go service.RunPeriodically(context.Background(), "delete stale login attempts", time.Hour, loginGuard.DeleteStale)
+	go service.RunPeriodically(context.Background(), "process pending data exports", time.Minute, exportService.ProcessPending)
+	go service.RunPeriodically(context.Background(), "delete expired data exports", time.Hour, exportService.DeleteExpired)
 
 	r := http.NewServeMux()
 
+	// personal access tokens only reach the routes their scopes allow; login
+	// sessions pass every scope check
+	todosRead := middleware.RequireScope(model.ScopeTodosRead)
+	todosWrite := middleware.RequireScope(model.ScopeTodosWrite)
+	userRead := middleware.RequireScope(model.ScopeUserRead)
+	adminOnly := middleware.RequireRole(model.RoleAdmin)
+
+	r.Handle("GET /.well-known/jwks.json", http.HandlerFunc(jwksHandler.GetJWKS))
+
+	r.Handle("GET /auth/oidc/login", http.HandlerFunc(oidcHandler.Login))
+	r.Handle("GET /auth/oidc/callback", http.HandlerFunc(oidcHandler.Callback))
+
 	r.Handle("POST /users/register", middleware.ValidationMiddleware[dto.RegisterPayload](http.HandlerFunc(userHandler.Register)))
 	r.Handle("POST /users/login", middleware.ValidationMiddleware[dto.LoginPayload](http.HandlerFunc(userHandler.Login)))
-	r.Handle("GET /users/me", middleware.JWTAuth(http.HandlerFunc(userHandler.GetUserData)))
+	r.Handle("POST /users/login/2fa", middleware.ValidationMiddleware[dto.TwoFactorLoginPayload](http.HandlerFunc(userHandler.CompleteTwoFactorLogin)))
+	r.Handle("POST /users/token/refresh", middleware.ValidationMiddleware[dto.RefreshTokenPayload](http.HandlerFunc(userHandler.RefreshToken)))
+	r.Handle("POST /users/logout", middleware.Chain(http.HandlerFunc(userHandler.Logout),
+		middleware.JWTAuth,
+		middleware.RequireSession,
+		middleware.ValidationMiddleware[dto.LogoutPayload],
+	))
+	r.Handle("POST /users/password/forgot", middleware.ValidationMiddleware[dto.ForgotPasswordPayload](http.HandlerFunc(userHandler.ForgotPassword)))
+	r.Handle("POST /users/password/reset", middleware.ValidationMiddleware[dto.ResetPasswordPayload](http.HandlerFunc(userHandler.ResetPassword)))
+	r.Handle("GET /users/verify", http.HandlerFunc(userHandler.VerifyEmail))
+	r.Handle("GET /users/unlock", http.HandlerFunc(userHandler.UnlockAccount))
+	r.Handle("POST /users/verify/resend", middleware.Chain(http.HandlerFunc(userHandler.ResendVerificationEmail), middleware.JWTAuth, middleware.RequireSession))
+	r.Handle("GET /users/email/confirm", http.HandlerFunc(userHandler.ConfirmEmailChange))
+	r.Handle("GET /users/me", middleware.Chain(http.HandlerFunc(userHandler.GetUserData), middleware.JWTAuth, userRead))
+	r.Handle("PATCH /users/me", middleware.Chain(http.HandlerFunc(userHandler.UpdateUserData),
+		middleware.JWTAuth,
+		middleware.RequireSession,
+		middleware.ValidationMiddleware[dto.UpdateProfilePayload],
+	))
+	r.Handle("DELETE /users/me", middleware.Chain(http.HandlerFunc(userHandler.DeleteAccount),
+		middleware.JWTAuth,
+		middleware.RequireSession,
+		middleware.ValidationMiddleware[dto.DeleteAccountPayload],
+	))
+	r.Handle("PUT /users/me/password", middleware.Chain(http.HandlerFunc(userHandler.ChangePassword),
+		middleware.JWTAuth,
+		middleware.RequireSession,
+		middleware.ValidationMiddleware[dto.ChangePasswordPayload],
+	))
+	r.Handle("GET /users/me/tokens", middleware.Chain(http.HandlerFunc(patHandler.GetTokens), middleware.JWTAuth, middleware.RequireSession))
+	r.Handle("POST /users/me/tokens", middleware.Chain(http.HandlerFunc(patHandler.CreateToken),
+		middleware.JWTAuth,
+		middleware.RequireSession,
+		middleware.ValidationMiddleware[dto.CreatePersonalAccessTokenPayload],
+	))
+	r.Handle("DELETE /users/me/tokens/{tokenID}", middleware.Chain(http.HandlerFunc(patHandler.RevokeTokenById), middleware.JWTAuth, middleware.RequireSession))
+	r.Handle("GET /users/me/invitations", middleware.Chain(http.HandlerFunc(shareHandler.GetInvitations), middleware.JWTAuth, middleware.RequireSession))
+	r.Handle("POST /users/me/invitations/{shareID}/accept", middleware.Chain(http.HandlerFunc(shareHandler.AcceptInvitation), middleware.JWTAuth, middleware.RequireSession))
+	r.Handle("POST /users/me/invitations/{shareID}/decline", middleware.Chain(http.HandlerFunc(shareHandler.DeclineInvitation), middleware.JWTAuth, middleware.RequireSession))
+	r.Handle("GET /users/me/audit", middleware.Chain(http.HandlerFunc(auditHandler.GetMyAudit), middleware.JWTAuth, middleware.RequireSession))
+	r.Handle("POST /users/me/export", middleware.Chain(http.HandlerFunc(exportHandler.RequestExport), middleware.JWTAuth, middleware.RequireSession))
+	r.Handle("GET /users/me/export/{exportID}", middleware.Chain(http.HandlerFunc(exportHandler.GetExport), middleware.JWTAuth, middleware.RequireSession))
+	r.Handle("POST /users/me/2fa/setup", middleware.Chain(http.HandlerFunc(twoFactorHandler.BeginSetup), middleware.JWTAuth, middleware.RequireSession))
+	r.Handle("POST /users/me/2fa/confirm", middleware.Chain(http.HandlerFunc(twoFactorHandler.ConfirmSetup),
+		middleware.JWTAuth,
+		middleware.RequireSession,
+		middleware.ValidationMiddleware[dto.TwoFactorCodePayload],
+	))
+	r.Handle("POST /users/me/2fa/disable", middleware.Chain(http.HandlerFunc(twoFactorHandler.Disable),
+		middleware.JWTAuth,
+		middleware.RequireSession,
+		middleware.ValidationMiddleware[dto.DisableTwoFactorPayload],
+	))
+	r.Handle("POST /users/me/2fa/recovery-codes", middleware.Chain(http.HandlerFunc(twoFactorHandler.RegenerateRecoveryCodes),
+		middleware.JWTAuth,
+		middleware.RequireSession,
+		middleware.ValidationMiddleware[dto.TwoFactorCodePayload],
+	))
 
 	r.Handle("POST /todos", middleware.Chain(http.HandlerFunc(todoHandler.CreateTodo),
 		middleware.JWTAuth,
+		middleware.WorkspaceContext,
+		todosWrite,
 		middleware.ValidationMiddleware[dto.CreateTodoPayload],
 	))
-	r.Handle("GET /todos", middleware.JWTAuth(http.HandlerFunc(todoHandler.GetTodos)))
-	r.Handle("GET /todos/{todoID}", middleware.JWTAuth(http.HandlerFunc(todoHandler.GetOneTodoByID)))
+	r.Handle("GET /todos", middleware.Chain(http.HandlerFunc(todoHandler.GetTodos), middleware.JWTAuth, middleware.WorkspaceContext, todosRead))
+	r.Handle("GET /todos/search", middleware.Chain(http.HandlerFunc(todoHandler.SearchTodos), middleware.JWTAuth, middleware.WorkspaceContext, todosRead))
+	r.Handle("GET /todos/overdue", middleware.Chain(http.HandlerFunc(todoHandler.GetOverdueTodos), middleware.JWTAuth, middleware.WorkspaceContext, todosRead))
+	r.Handle("GET /todos/upcoming", middleware.Chain(http.HandlerFunc(todoHandler.GetUpcomingTodos), middleware.JWTAuth, middleware.WorkspaceContext, todosRead))
+	r.Handle("GET /todos/{todoID}", middleware.Chain(http.HandlerFunc(todoHandler.GetOneTodoByID), middleware.JWTAuth, middleware.WorkspaceContext, todosRead))
 	r.Handle("PUT /todos/{todoID}", middleware.Chain(http.HandlerFunc(todoHandler.UpdateTodoById),
 		middleware.JWTAuth,
+		middleware.WorkspaceContext,
+		todosWrite,
 		middleware.ValidationMiddleware[dto.UpdateTodoPayload],
 	))
-	r.Handle("PATCH /todos/{todoID}/done", middleware.JWTAuth(http.HandlerFunc(todoHandler.MarkTodoDoneById)))
-	r.Handle("DELETE /todos/{todoID}", middleware.JWTAuth(http.HandlerFunc(todoHandler.DeleteTodoById)))
+	r.Handle("PATCH /todos/{todoID}", middleware.Chain(http.HandlerFunc(todoHandler.PatchTodoById), middleware.JWTAuth, middleware.WorkspaceContext, todosWrite))
+	r.Handle("PATCH /todos/{todoID}/done", middleware.Chain(http.HandlerFunc(todoHandler.MarkTodoDoneById), middleware.JWTAuth, middleware.WorkspaceContext, todosWrite))
+	r.Handle("DELETE /todos/{todoID}", middleware.Chain(http.HandlerFunc(todoHandler.DeleteTodoById), middleware.JWTAuth, middleware.WorkspaceContext, todosWrite))
+
+	r.Handle("POST /todos/{todoID}/tags", middleware.Chain(http.HandlerFunc(tagHandler.AttachTagsToTodo),
+		middleware.JWTAuth,
+		middleware.WorkspaceContext,
+		todosWrite,
+		middleware.ValidationMiddleware[dto.AttachTagsPayload],
+	))
+	r.Handle("DELETE /todos/{todoID}/tags/{tagID}", middleware.Chain(http.HandlerFunc(tagHandler.DetachTagFromTodo), middleware.JWTAuth, middleware.WorkspaceContext, todosWrite))
+
+	r.Handle("GET /todos/{todoID}/items", middleware.Chain(http.HandlerFunc(itemHandler.GetItems), middleware.JWTAuth, middleware.WorkspaceContext, todosRead))
+	r.Handle("POST /todos/{todoID}/items", middleware.Chain(http.HandlerFunc(itemHandler.CreateItem),
+		middleware.JWTAuth,
+		middleware.WorkspaceContext,
+		todosWrite,
+		middleware.ValidationMiddleware[dto.ChecklistItemPayload],
+	))
+	r.Handle("PUT /todos/{todoID}/items/{itemID}", middleware.Chain(http.HandlerFunc(itemHandler.UpdateItemById),
+		middleware.JWTAuth,
+		middleware.WorkspaceContext,
+		todosWrite,
+		middleware.ValidationMiddleware[dto.ChecklistItemPayload],
+	))
+	r.Handle("DELETE /todos/{todoID}/items/{itemID}", middleware.Chain(http.HandlerFunc(itemHandler.DeleteItemById), middleware.JWTAuth, middleware.WorkspaceContext, todosWrite))
+
+	r.Handle("GET /todos/{todoID}/shares", middleware.Chain(http.HandlerFunc(shareHandler.GetTodoShares), middleware.JWTAuth, middleware.WorkspaceContext, todosRead))
+	r.Handle("POST /todos/{todoID}/shares", middleware.Chain(http.HandlerFunc(shareHandler.CreateTodoShare),
+		middleware.JWTAuth,
+		middleware.WorkspaceContext,
+		todosWrite,
+		middleware.ValidationMiddleware[dto.SharePayload],
+	))
+	r.Handle("DELETE /todos/{todoID}/shares/{shareID}", middleware.Chain(http.HandlerFunc(shareHandler.DeleteTodoShare), middleware.JWTAuth, middleware.WorkspaceContext, todosWrite))
+
+	r.Handle("GET /todos/{todoID}/comments", middleware.Chain(http.HandlerFunc(commentHandler.GetComments), middleware.JWTAuth, middleware.WorkspaceContext, todosRead))
+	r.Handle("POST /todos/{todoID}/comments", middleware.Chain(http.HandlerFunc(commentHandler.CreateComment),
+		middleware.JWTAuth,
+		middleware.WorkspaceContext,
+		todosWrite,
+		middleware.ValidationMiddleware[dto.CommentPayload],
+	))
+	r.Handle("PUT /todos/{todoID}/comments/{commentID}", middleware.Chain(http.HandlerFunc(commentHandler.UpdateCommentById),
+		middleware.JWTAuth,
+		middleware.WorkspaceContext,
+		todosWrite,
+		middleware.ValidationMiddleware[dto.CommentPayload],
+	))
+	r.Handle("DELETE /todos/{todoID}/comments/{commentID}", middleware.Chain(http.HandlerFunc(commentHandler.DeleteCommentById), middleware.JWTAuth, middleware.WorkspaceContext, todosWrite))
+	r.Handle("GET /todos/{todoID}/revisions", middleware.Chain(http.HandlerFunc(revisionHandler.GetRevisions), middleware.JWTAuth, middleware.WorkspaceContext, todosRead))
+	r.Handle("GET /todos/{todoID}/revisions/diff", middleware.Chain(http.HandlerFunc(revisionHandler.DiffRevisions), middleware.JWTAuth, middleware.WorkspaceContext, todosRead))
+	r.Handle("POST /todos/{todoID}/revisions/{rev}/restore", middleware.Chain(http.HandlerFunc(revisionHandler.RestoreRevision), middleware.JWTAuth, middleware.WorkspaceContext, todosWrite))
+	r.Handle("GET /todos/{todoID}/activity", middleware.Chain(http.HandlerFunc(commentHandler.GetActivity), middleware.JWTAuth, middleware.WorkspaceContext, todosRead))
+
+	r.Handle("PATCH /todos/{todoID}/project", middleware.Chain(http.HandlerFunc(projectHandler.MoveTodoToProject),
+		middleware.JWTAuth,
+		middleware.WorkspaceContext,
+		todosWrite,
+		middleware.ValidationMiddleware[dto.MoveTodoPayload],
+	))
+
+	r.Handle("POST /projects", middleware.Chain(http.HandlerFunc(projectHandler.CreateProject),
+		middleware.JWTAuth,
+		middleware.WorkspaceContext,
+		todosWrite,
+		middleware.ValidationMiddleware[dto.ProjectPayload],
+	))
+	r.Handle("GET /projects", middleware.Chain(http.HandlerFunc(projectHandler.GetProjects), middleware.JWTAuth, middleware.WorkspaceContext, todosRead))
+	r.Handle("GET /projects/{projectID}", middleware.Chain(http.HandlerFunc(projectHandler.GetOneProjectByID), middleware.JWTAuth, middleware.WorkspaceContext, todosRead))
+	r.Handle("PUT /projects/{projectID}", middleware.Chain(http.HandlerFunc(projectHandler.UpdateProjectById),
+		middleware.JWTAuth,
+		middleware.WorkspaceContext,
+		todosWrite,
+		middleware.ValidationMiddleware[dto.ProjectPayload],
+	))
+	r.Handle("DELETE /projects/{projectID}", middleware.Chain(http.HandlerFunc(projectHandler.DeleteProjectById), middleware.JWTAuth, middleware.WorkspaceContext, todosWrite))
+	r.Handle("GET /projects/{projectID}/todos", middleware.Chain(http.HandlerFunc(projectHandler.GetProjectTodos), middleware.JWTAuth, middleware.WorkspaceContext, todosRead))
+	r.Handle("GET /projects/{projectID}/shares", middleware.Chain(http.HandlerFunc(shareHandler.GetProjectShares), middleware.JWTAuth, middleware.WorkspaceContext, todosRead))
+	r.Handle("POST /projects/{projectID}/shares", middleware.Chain(http.HandlerFunc(shareHandler.CreateProjectShare),
+		middleware.JWTAuth,
+		middleware.WorkspaceContext,
+		todosWrite,
+		middleware.ValidationMiddleware[dto.SharePayload],
+	))
+	r.Handle("DELETE /projects/{projectID}/shares/{shareID}", middleware.Chain(http.HandlerFunc(shareHandler.DeleteProjectShare), middleware.JWTAuth, middleware.WorkspaceContext, todosWrite))
+
+	r.Handle("GET /trash", middleware.Chain(http.HandlerFunc(todoHandler.GetTrash), middleware.JWTAuth, middleware.WorkspaceContext, todosRead))
+	r.Handle("POST /trash/{todoID}/restore", middleware.Chain(http.HandlerFunc(todoHandler.RestoreTodoById), middleware.JWTAuth, middleware.WorkspaceContext, todosWrite))
+	r.Handle("DELETE /trash/{todoID}", middleware.Chain(http.HandlerFunc(todoHandler.PurgeTodoById), middleware.JWTAuth, middleware.WorkspaceContext, todosWrite))
+
+	r.Handle("POST /tags", middleware.Chain(http.HandlerFunc(tagHandler.CreateTag),
+		middleware.JWTAuth,
+		middleware.WorkspaceContext,
+		todosWrite,
+		middleware.ValidationMiddleware[dto.TagPayload],
+	))
+	r.Handle("GET /tags", middleware.Chain(http.HandlerFunc(tagHandler.GetTags), middleware.JWTAuth, middleware.WorkspaceContext, todosRead))
+	r.Handle("GET /tags/{tagID}", middleware.Chain(http.HandlerFunc(tagHandler.GetOneTagByID), middleware.JWTAuth, middleware.WorkspaceContext, todosRead))
+	r.Handle("PUT /tags/{tagID}", middleware.Chain(http.HandlerFunc(tagHandler.UpdateTagById),
+		middleware.JWTAuth,
+		middleware.WorkspaceContext,
+		todosWrite,
+		middleware.ValidationMiddleware[dto.TagPayload],
+	))
+	r.Handle("DELETE /tags/{tagID}", middleware.Chain(http.HandlerFunc(tagHandler.DeleteTagById), middleware.JWTAuth, middleware.WorkspaceContext, todosWrite))
+
+	r.Handle("POST /workspaces", middleware.Chain(http.HandlerFunc(workspaceHandler.CreateWorkspace),
+		middleware.JWTAuth,
+		todosWrite,
+		middleware.ValidationMiddleware[dto.WorkspacePayload],
+	))
+	r.Handle("GET /workspaces", middleware.Chain(http.HandlerFunc(workspaceHandler.GetWorkspaces), middleware.JWTAuth, todosRead))
+	r.Handle("GET /workspaces/{workspaceID}", middleware.Chain(http.HandlerFunc(workspaceHandler.GetOneWorkspaceByID), middleware.JWTAuth, todosRead))
+	r.Handle("PUT /workspaces/{workspaceID}", middleware.Chain(http.HandlerFunc(workspaceHandler.UpdateWorkspaceById),
+		middleware.JWTAuth,
+		todosWrite,
+		middleware.ValidationMiddleware[dto.WorkspacePayload],
+	))
+	r.Handle("DELETE /workspaces/{workspaceID}", middleware.Chain(http.HandlerFunc(workspaceHandler.DeleteWorkspaceById), middleware.JWTAuth, todosWrite))
+	r.Handle("GET /workspaces/{workspaceID}/members", middleware.Chain(http.HandlerFunc(workspaceHandler.GetMembers), middleware.JWTAuth, todosRead))
+	r.Handle("POST /workspaces/{workspaceID}/members", middleware.Chain(http.HandlerFunc(workspaceHandler.AddMember),
+		middleware.JWTAuth,
+		todosWrite,
+		middleware.ValidationMiddleware[dto.AddMemberPayload],
+	))
+	r.Handle("PUT /workspaces/{workspaceID}/members/{userID}", middleware.Chain(http.HandlerFunc(workspaceHandler.SetMemberRole),
+		middleware.JWTAuth,
+		todosWrite,
+		middleware.ValidationMiddleware[dto.SetMemberRolePayload],
+	))
+	r.Handle("DELETE /workspaces/{workspaceID}/members/{userID}", middleware.Chain(http.HandlerFunc(workspaceHandler.RemoveMember), middleware.JWTAuth, todosWrite))
+
+	r.Handle("GET /admin/users", middleware.Chain(http.HandlerFunc(adminHandler.ListUsers), middleware.JWTAuth, middleware.RequireSession, adminOnly))
+	r.Handle("GET /admin/users/{userID}", middleware.Chain(http.HandlerFunc(adminHandler.GetUser), middleware.JWTAuth, middleware.RequireSession, adminOnly))
+	r.Handle("PUT /admin/users/{userID}/role", middleware.Chain(http.HandlerFunc(adminHandler.SetUserRole),
+		middleware.JWTAuth,
+		middleware.RequireSession,
+		adminOnly,
+		middleware.ValidationMiddleware[dto.SetRolePayload],
+	))
+	r.Handle("POST /admin/users/{userID}/disable", middleware.Chain(http.HandlerFunc(adminHandler.DisableUser), middleware.JWTAuth, middleware.RequireSession, adminOnly))
+	r.Handle("POST /admin/users/{userID}/enable", middleware.Chain(http.HandlerFunc(adminHandler.EnableUser), middleware.JWTAuth, middleware.RequireSession, adminOnly))
+	r.Handle("POST /admin/users/{userID}/unlock", middleware.Chain(http.HandlerFunc(adminHandler.UnlockUser), middleware.JWTAuth, middleware.RequireSession, adminOnly))
+	r.Handle("GET /admin/stats", middleware.Chain(http.HandlerFunc(adminHandler.GetStats), middleware.JWTAuth, middleware.RequireSession, adminOnly))
+	r.Handle("GET /admin/audit", middleware.Chain(http.HandlerFunc(auditHandler.ListAudit), middleware.JWTAuth, middleware.RequireSession, adminOnly))
+
+	var root http.Handler = middleware.RequestContext(r)
+	if os.Getenv("TRUST_PROXY") == "true" {
+		root = middleware.RealIP(root)
+	}
 
-	log.Fatal(http.ListenAndServe(":11451", r))
+	log.Fatal(http.ListenAndServe(":11451", root))
 }
@@ -0,0 +1,41 @@
+// Package actor carries the user a request is made by, and where it came
+// from, through the context, so that services can attribute what they record
+// without every method taking a user id.
+package actor
+
+import "context"
+
+type userKey struct{}
+type requestKey struct{}
+
+// Request describes the HTTP request work is done for.
+type Request struct {
+	ID        string
+	IP        string
+	UserAgent string
+}
+
+// WithUserID returns a copy of ctx acting on behalf of userID.
+func WithUserID(ctx context.Context, userID int) context.Context {
+	return context.WithValue(ctx, userKey{}, userID)
+}
+
+// UserID returns the user ctx acts on behalf of. It reports false for work
+// the system does on its own, like periodic jobs, and for requests made
+// before logging in.
+func UserID(ctx context.Context) (int, bool) {
+	id, ok := ctx.Value(userKey{}).(int)
+	return id, ok
+}
+
+// WithRequest returns a copy of ctx doing work for req.
+func WithRequest(ctx context.Context, req Request) context.Context {
+	return context.WithValue(ctx, requestKey{}, req)
+}
+
+// RequestFrom returns the request ctx does work for, or a zero Request
+// outside of requests.
+func RequestFrom(ctx context.Context) Request {
+	req, _ := ctx.Value(requestKey{}).(Request)
+	return req
+}
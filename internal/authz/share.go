@@ -0,0 +1,69 @@
+package authz
+
+import (
+	"context"
+
+	"github.com/King0625/golang-todolist/internal/model"
+)
+
+// ShareLookup reports what a user was granted on a shared todo or project.
+type ShareLookup interface {
+	GetPermission(ctx context.Context, userID int, resourceType model.ShareResource, resourceID int) (model.SharePermission, error)
+}
+
+type sharePolicy struct {
+	shares ShareLookup
+}
+
+// NewSharePolicy returns a policy that extends NewOwnerPolicy to todos and
+// projects shared with the subject. Viewers may read; editors may also change
+// todos and move them to the trash, and change shared projects. Deleting a
+// project, anything in the trash and sharing are left to the owner.
+func NewSharePolicy(shares ShareLookup) Policy {
+	return sharePolicy{shares}
+}
+
+func (p sharePolicy) Authorize(ctx context.Context, subject Subject, action Action, resource Resource) error {
+	if resource.OwnerID() == subject.UserID {
+		return nil
+	}
+
+	required := model.PermissionEditor
+	switch action {
+	case ActionRead:
+		required = model.PermissionViewer
+	case ActionShare:
+		return ErrForbidden
+	}
+
+	var permission model.SharePermission
+	var err error
+
+	switch r := resource.(type) {
+	case *model.Todo:
+		if r.DeletedAt != nil {
+			return ErrForbidden
+		}
+		permission, err = p.shares.GetPermission(ctx, subject.UserID, model.ShareTodo, r.ID)
+		if err == nil && !permission.Includes(required) && r.ProjectID != nil {
+			// a share of the project covers every todo in it
+			permission, err = p.shares.GetPermission(ctx, subject.UserID, model.ShareProject, *r.ProjectID)
+		}
+	case *model.Project:
+		if action == ActionDelete {
+			return ErrForbidden
+		}
+		permission, err = p.shares.GetPermission(ctx, subject.UserID, model.ShareProject, r.ID)
+	default:
+		return ErrForbidden
+	}
+
+	if err != nil {
+		return err
+	}
+	if !permission.Includes(required) {
+		return ErrForbidden
+	}
+
+	return nil
+}
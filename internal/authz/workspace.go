@@ -0,0 +1,57 @@
+package authz
+
+import (
+	"context"
+
+	"github.com/King0625/golang-todolist/internal/model"
+)
+
+// TenantResource is a resource that may belong to a workspace rather than to
+// its owner alone.
+type TenantResource interface {
+	Resource
+	Tenant() model.Tenant
+}
+
+// MemberLookup reports the role of a user in a workspace, or an empty role for
+// users that are not members.
+type MemberLookup interface {
+	GetMemberRole(ctx context.Context, workspaceID, userID int) (model.WorkspaceRole, error)
+}
+
+type workspacePolicy struct {
+	members MemberLookup
+	next    Policy
+}
+
+// NewWorkspacePolicy returns a policy for the todos, projects and tags of
+// workspaces, and leaves everything else to next. Every member may read and
+// change workspace content; deleting is left to its creator and the owner and
+// admins of the workspace. Workspace content is shared through membership, so
+// it cannot be shared with individual users.
+func NewWorkspacePolicy(members MemberLookup, next Policy) Policy {
+	return workspacePolicy{members, next}
+}
+
+func (p workspacePolicy) Authorize(ctx context.Context, subject Subject, action Action, resource Resource) error {
+	tenanted, ok := resource.(TenantResource)
+	if !ok || tenanted.Tenant().WorkspaceID == 0 {
+		return p.next.Authorize(ctx, subject, action, resource)
+	}
+
+	role, err := p.members.GetMemberRole(ctx, tenanted.Tenant().WorkspaceID, subject.UserID)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case role == "":
+		return ErrForbidden
+	case action == ActionShare:
+		return ErrForbidden
+	case action == ActionDelete && !role.CanManage() && resource.OwnerID() != subject.UserID:
+		return ErrForbidden
+	}
+
+	return nil
+}
@@ -0,0 +1,5 @@
+package dto
+
+type SetRolePayload struct {
+	Role string `json:"role" validate:"required,oneof=user admin"`
+}
@@ -0,0 +1,7 @@
+package dto
+
+// CommentPayload is the Markdown content of a comment. Users are mentioned
+// with @ followed by their email address.
+type CommentPayload struct {
+	Content string `json:"content" validate:"required,max=6666"`
+}
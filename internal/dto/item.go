@@ -0,0 +1,8 @@
+package dto
+
+type ChecklistItemPayload struct {
+	Title string `json:"title" validate:"required,max=255"`
+	Done  bool   `json:"done"`
+	// Position defaults to the end of the checklist when omitted.
+	Position *int `json:"position" validate:"omitempty,min=0"`
+}
@@ -0,0 +1,13 @@
+package dto
+
+type ProjectPayload struct {
+	Name     string `json:"name" validate:"required,max=255"`
+	Color    string `json:"color" validate:"omitempty,hexcolor,max=7"`
+	Archived bool   `json:"archived"`
+	Position int    `json:"position" validate:"min=0"`
+}
+
+// MoveTodoPayload moves a todo to a project; a null projectId moves it to the inbox.
+type MoveTodoPayload struct {
+	ProjectID *int `json:"projectId" validate:"omitempty,gt=0"`
+}
@@ -0,0 +1,6 @@
+package dto
+
+type SharePayload struct {
+	Email      string `json:"email" validate:"required,email,max=255"`
+	Permission string `json:"permission" validate:"required,oneof=viewer editor"`
+}
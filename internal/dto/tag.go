@@ -0,0 +1,10 @@
+package dto
+
+type TagPayload struct {
+	Name  string `json:"name" validate:"required,max=64"`
+	Color string `json:"color" validate:"omitempty,hexcolor,max=7"`
+}
+
+type AttachTagsPayload struct {
+	TagIDs []int `json:"tagIds" validate:"required,min=1,dive,gt=0"`
+}
@@ -1,11 +1,35 @@
 package dto
 
+import "github.com/King0625/golang-todolist/internal/model"
+
 type CreateTodoPayload struct {
 	Title   string `json:"title" validate:"required,max=666"`
 	Content string `json:"content" validate:"required,max=6666"`
+	// DueDate is YYYY-MM-DD, DueTime is HH:MM and Timezone an IANA zone name.
+	DueDate  string `json:"dueDate" validate:"required_with=DueTime Timezone Recurrence,omitempty,datetime=2006-01-02"`
+	DueTime  string `json:"dueTime" validate:"omitempty,datetime=15:04"`
+	Timezone string `json:"timezone" validate:"omitempty,timezone"`
+	Priority string `json:"priority" validate:"omitempty,oneof=none low medium high urgent"`
+	// Recurrence is an RRULE such as FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR.
+	Recurrence string `json:"recurrence" validate:"omitempty,max=255,rrule"`
+	// ProjectID is nil for todos in the inbox.
+	ProjectID *int `json:"projectId" validate:"omitempty,gt=0"`
+	// AssigneeID is a member of the workspace the todo is in.
+	AssigneeID *int `json:"assigneeId" validate:"omitempty,gt=0"`
 }
 
 type UpdateTodoPayload struct {
 	CreateTodoPayload
-	Done bool `json:"done" validate:"required"`
+	Done bool `json:"done"`
+}
+
+type PaginationMeta struct {
+	Limit      int    `json:"limit"`
+	HasMore    bool   `json:"hasMore"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+type MarkDoneData struct {
+	// NextOccurrence is the todo created by completing a recurring todo.
+	NextOccurrence *model.Todo `json:"nextOccurrence"`
 }
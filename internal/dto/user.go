@@ -1,5 +1,7 @@
 package dto
 
+import "github.com/King0625/golang-todolist/internal/model"
+
 type RegisterPayload struct {
 	Email     string `json:"email" validate:"required,email"`
 	FirstName string `json:"firstName" validate:"required,max=666"`
@@ -13,5 +15,84 @@ type LoginPayload struct {
 }
 
 type LoginSuccessData struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+	ExpiresIn    int    `json:"expiresIn"`
+}
+
+// TwoFactorChallengeData replaces LoginSuccessData for users with 2FA on. The
+// challenge token is exchanged for a session at /users/login/2fa.
+type TwoFactorChallengeData struct {
+	TwoFactorRequired bool   `json:"twoFactorRequired"`
+	ChallengeToken    string `json:"challengeToken"`
+	ExpiresIn         int    `json:"expiresIn"`
+}
+
+// TwoFactorLoginPayload takes either a TOTP code or a recovery code.
+type TwoFactorLoginPayload struct {
+	ChallengeToken string `json:"challengeToken" validate:"required"`
+	Code           string `json:"code" validate:"required,max=32"`
+}
+
+type RefreshTokenPayload struct {
+	RefreshToken string `json:"refreshToken" validate:"required"`
+}
+
+// LogoutPayload revokes the session of RefreshToken, or every session of the
+// user when it is empty.
+type LogoutPayload struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+type ForgotPasswordPayload struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordPayload struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=6,max=12"`
+}
+
+type CreatePersonalAccessTokenPayload struct {
+	Name   string   `json:"name" validate:"required,max=100"`
+	Scopes []string `json:"scopes" validate:"required,min=1,dive,oneof=todos:read todos:write user:read"`
+	// ExpiresInDays is optional; tokens without it never expire.
+	ExpiresInDays int `json:"expiresInDays" validate:"omitempty,min=1,max=365"`
+}
+
+type CreatePersonalAccessTokenData struct {
+	*model.PersonalAccessToken
+	// Token is only ever shown once, right after creation.
 	Token string `json:"token"`
 }
+
+type TwoFactorCodePayload struct {
+	Code string `json:"code" validate:"required,max=32"`
+}
+
+type DisableTwoFactorPayload struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"required,max=32"`
+}
+
+// RecoveryCodesData is only ever shown once, right after the codes are made.
+type RecoveryCodesData struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// UpdateProfilePayload changes only the fields that are present. A new email
+// takes effect once confirmed from that address.
+type UpdateProfilePayload struct {
+	FirstName *string `json:"firstName" validate:"omitnil,min=1,max=666"`
+	LastName  *string `json:"lastName" validate:"omitnil,min=1,max=666"`
+	Email     *string `json:"email" validate:"omitnil,email,max=255"`
+}
+
+type ChangePasswordPayload struct {
+	CurrentPassword string `json:"currentPassword" validate:"required"`
+	NewPassword     string `json:"newPassword" validate:"required,min=6,max=12"`
+}
+
+type DeleteAccountPayload struct {
+	Password string `json:"password" validate:"required"`
+}
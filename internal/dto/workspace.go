@@ -0,0 +1,16 @@
+package dto
+
+type WorkspacePayload struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+// AddMemberPayload adds a registered user to a workspace. The owner role
+// cannot be given away.
+type AddMemberPayload struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+	Role  string `json:"role" validate:"required,oneof=admin member"`
+}
+
+type SetMemberRolePayload struct {
+	Role string `json:"role" validate:"required,oneof=admin member"`
+}
@@ -0,0 +1,263 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/King0625/golang-todolist/internal/dto"
+	"github.com/King0625/golang-todolist/internal/middleware"
+	"github.com/King0625/golang-todolist/internal/model"
+	"github.com/King0625/golang-todolist/internal/service"
+	"github.com/King0625/golang-todolist/pkg/utils"
+)
+
+const (
+	defaultUserLimit = 50
+	maxUserLimit     = 200
+)
+
+type AdminHandler struct {
+	service service.AdminService
+}
+
+func NewAdminHandler(s service.AdminService) *AdminHandler {
+	return &AdminHandler{s}
+}
+
+// parseUserQuery reads the query parameters of GET /admin/users. The cursor
+// is the id of the last user of the previous page.
+func parseUserQuery(query url.Values) (model.UserQuery, map[string]string) {
+	q := model.UserQuery{
+		Search: query.Get("q"),
+		Limit:  defaultUserLimit,
+	}
+	details := make(map[string]string)
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxUserLimit {
+			details["limit"] = "must be an integer between 1 and " + strconv.Itoa(maxUserLimit)
+		} else {
+			q.Limit = limit
+		}
+	}
+
+	if v := query.Get("cursor"); v != "" {
+		afterID, err := strconv.Atoi(v)
+		if err != nil || afterID < 0 {
+			details["cursor"] = "invalid cursor"
+		} else {
+			q.AfterID = afterID
+		}
+	}
+
+	if v := query.Get("role"); v != "" {
+		if role := model.Role(v); role.Valid() {
+			q.Role = role
+		} else {
+			details["role"] = "must be user or admin"
+		}
+	}
+
+	if v := query.Get("disabled"); v != "" {
+		disabled, err := strconv.ParseBool(v)
+		if err != nil {
+			details["disabled"] = "must be true or false"
+		} else {
+			q.Disabled = &disabled
+		}
+	}
+
+	return q, details
+}
+
+func (h *AdminHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	var message string
+
+	query, details := parseUserQuery(r.URL.Query())
+	if len(details) > 0 {
+		message = "invalid query parameters"
+		utils.RespondError(w, http.StatusBadRequest, ValidationError, message, details)
+		return
+	}
+
+	page, err := h.service.ListUsers(r.Context(), query)
+	if err != nil {
+		fmt.Println(err)
+		message = "cannot get users from db"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	meta := dto.PaginationMeta{
+		Limit:   query.Limit,
+		HasMore: page.HasMore,
+	}
+	if page.HasMore {
+		meta.NextCursor = strconv.Itoa(page.Users[len(page.Users)-1].ID)
+	}
+
+	message = "fetch users successfully"
+	utils.RespondSuccessWithMeta(w, http.StatusOK, message, page.Users, meta)
+}
+
+// getUser resolves the user at the userID path parameter. It responds with an
+// error and returns nil when there is none.
+func (h *AdminHandler) getUser(w http.ResponseWriter, r *http.Request) *model.User {
+	var message string
+
+	userID, err := strconv.Atoi(r.PathValue("userID"))
+	if err != nil {
+		message = "invalid userID"
+		utils.RespondError(w, http.StatusBadRequest, ValidationError, message, nil)
+		return nil
+	}
+
+	user, err := h.service.GetUserById(r.Context(), userID)
+	if user == nil {
+		fmt.Println(err)
+		message = "user not found"
+		utils.RespondError(w, http.StatusNotFound, UserNotFound, message, nil)
+		return nil
+	}
+
+	return user
+}
+
+func (h *AdminHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	user := h.getUser(w, r)
+	if user == nil {
+		return
+	}
+
+	message := "fetch user successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, user)
+}
+
+// respondCannotModifySelf answers with 409 when err is
+// service.ErrCannotModifySelf, and reports whether it did.
+func respondCannotModifySelf(w http.ResponseWriter, err error) bool {
+	if !errors.Is(err, service.ErrCannotModifySelf) {
+		return false
+	}
+
+	message := "you cannot demote or disable your own account"
+	utils.RespondError(w, http.StatusConflict, CannotModifySelf, message, nil)
+	return true
+}
+
+func (h *AdminHandler) SetUserRole(w http.ResponseWriter, r *http.Request) {
+	var message string
+	actorID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	user := h.getUser(w, r)
+	if user == nil {
+		return
+	}
+
+	payload := middleware.GetValidatedRequest[dto.SetRolePayload](r)
+
+	err := h.service.SetUserRole(r.Context(), actorID, user.ID, model.Role(payload.Role))
+	if respondCannotModifySelf(w, err) {
+		return
+	}
+	if err != nil {
+		fmt.Println(err)
+		message = "failed to update the role in DB"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "update the role successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, nil)
+}
+
+func (h *AdminHandler) DisableUser(w http.ResponseWriter, r *http.Request) {
+	var message string
+	actorID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	user := h.getUser(w, r)
+	if user == nil {
+		return
+	}
+
+	err := h.service.DisableUser(r.Context(), actorID, user.ID)
+	if respondCannotModifySelf(w, err) {
+		return
+	}
+	if err != nil {
+		fmt.Println(err)
+		message = "failed to disable the user in DB"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "disable the user successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, nil)
+}
+
+func (h *AdminHandler) EnableUser(w http.ResponseWriter, r *http.Request) {
+	var message string
+
+	user := h.getUser(w, r)
+	if user == nil {
+		return
+	}
+
+	if err := h.service.EnableUser(r.Context(), user.ID); err != nil {
+		fmt.Println(err)
+		message = "failed to enable the user in DB"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "enable the user successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, nil)
+}
+
+func (h *AdminHandler) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	var message string
+
+	user := h.getUser(w, r)
+	if user == nil {
+		return
+	}
+
+	if err := h.service.UnlockUser(r.Context(), user.ID); err != nil {
+		fmt.Println(err)
+		message = "failed to unlock the user"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "unlock the user successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, nil)
+}
+
+func (h *AdminHandler) GetStats(w http.ResponseWriter, r *http.Request) {
+	var message string
+
+	stats, err := h.service.GetStats(r.Context())
+	if err != nil {
+		fmt.Println(err)
+		message = "cannot get stats from db"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "fetch stats successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, stats)
+}
@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/King0625/golang-todolist/internal/dto"
+	"github.com/King0625/golang-todolist/internal/middleware"
+	"github.com/King0625/golang-todolist/internal/model"
+	"github.com/King0625/golang-todolist/internal/service"
+	"github.com/King0625/golang-todolist/pkg/utils"
+)
+
+const (
+	defaultAuditLimit = 50
+	maxAuditLimit     = 200
+)
+
+type AuditHandler struct {
+	service service.AuditLog
+}
+
+func NewAuditHandler(s service.AuditLog) *AuditHandler {
+	return &AuditHandler{s}
+}
+
+// parseAuditQuery reads the filters of the audit endpoints. The cursor is the
+// id of the last event of the previous page. Only admins may filter by actor
+// or user; everyone else sees their own events.
+func parseAuditQuery(query url.Values, admin bool) (model.AuditQuery, map[string]string) {
+	q := model.AuditQuery{
+		Action:     model.AuditAction(query.Get("action")),
+		TargetType: query.Get("targetType"),
+		Limit:      defaultAuditLimit,
+	}
+	details := make(map[string]string)
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxAuditLimit {
+			details["limit"] = "must be an integer between 1 and " + strconv.Itoa(maxAuditLimit)
+		} else {
+			q.Limit = limit
+		}
+	}
+
+	if v := query.Get("cursor"); v != "" {
+		beforeID, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || beforeID < 1 {
+			details["cursor"] = "invalid cursor"
+		} else {
+			q.BeforeID = beforeID
+		}
+	}
+
+	idParams := map[string]**int{"targetId": &q.TargetID}
+	if admin {
+		idParams["actorId"] = &q.ActorID
+		idParams["userId"] = &q.SubjectID
+	}
+	for name, target := range idParams {
+		v := query.Get(name)
+		if v == "" {
+			continue
+		}
+		id, err := strconv.Atoi(v)
+		if err != nil || id < 1 {
+			details[name] = "must be a positive integer"
+			continue
+		}
+		*target = &id
+	}
+
+	timeParams := map[string]**time.Time{
+		"from": &q.From,
+		"to":   &q.To,
+	}
+	for name, target := range timeParams {
+		v := query.Get(name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			details[name] = "must be an RFC3339 timestamp"
+			continue
+		}
+		*target = &t
+	}
+
+	return q, details
+}
+
+func (h *AuditHandler) respondAuditPage(w http.ResponseWriter, r *http.Request, query model.AuditQuery) {
+	var message string
+
+	page, err := h.service.List(r.Context(), query)
+	if err != nil {
+		fmt.Println(err)
+		message = "cannot get audit events from db"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	meta := dto.PaginationMeta{
+		Limit:   query.Limit,
+		HasMore: page.HasMore,
+	}
+	if page.HasMore {
+		meta.NextCursor = strconv.FormatInt(page.Events[len(page.Events)-1].ID, 10)
+	}
+
+	message = "fetch audit events successfully"
+	utils.RespondSuccessWithMeta(w, http.StatusOK, message, page.Events, meta)
+}
+
+// GetMyAudit lists what the user did and the security events of their
+// account, like failed logins, newest first.
+func (h *AuditHandler) GetMyAudit(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	query, details := parseAuditQuery(r.URL.Query(), false)
+	if len(details) > 0 {
+		message = "invalid query parameters"
+		utils.RespondError(w, http.StatusBadRequest, ValidationError, message, details)
+		return
+	}
+	query.SubjectID = &userID
+
+	h.respondAuditPage(w, r, query)
+}
+
+// ListAudit searches the whole audit log, newest first.
+func (h *AuditHandler) ListAudit(w http.ResponseWriter, r *http.Request) {
+	query, details := parseAuditQuery(r.URL.Query(), true)
+	if len(details) > 0 {
+		message := "invalid query parameters"
+		utils.RespondError(w, http.StatusBadRequest, ValidationError, message, details)
+		return
+	}
+
+	h.respondAuditPage(w, r, query)
+}
@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/King0625/golang-todolist/internal/authz"
+	"github.com/King0625/golang-todolist/internal/middleware"
+	"github.com/King0625/golang-todolist/internal/model"
+	"github.com/King0625/golang-todolist/internal/service"
+	"github.com/King0625/golang-todolist/pkg/utils"
+)
+
+// authorizer is embedded by the handlers of user content to check requests
+// against the authorization policy.
+type authorizer struct {
+	policy authz.Policy
+}
+
+// check asks the policy whether userID may perform action on resource.
+func (a authorizer) check(r *http.Request, userID int, action authz.Action, resource authz.Resource) error {
+	subject := authz.Subject{UserID: userID, Role: middleware.GetRole(r)}
+	return a.policy.Authorize(r.Context(), subject, action, resource)
+}
+
+// authorize checks that userID may perform action on resource, called noun in
+// the error message. It responds with an error when it returns false.
+func (a authorizer) authorize(w http.ResponseWriter, r *http.Request, userID int, action authz.Action, resource authz.Resource, noun string) bool {
+	var message string
+
+	err := a.check(r, userID, action, resource)
+	if errors.Is(err, authz.ErrForbidden) {
+		message = fmt.Sprintf("you are not allowed to %s this %s", action, noun)
+		utils.RespondError(w, http.StatusForbidden, PermissionDenied, message, nil)
+		return false
+	}
+	if err != nil {
+		fmt.Println(err)
+		message = "cannot check permissions"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return false
+	}
+
+	return true
+}
+
+// tenantOf returns the tenant the request of userID works in.
+func tenantOf(r *http.Request, userID int) model.Tenant {
+	return model.Tenant{UserID: userID, WorkspaceID: middleware.GetWorkspaceID(r)}
+}
+
+// inTenant reports whether resource lives in the workspace the request works
+// in, or outside of any workspace for personal requests. Resources elsewhere
+// are reported as not found.
+func inTenant(r *http.Request, resource authz.TenantResource) bool {
+	return resource.Tenant().WorkspaceID == middleware.GetWorkspaceID(r)
+}
+
+// getTodo resolves the todo at the todoID path parameter and makes sure userID
+// may perform action on it. It responds with an error and returns nil
+// otherwise.
+func (a authorizer) getTodo(w http.ResponseWriter, r *http.Request, todos service.TodoService, userID int, action authz.Action) *model.Todo {
+	var message string
+
+	todoID, err := strconv.Atoi(r.PathValue("todoID"))
+	if err != nil {
+		message = "invalid todoID"
+		utils.RespondError(w, http.StatusBadRequest, ValidationError, message, nil)
+		return nil
+	}
+
+	todo, err := todos.GetTodoById(r.Context(), todoID)
+	if todo == nil || !inTenant(r, todo) {
+		fmt.Println(err)
+		message = "todo not found"
+		utils.RespondError(w, http.StatusNotFound, TodoNotFound, message, nil)
+		return nil
+	}
+
+	if !a.authorize(w, r, userID, action, todo, "todo") {
+		return nil
+	}
+
+	return todo
+}
+
+// getProject resolves the project at the projectID path parameter and makes
+// sure userID may perform action on it. It responds with an error and returns
+// nil otherwise.
+func (a authorizer) getProject(w http.ResponseWriter, r *http.Request, projects service.ProjectService, userID int, action authz.Action) *model.Project {
+	var message string
+
+	projectID, err := strconv.Atoi(r.PathValue("projectID"))
+	if err != nil {
+		message = "invalid projectID"
+		utils.RespondError(w, http.StatusBadRequest, ValidationError, message, nil)
+		return nil
+	}
+
+	project, err := projects.GetProjectById(r.Context(), projectID)
+	if project == nil || !inTenant(r, project) {
+		fmt.Println(err)
+		message = "project not found"
+		utils.RespondError(w, http.StatusNotFound, ProjectNotFound, message, nil)
+		return nil
+	}
+
+	if !a.authorize(w, r, userID, action, project, "project") {
+		return nil
+	}
+
+	return project
+}
+
+// getTargetProject resolves projectID, the project a todo is put into, and
+// makes sure userID may add todos to it. A nil projectID, the inbox, gives a
+// nil project. It responds with an error and returns false otherwise;
+// projects userID cannot change are reported as not found.
+func (a authorizer) getTargetProject(w http.ResponseWriter, r *http.Request, projects service.ProjectService, userID int, projectID *int) (*model.Project, bool) {
+	if projectID == nil {
+		return nil, true
+	}
+
+	project, err := projects.GetProjectById(r.Context(), *projectID)
+	if project != nil && !inTenant(r, project) {
+		project = nil
+	}
+	if project != nil {
+		err = a.check(r, userID, authz.ActionWrite, project)
+	}
+	if project == nil || errors.Is(err, authz.ErrForbidden) {
+		fmt.Println(err)
+		message := "project not found"
+		utils.RespondError(w, http.StatusNotFound, ProjectNotFound, message, nil)
+		return nil, false
+	}
+	if err != nil {
+		fmt.Println(err)
+		message := "cannot check permissions"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return nil, false
+	}
+
+	return project, true
+}
+
+// canMoveTodo reports whether todo may go into project, which must be in the
+// same tenant and, for personal todos, belong to the owner of the todo unless
+// it is the inbox. It responds with an error when it returns false.
+func canMoveTodo(w http.ResponseWriter, todo *model.Todo, project *model.Project) bool {
+	if project != nil && !project.Tenant().Equal(todo.Tenant()) {
+		message := "validation failed"
+		utils.RespondError(w, http.StatusBadRequest, ValidationError, message, map[string]string{"projectId": "must be a project of the todo's owner or workspace"})
+		return false
+	}
+
+	return true
+}
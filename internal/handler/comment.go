@@ -0,0 +1,224 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/King0625/golang-todolist/internal/authz"
+	"github.com/King0625/golang-todolist/internal/dto"
+	"github.com/King0625/golang-todolist/internal/middleware"
+	"github.com/King0625/golang-todolist/internal/model"
+	"github.com/King0625/golang-todolist/internal/service"
+	"github.com/King0625/golang-todolist/pkg/utils"
+)
+
+// CommentHandler serves the comments and activity feed of todos. Everyone who
+// can read a todo can comment on it.
+type CommentHandler struct {
+	authorizer
+	service     service.CommentService
+	todoService service.TodoService
+}
+
+func NewCommentHandler(s service.CommentService, todoService service.TodoService, policy authz.Policy) *CommentHandler {
+	return &CommentHandler{authorizer{policy}, s, todoService}
+}
+
+// getComment resolves the comment at the commentID path parameter, which must
+// belong to todo. It responds with an error and returns nil otherwise.
+func (h *CommentHandler) getComment(w http.ResponseWriter, r *http.Request, todo *model.Todo) *model.Comment {
+	var message string
+
+	commentID, err := strconv.Atoi(r.PathValue("commentID"))
+	if err != nil {
+		message = "invalid commentID"
+		utils.RespondError(w, http.StatusBadRequest, ValidationError, message, nil)
+		return nil
+	}
+
+	comment, err := h.service.GetCommentById(r.Context(), commentID)
+	if errors.Is(err, service.ErrCommentNotFound) || (err == nil && comment.TodoID != todo.ID) {
+		message = "comment not found"
+		utils.RespondError(w, http.StatusNotFound, CommentNotFound, message, nil)
+		return nil
+	}
+	if err != nil {
+		fmt.Println(err)
+		message = "cannot get the comment from db"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return nil
+	}
+
+	return comment
+}
+
+// respondNotAuthor answers attempts to change the comments of others, and
+// reports whether it did.
+func respondNotAuthor(w http.ResponseWriter, err error) bool {
+	if !errors.Is(err, service.ErrNotCommentAuthor) {
+		return false
+	}
+
+	message := "only the author can change this comment"
+	utils.RespondError(w, http.StatusForbidden, NotCommentAuthor, message, nil)
+	return true
+}
+
+func (h *CommentHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	todo := h.getTodo(w, r, h.todoService, userID, authz.ActionRead)
+	if todo == nil {
+		return
+	}
+
+	payload := middleware.GetValidatedRequest[dto.CommentPayload](r)
+
+	comment := model.Comment{
+		UserID:  userID,
+		Content: payload.Content,
+	}
+
+	err := h.service.AddComment(r.Context(), todo, &comment)
+	if err != nil {
+		fmt.Println(err)
+		message = "cannot insert comment into db"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "create comment successfully"
+	utils.RespondSuccess(w, http.StatusCreated, message, comment)
+}
+
+func (h *CommentHandler) GetComments(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	todo := h.getTodo(w, r, h.todoService, userID, authz.ActionRead)
+	if todo == nil {
+		return
+	}
+
+	comments, err := h.service.GetComments(r.Context(), todo.ID)
+	if err != nil {
+		fmt.Println(err)
+		message = "cannot get comments from db"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "fetch comments successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, comments)
+}
+
+func (h *CommentHandler) UpdateCommentById(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	todo := h.getTodo(w, r, h.todoService, userID, authz.ActionRead)
+	if todo == nil {
+		return
+	}
+
+	comment := h.getComment(w, r, todo)
+	if comment == nil {
+		return
+	}
+
+	payload := middleware.GetValidatedRequest[dto.CommentPayload](r)
+
+	err := h.service.EditComment(r.Context(), todo, comment, userID, payload.Content)
+	if respondNotAuthor(w, err) {
+		return
+	}
+	if err != nil {
+		fmt.Println(err)
+		message = "failed to update the comment in DB"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "update the comment successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, nil)
+}
+
+func (h *CommentHandler) DeleteCommentById(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	todo := h.getTodo(w, r, h.todoService, userID, authz.ActionRead)
+	if todo == nil {
+		return
+	}
+
+	comment := h.getComment(w, r, todo)
+	if comment == nil {
+		return
+	}
+
+	err := h.service.DeleteComment(r.Context(), comment, userID)
+	if respondNotAuthor(w, err) {
+		return
+	}
+	if err != nil {
+		fmt.Println(err)
+		message = "cannot delete the comment from DB"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "delete the comment successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, nil)
+}
+
+// GetActivity lists the comments on the todo together with what happened to
+// it, oldest first.
+func (h *CommentHandler) GetActivity(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	todo := h.getTodo(w, r, h.todoService, userID, authz.ActionRead)
+	if todo == nil {
+		return
+	}
+
+	activity, err := h.service.GetActivity(r.Context(), todo.ID)
+	if err != nil {
+		fmt.Println(err)
+		message = "cannot get activity from db"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "fetch activity successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, activity)
+}
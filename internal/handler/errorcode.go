@@ -6,13 +6,72 @@ const (
 	InvalidJSON     = "INVALID_JSON"
 	ValidationError = "VALIDATION_ERROR"
 
+	PreconditionFailed   = "PRECONDITION_FAILED"
+	PreconditionRequired = "PRECONDITION_REQUIRED"
+
+	UnsupportedMediaType = "UNSUPPORTED_MEDIA_TYPE"
+	InvalidPatch         = "INVALID_PATCH"
+	PatchTestFailed      = "PATCH_TEST_FAILED"
+	PatchFailed          = "PATCH_FAILED"
+
 	// Auth
 	Unauthorized     = "UNAUTHORIZED"
 	TokenExpired     = "TOKEN_EXPIRED"
 	UserNotFound     = "USER_NOT_FOUND"
 	PermissionDenied = "PERMISSION_DENIED"
+	TokenRevoked     = "TOKEN_REVOKED"
+	CannotModifySelf = "CANNOT_MODIFY_SELF"
+
+	RefreshTokenInvalid = "REFRESH_TOKEN_INVALID"
+	RefreshTokenReused  = "REFRESH_TOKEN_REUSED"
+	InvalidUserToken    = "INVALID_USER_TOKEN"
+	EmailNotVerified    = "EMAIL_NOT_VERIFIED"
+	TokenNotFound       = "TOKEN_NOT_FOUND"
+	WrongPassword       = "WRONG_PASSWORD"
+	AccountLocked       = "ACCOUNT_LOCKED"
+	AccountDisabled     = "ACCOUNT_DISABLED"
+	EmailAlreadyExists  = "EMAIL_ALREADY_EXISTS"
+
+	TwoFactorAlreadyEnabled   = "TWO_FACTOR_ALREADY_ENABLED"
+	TwoFactorNotEnabled       = "TWO_FACTOR_NOT_ENABLED"
+	TwoFactorSetupMissing     = "TWO_FACTOR_SETUP_MISSING"
+	InvalidTwoFactorCode      = "INVALID_TWO_FACTOR_CODE"
+	TwoFactorChallengeInvalid = "TWO_FACTOR_CHALLENGE_INVALID"
+
+	OIDCProviderNotFound = "OIDC_PROVIDER_NOT_FOUND"
+	OIDCStateInvalid     = "OIDC_STATE_INVALID"
+	OIDCLoginFailed      = "OIDC_LOGIN_FAILED"
+	OIDCEmailNotVerified = "OIDC_EMAIL_NOT_VERIFIED"
+
+	ExportNotFound = "EXPORT_NOT_FOUND"
+	ExportExpired  = "EXPORT_EXPIRED"
+	ExportFailed   = "EXPORT_FAILED"
 
 	// Todo-related
-	TodoNotFound  = "TODO_NOT_FOUND"
-	TitleTooShort = "TITLE_TOO_SHORT"
+	TodoNotFound     = "TODO_NOT_FOUND"
+	TitleTooShort    = "TITLE_TOO_SHORT"
+	TodoHasOpenItems = "TODO_HAS_OPEN_ITEMS"
+	ItemNotFound     = "ITEM_NOT_FOUND"
+	RevisionNotFound = "REVISION_NOT_FOUND"
+
+	// Tag-related
+	TagNotFound      = "TAG_NOT_FOUND"
+	TagAlreadyExists = "TAG_ALREADY_EXISTS"
+
+	// Project-related
+	ProjectNotFound = "PROJECT_NOT_FOUND"
+
+	// Sharing
+	ShareNotFound      = "SHARE_NOT_FOUND"
+	InvitationNotFound = "INVITATION_NOT_FOUND"
+
+	// Workspaces
+	WorkspaceNotFound = "WORKSPACE_NOT_FOUND"
+	MemberNotFound    = "MEMBER_NOT_FOUND"
+	AlreadyMember     = "ALREADY_MEMBER"
+	CannotModifyOwner = "CANNOT_MODIFY_OWNER"
+
+	// Comments
+	CommentNotFound  = "COMMENT_NOT_FOUND"
+	NotCommentAuthor = "NOT_COMMENT_AUTHOR"
 )
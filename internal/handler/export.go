@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/King0625/golang-todolist/internal/authz"
+	"github.com/King0625/golang-todolist/internal/middleware"
+	"github.com/King0625/golang-todolist/internal/model"
+	"github.com/King0625/golang-todolist/internal/service"
+	"github.com/King0625/golang-todolist/pkg/utils"
+)
+
+type DataExportHandler struct {
+	authorizer
+	service service.DataExportService
+}
+
+func NewDataExportHandler(s service.DataExportService, policy authz.Policy) *DataExportHandler {
+	return &DataExportHandler{authorizer{policy}, s}
+}
+
+func (h *DataExportHandler) RequestExport(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	export, err := h.service.RequestExport(r.Context(), userID)
+	if err != nil {
+		fmt.Println(err)
+		message = "cannot request data export"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/users/me/export/%d", export.ID))
+	message = "data export requested"
+	utils.RespondSuccess(w, http.StatusAccepted, message, export)
+}
+
+// GetExport reports the status of an export that is still being built and
+// serves the archive once it is ready.
+func (h *DataExportHandler) GetExport(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	exportID, err := strconv.Atoi(r.PathValue("exportID"))
+	if err != nil {
+		message = "invalid exportID"
+		utils.RespondError(w, http.StatusBadRequest, ValidationError, message, nil)
+		return
+	}
+
+	export, err := h.service.GetExportById(r.Context(), exportID)
+	if export == nil {
+		fmt.Println(err)
+		message = "export not found"
+		utils.RespondError(w, http.StatusNotFound, ExportNotFound, message, nil)
+		return
+	}
+
+	if !h.authorize(w, r, userID, authz.ActionRead, export, "export") {
+		return
+	}
+
+	if export.Expired(time.Now()) {
+		message = "export has expired, request a new one"
+		utils.RespondError(w, http.StatusGone, ExportExpired, message, nil)
+		return
+	}
+
+	switch export.Status {
+	case model.ExportPending, model.ExportRunning:
+		message = "data export is being prepared"
+		utils.RespondSuccess(w, http.StatusAccepted, message, export)
+		return
+	case model.ExportFailed:
+		message = "data export failed, request a new one"
+		utils.RespondError(w, http.StatusInternalServerError, ExportFailed, message, export)
+		return
+	}
+
+	archive, err := h.service.GetArchiveById(r.Context(), exportID)
+	if errors.Is(err, service.ErrExportNotReady) {
+		// expired and deleted between the two reads
+		message = "export has expired, request a new one"
+		utils.RespondError(w, http.StatusGone, ExportExpired, message, nil)
+		return
+	}
+	if err != nil {
+		fmt.Println(err)
+		message = "cannot get export archive from db"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	filename := fmt.Sprintf("todolist-export-%s.zip", export.CreatedAt.Format("2006-01-02"))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.Header().Set("Content-Length", strconv.Itoa(len(archive)))
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(archive)
+}
@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/King0625/golang-todolist/internal/authz"
+	"github.com/King0625/golang-todolist/internal/dto"
+	"github.com/King0625/golang-todolist/internal/middleware"
+	"github.com/King0625/golang-todolist/internal/model"
+	"github.com/King0625/golang-todolist/internal/service"
+	"github.com/King0625/golang-todolist/pkg/utils"
+	"github.com/go-playground/validator/v10"
+)
+
+type ChecklistItemHandler struct {
+	authorizer
+	service     service.ChecklistItemService
+	todoService service.TodoService
+	validate    *validator.Validate
+}
+
+func NewChecklistItemHandler(s service.ChecklistItemService, todoService service.TodoService, policy authz.Policy) *ChecklistItemHandler {
+	validate := validator.New()
+	return &ChecklistItemHandler{authorizer{policy}, s, todoService, validate}
+}
+
+// getTodoItem resolves the checklist item at the itemID path parameter and
+// makes sure it belongs to todo. It responds with an error and returns nil
+// otherwise.
+func (h *ChecklistItemHandler) getTodoItem(w http.ResponseWriter, r *http.Request, todo *model.Todo) *model.ChecklistItem {
+	var message string
+
+	itemID, err := strconv.Atoi(r.PathValue("itemID"))
+	if err != nil {
+		message = "invalid itemID"
+		utils.RespondError(w, http.StatusBadRequest, ValidationError, message, nil)
+		return nil
+	}
+
+	item, err := h.service.GetItemById(r.Context(), itemID)
+	if item == nil || item.TodoID != todo.ID {
+		fmt.Println(err)
+		message = "checklist item not found"
+		utils.RespondError(w, http.StatusNotFound, ItemNotFound, message, nil)
+		return nil
+	}
+
+	return item
+}
+
+func (h *ChecklistItemHandler) CreateItem(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	todo := h.getTodo(w, r, h.todoService, userID, authz.ActionWrite)
+	if todo == nil {
+		return
+	}
+
+	payload := middleware.GetValidatedRequest[dto.ChecklistItemPayload](r)
+
+	item := model.ChecklistItem{
+		TodoID:   todo.ID,
+		Title:    payload.Title,
+		Done:     payload.Done,
+		Position: -1,
+	}
+	if payload.Position != nil {
+		item.Position = *payload.Position
+	}
+
+	err := h.service.CreateItem(r.Context(), &item)
+	if err != nil {
+		message = "cannot insert checklist item into db"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "create checklist item successfully"
+	utils.RespondSuccess(w, http.StatusCreated, message, item)
+}
+
+func (h *ChecklistItemHandler) GetItems(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	todo := h.getTodo(w, r, h.todoService, userID, authz.ActionRead)
+	if todo == nil {
+		return
+	}
+
+	items, err := h.service.GetItemsByTodoId(r.Context(), todo.ID)
+	if err != nil {
+		message = "cannot get checklist items from db"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "fetch checklist items successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, items)
+}
+
+func (h *ChecklistItemHandler) UpdateItemById(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	todo := h.getTodo(w, r, h.todoService, userID, authz.ActionWrite)
+	if todo == nil {
+		return
+	}
+
+	item := h.getTodoItem(w, r, todo)
+	if item == nil {
+		return
+	}
+
+	payload := middleware.GetValidatedRequest[dto.ChecklistItemPayload](r)
+
+	item.Title = payload.Title
+	item.Done = payload.Done
+	if payload.Position != nil {
+		item.Position = *payload.Position
+	}
+
+	err := h.service.UpdateItemById(r.Context(), item.ID, item)
+	if err != nil {
+		message = "failed to update the checklist item in DB"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "update the checklist item successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, nil)
+}
+
+func (h *ChecklistItemHandler) DeleteItemById(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	todo := h.getTodo(w, r, h.todoService, userID, authz.ActionWrite)
+	if todo == nil {
+		return
+	}
+
+	item := h.getTodoItem(w, r, todo)
+	if item == nil {
+		return
+	}
+
+	err := h.service.DeleteItemById(r.Context(), item.ID)
+	if err != nil {
+		message = "cannot delete the checklist item from DB"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "delete the checklist item successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, nil)
+}
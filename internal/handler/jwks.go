@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/King0625/golang-todolist/pkg/utils"
+)
+
+type JWKSHandler struct {
+	keys *utils.JWTKeySet
+}
+
+func NewJWKSHandler(keys *utils.JWTKeySet) *JWKSHandler {
+	return &JWKSHandler{keys}
+}
+
+// GetJWKS serves the public signing keys as a bare JWK set rather than in the
+// usual response envelope, since JWT libraries of other services read it.
+func (h *JWKSHandler) GetJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	// verifiers refetch on an unknown kid, so rotation does not wait for this
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(h.keys.JWKS())
+}
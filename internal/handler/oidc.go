@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/King0625/golang-todolist/internal/service"
+	"github.com/King0625/golang-todolist/pkg/utils"
+)
+
+// oidcStateCookie ties the callback to the browser that started the login,
+// so nobody can complete a login they started into someone else's browser.
+const oidcStateCookie = "oidc_state"
+
+type OIDCHandler struct {
+	service          service.OIDCService
+	authService      service.AuthService
+	twoFactorService service.TwoFactorService
+}
+
+func NewOIDCHandler(s service.OIDCService, authService service.AuthService, twoFactorService service.TwoFactorService) *OIDCHandler {
+	return &OIDCHandler{s, authService, twoFactorService}
+}
+
+func setOIDCStateCookie(w http.ResponseWriter, r *http.Request, value string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    value,
+		Path:     "/auth/oidc",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https",
+		// Lax, not Strict: the provider redirects back cross-site
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (h *OIDCHandler) Login(w http.ResponseWriter, r *http.Request) {
+	var message string
+
+	authURL, state, err := h.service.StartLogin(r.Context(), r.URL.Query().Get("provider"))
+	if errors.Is(err, service.ErrUnknownOIDCProvider) {
+		message = "unknown identity provider"
+		utils.RespondError(w, http.StatusNotFound, OIDCProviderNotFound, message, nil)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		message = "failed to start login with the identity provider"
+		utils.RespondError(w, http.StatusBadGateway, OIDCLoginFailed, message, nil)
+		return
+	}
+
+	setOIDCStateCookie(w, r, state, int(service.OIDCLoginStateTTL.Seconds()))
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+func (h *OIDCHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	var message string
+	query := r.URL.Query()
+
+	if providerError := query.Get("error"); providerError != "" {
+		message = "the identity provider refused the login: " + providerError
+		utils.RespondError(w, http.StatusUnauthorized, OIDCLoginFailed, message, nil)
+		return
+	}
+
+	state := query.Get("state")
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		message = "invalid login state, please start the login again"
+		utils.RespondError(w, http.StatusBadRequest, OIDCStateInvalid, message, nil)
+		return
+	}
+	setOIDCStateCookie(w, r, "", -1)
+
+	user, err := h.service.CompleteLogin(r.Context(), state, query.Get("code"))
+	if errors.Is(err, service.ErrInvalidOIDCState) {
+		message = "invalid login state, please start the login again"
+		utils.RespondError(w, http.StatusBadRequest, OIDCStateInvalid, message, nil)
+		return
+	}
+	if errors.Is(err, service.ErrOIDCEmailNotVerified) {
+		message = "the identity provider has not verified your email address"
+		utils.RespondError(w, http.StatusForbidden, OIDCEmailNotVerified, message, nil)
+		return
+	}
+	if errors.Is(err, service.ErrOIDCLoginFailed) {
+		log.Println(err)
+		message = "login with the identity provider failed"
+		utils.RespondError(w, http.StatusUnauthorized, OIDCLoginFailed, message, nil)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		message = "login failed"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	respondLogin(w, r, h.authService, h.twoFactorService, user)
+}
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/King0625/golang-todolist/internal/authz"
+	"github.com/King0625/golang-todolist/internal/dto"
+	"github.com/King0625/golang-todolist/internal/middleware"
+	"github.com/King0625/golang-todolist/internal/model"
+	"github.com/King0625/golang-todolist/internal/service"
+	"github.com/King0625/golang-todolist/pkg/utils"
+)
+
+type PersonalAccessTokenHandler struct {
+	authorizer
+	service service.PersonalAccessTokenService
+}
+
+func NewPersonalAccessTokenHandler(s service.PersonalAccessTokenService, policy authz.Policy) *PersonalAccessTokenHandler {
+	return &PersonalAccessTokenHandler{authorizer{policy}, s}
+}
+
+func (h *PersonalAccessTokenHandler) CreateToken(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	payload := middleware.GetValidatedRequest[dto.CreatePersonalAccessTokenPayload](r)
+
+	token := model.PersonalAccessToken{
+		UserID: userID,
+		Name:   payload.Name,
+		Scopes: payload.Scopes,
+	}
+	if payload.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, payload.ExpiresInDays)
+		token.ExpiresAt = &expiresAt
+	}
+
+	raw, err := h.service.CreateToken(r.Context(), &token)
+	if err != nil {
+		fmt.Println(err)
+		message = "cannot insert token into db"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "create token successfully"
+	utils.RespondSuccess(w, http.StatusCreated, message, dto.CreatePersonalAccessTokenData{
+		PersonalAccessToken: &token,
+		Token:               raw,
+	})
+}
+
+func (h *PersonalAccessTokenHandler) GetTokens(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	tokens, err := h.service.GetTokensByUserId(r.Context(), userID)
+	if err != nil {
+		message = "cannot get tokens from db"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "fetch tokens successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, tokens)
+}
+
+func (h *PersonalAccessTokenHandler) RevokeTokenById(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	tokenID, err := strconv.Atoi(r.PathValue("tokenID"))
+	if err != nil {
+		message = "invalid tokenID"
+		utils.RespondError(w, http.StatusBadRequest, ValidationError, message, nil)
+		return
+	}
+
+	token, err := h.service.GetTokenById(r.Context(), tokenID)
+	if token == nil || token.RevokedAt != nil {
+		fmt.Println(err)
+		message = "token not found"
+		utils.RespondError(w, http.StatusNotFound, TokenNotFound, message, nil)
+		return
+	}
+
+	if !h.authorize(w, r, userID, authz.ActionDelete, token, "token") {
+		return
+	}
+
+	if err := h.service.RevokeTokenById(r.Context(), tokenID); err != nil {
+		message = "cannot revoke token"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "revoke token successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, nil)
+}
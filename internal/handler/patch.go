@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/King0625/golang-todolist/internal/dto"
+	"github.com/King0625/golang-todolist/internal/model"
+	"github.com/King0625/golang-todolist/pkg/jsonpatch"
+	"github.com/King0625/golang-todolist/pkg/utils"
+)
+
+const maxPatchBytes = 1048576
+
+// todoPayloadOf returns the editable fields of todo as an update payload, the
+// document patches of the todo apply to.
+func todoPayloadOf(todo *model.Todo) dto.UpdateTodoPayload {
+	return dto.UpdateTodoPayload{
+		CreateTodoPayload: dto.CreateTodoPayload{
+			Title:      todo.Title,
+			Content:    todo.Content,
+			DueDate:    todo.DueDate,
+			DueTime:    todo.DueTime,
+			Timezone:   todo.Timezone,
+			Priority:   todo.Priority.String(),
+			Recurrence: todo.Recurrence,
+			ProjectID:  todo.ProjectID,
+			AssigneeID: todo.AssigneeID,
+		},
+		Done: todo.Done,
+	}
+}
+
+// patchTodoPayload applies the patch in the request body to the update
+// payload of todo. It responds with an error and returns false when the
+// patch has an unsupported media type, is malformed or does not apply.
+func patchTodoPayload(w http.ResponseWriter, r *http.Request, todo *model.Todo) (dto.UpdateTodoPayload, bool) {
+	var message string
+	var payload dto.UpdateTodoPayload
+
+	var apply func(doc, patch []byte) ([]byte, error)
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case jsonpatch.MergePatchType:
+		apply = jsonpatch.MergePatch
+	case jsonpatch.JSONPatchType:
+		apply = jsonpatch.Apply
+	default:
+		w.Header().Set("Accept-Patch", jsonpatch.MergePatchType+", "+jsonpatch.JSONPatchType)
+		message = fmt.Sprintf("send the patch as %s or %s", jsonpatch.MergePatchType, jsonpatch.JSONPatchType)
+		utils.RespondError(w, http.StatusUnsupportedMediaType, UnsupportedMediaType, message, nil)
+		return payload, false
+	}
+
+	patch, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPatchBytes))
+	if err != nil {
+		fmt.Println(err)
+		message = "cannot read the patch"
+		utils.RespondError(w, http.StatusBadRequest, InvalidPatch, message, nil)
+		return payload, false
+	}
+
+	doc, err := json.Marshal(todoPayloadOf(todo))
+	if err != nil {
+		fmt.Println(err)
+		message = "cannot encode the todo"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return payload, false
+	}
+
+	patched, err := apply(doc, patch)
+	switch {
+	case errors.Is(err, jsonpatch.ErrInvalidPatch):
+		message = "invalid patch document"
+		utils.RespondError(w, http.StatusBadRequest, InvalidPatch, message, err.Error())
+		return payload, false
+	case errors.Is(err, jsonpatch.ErrTestFailed):
+		message = "a test operation of the patch failed"
+		utils.RespondError(w, http.StatusConflict, PatchTestFailed, message, err.Error())
+		return payload, false
+	case err != nil:
+		message = "the patch cannot be applied to the todo"
+		utils.RespondError(w, http.StatusUnprocessableEntity, PatchFailed, message, err.Error())
+		return payload, false
+	}
+
+	// patches may only touch the fields of the payload, with the same types
+	dec := json.NewDecoder(bytes.NewReader(patched))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&payload); err != nil {
+		message = "the patched todo is invalid"
+		utils.RespondError(w, http.StatusUnprocessableEntity, PatchFailed, message, err.Error())
+		return payload, false
+	}
+
+	return payload, true
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/King0625/golang-todolist/internal/repository"
+	"github.com/King0625/golang-todolist/pkg/utils"
+)
+
+var ifMatchRequired bool
+
+// RequireIfMatch makes changes to todos without an If-Match header fail with
+// 428 Precondition Required instead of overwriting whatever is stored.
+func RequireIfMatch(required bool) {
+	ifMatchRequired = required
+}
+
+// etagListContains reports whether the If-Match or If-None-Match header value
+// names etag or is "*". Weak tags only match with weak comparison.
+func etagListContains(header, etag string, weak bool) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+		if weak {
+			tag = strings.TrimPrefix(tag, "W/")
+		}
+		if tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// checkIfMatch makes sure the If-Match header of a change names etag, the
+// current version of the resource. It responds with an error and returns
+// false otherwise.
+func checkIfMatch(w http.ResponseWriter, r *http.Request, etag string) bool {
+	var message string
+
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		if !ifMatchRequired {
+			return true
+		}
+		message = "send the ETag of the todo in If-Match to change it"
+		utils.RespondError(w, http.StatusPreconditionRequired, PreconditionRequired, message, nil)
+		return false
+	}
+
+	if !etagListContains(header, etag, false) {
+		w.Header().Set("ETag", etag)
+		respondVersionConflict(w)
+		return false
+	}
+
+	return true
+}
+
+// respondVersionConflict answers changes based on an outdated version with
+// 412.
+func respondVersionConflict(w http.ResponseWriter) {
+	message := "the todo was changed in the meantime; fetch it again and retry"
+	utils.RespondError(w, http.StatusPreconditionFailed, PreconditionFailed, message, nil)
+}
+
+// respondIfVersionConflict answers with 412 when err is
+// repository.ErrVersionConflict, which means the todo changed between
+// checking If-Match and saving it, and reports whether it did.
+func respondIfVersionConflict(w http.ResponseWriter, err error) bool {
+	if !errors.Is(err, repository.ErrVersionConflict) {
+		return false
+	}
+
+	respondVersionConflict(w)
+	return true
+}
+
+// notModified sets the ETag header and answers with 304 when the
+// If-None-Match header of a read names etag. It reports whether it did.
+func notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	w.Header().Set("ETag", etag)
+
+	header := r.Header.Get("If-None-Match")
+	if header == "" || !etagListContains(header, etag, true) {
+		return false
+	}
+
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
@@ -0,0 +1,249 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/King0625/golang-todolist/internal/authz"
+	"github.com/King0625/golang-todolist/internal/dto"
+	"github.com/King0625/golang-todolist/internal/middleware"
+	"github.com/King0625/golang-todolist/internal/model"
+	"github.com/King0625/golang-todolist/internal/service"
+	"github.com/King0625/golang-todolist/pkg/utils"
+	"github.com/go-playground/validator/v10"
+)
+
+type ProjectHandler struct {
+	authorizer
+	service     service.ProjectService
+	todoService service.TodoService
+	validate    *validator.Validate
+}
+
+func NewProjectHandler(s service.ProjectService, todoService service.TodoService, policy authz.Policy) *ProjectHandler {
+	validate := validator.New()
+	return &ProjectHandler{authorizer{policy}, s, todoService, validate}
+}
+
+func (h *ProjectHandler) CreateProject(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	payload := middleware.GetValidatedRequest[dto.ProjectPayload](r)
+
+	project := model.Project{
+		UserID:   userID,
+		Name:     payload.Name,
+		Color:    payload.Color,
+		Archived: payload.Archived,
+		Position: payload.Position,
+	}
+	if workspaceID := middleware.GetWorkspaceID(r); workspaceID != 0 {
+		project.WorkspaceID = &workspaceID
+	}
+
+	err := h.service.CreateProject(r.Context(), &project)
+	if err != nil {
+		message = "cannot insert project into db"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "create project successfully"
+	utils.RespondSuccess(w, http.StatusCreated, message, project)
+}
+
+func (h *ProjectHandler) GetProjects(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	includeArchived, _ := strconv.ParseBool(r.URL.Query().Get("includeArchived"))
+
+	projects, err := h.service.GetProjects(r.Context(), tenantOf(r, userID), includeArchived)
+	if err != nil {
+		message = "cannot get projects from db"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "fetch projects successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, projects)
+}
+
+func (h *ProjectHandler) GetOneProjectByID(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	project := h.getProject(w, r, h.service, userID, authz.ActionRead)
+	if project == nil {
+		return
+	}
+
+	message = "fetch a project successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, project)
+}
+
+func (h *ProjectHandler) UpdateProjectById(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	project := h.getProject(w, r, h.service, userID, authz.ActionWrite)
+	if project == nil {
+		return
+	}
+
+	payload := middleware.GetValidatedRequest[dto.ProjectPayload](r)
+
+	project.Name = payload.Name
+	project.Color = payload.Color
+	project.Archived = payload.Archived
+	project.Position = payload.Position
+
+	err := h.service.UpdateProjectById(r.Context(), project.ID, project)
+	if err != nil {
+		message = "failed to update the project in DB"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "update the project successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, nil)
+}
+
+func (h *ProjectHandler) DeleteProjectById(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	mode := model.ProjectDeleteMode(r.URL.Query().Get("mode"))
+	switch mode {
+	case "":
+		mode = model.ProjectDeleteMoveToInbox
+	case model.ProjectDeleteMoveToInbox, model.ProjectDeleteCascade:
+	default:
+		message = "invalid query parameters"
+		details := map[string]string{"mode": "must be inbox or cascade"}
+		utils.RespondError(w, http.StatusBadRequest, ValidationError, message, details)
+		return
+	}
+
+	project := h.getProject(w, r, h.service, userID, authz.ActionDelete)
+	if project == nil {
+		return
+	}
+
+	err := h.service.DeleteProjectById(r.Context(), project.ID, mode)
+	if err != nil {
+		message = "cannot delete the project from DB"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "delete the project successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, nil)
+}
+
+func (h *ProjectHandler) GetProjectTodos(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	project := h.getProject(w, r, h.service, userID, authz.ActionRead)
+	if project == nil {
+		return
+	}
+
+	opts, details := parseTodoQueryOptions(r.URL.Query())
+	if len(details) > 0 {
+		message = "invalid query parameters"
+		utils.RespondError(w, http.StatusBadRequest, ValidationError, message, details)
+		return
+	}
+	opts.ProjectID = &project.ID
+	opts.Shared, opts.Assigned = false, false
+
+	// the todos of a shared project belong to its owner
+	page, err := h.todoService.ListTodos(r.Context(), project.Tenant(), opts)
+	if errors.Is(err, model.ErrInvalidCursor) {
+		message = "invalid query parameters"
+		utils.RespondError(w, http.StatusBadRequest, ValidationError, message, map[string]string{"cursor": "invalid cursor"})
+		return
+	}
+	if err != nil {
+		message = "cannot get todos from db"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "fetch project todos successfully"
+	meta := dto.PaginationMeta{
+		Limit:      opts.Limit,
+		HasMore:    page.HasMore,
+		NextCursor: page.NextCursor,
+	}
+	utils.RespondSuccessWithMeta(w, http.StatusOK, message, page.Todos, meta)
+}
+
+func (h *ProjectHandler) MoveTodoToProject(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	todo := h.getTodo(w, r, h.todoService, userID, authz.ActionWrite)
+	if todo == nil || !checkIfMatch(w, r, todo.ETag) {
+		return
+	}
+
+	payload := middleware.GetValidatedRequest[dto.MoveTodoPayload](r)
+
+	project, ok := h.getTargetProject(w, r, h.service, userID, payload.ProjectID)
+	if !ok || !canMoveTodo(w, todo, project) {
+		return
+	}
+
+	err := h.todoService.MoveTodoToProject(r.Context(), todo.ID, todo.Version, payload.ProjectID)
+	if respondIfVersionConflict(w, err) {
+		return
+	}
+	if err != nil {
+		message = "failed to move the todo in DB"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "move the todo successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, nil)
+}
@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/King0625/golang-todolist/internal/model"
+)
+
+const (
+	defaultTodoLimit = 20
+	maxTodoLimit     = 100
+)
+
+// parseTodoQueryOptions reads the list query parameters of GET /todos.
+// Every invalid parameter is reported in the returned details map.
+func parseTodoQueryOptions(query url.Values) (model.TodoQueryOptions, map[string]string) {
+	opts := model.TodoQueryOptions{
+		Limit:  defaultTodoLimit,
+		SortBy: model.TodoSortCreatedAt,
+	}
+	details := make(map[string]string)
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxTodoLimit {
+			details["limit"] = "must be an integer between 1 and " + strconv.Itoa(maxTodoLimit)
+		} else {
+			opts.Limit = limit
+		}
+	}
+
+	if v := query.Get("cursor"); v != "" {
+		cursor, err := model.DecodeTodoCursor(v)
+		if err != nil {
+			details["cursor"] = "invalid cursor"
+		} else {
+			opts.Cursor = cursor
+		}
+	}
+
+	if v := query.Get("done"); v != "" {
+		done, err := strconv.ParseBool(v)
+		if err != nil {
+			details["done"] = "must be true or false"
+		} else {
+			opts.Done = &done
+		}
+	}
+
+	if v := query.Get("projectId"); v != "" {
+		projectID, err := strconv.Atoi(v)
+		if err != nil || projectID < 1 {
+			details["projectId"] = "must be a positive integer"
+		} else {
+			opts.ProjectID = &projectID
+		}
+	}
+
+	switch query.Get("scope") {
+	case "", "owned":
+	case "shared":
+		opts.Shared = true
+	default:
+		details["scope"] = "must be owned or shared"
+	}
+
+	// assignee=me looks across all workspaces of the user, an id only at the
+	// current one
+	switch v := query.Get("assignee"); v {
+	case "":
+	case "me":
+		opts.Assigned = true
+		if opts.Shared {
+			details["assignee"] = "cannot be combined with scope=shared"
+		}
+	default:
+		assigneeID, err := strconv.Atoi(v)
+		if err != nil || assigneeID < 1 {
+			details["assignee"] = "must be me or a positive integer"
+		} else {
+			opts.AssigneeID = &assigneeID
+		}
+	}
+
+	opts.Title = query.Get("title")
+
+	timeParams := map[string]**time.Time{
+		"createdFrom": &opts.CreatedFrom,
+		"createdTo":   &opts.CreatedTo,
+		"updatedFrom": &opts.UpdatedFrom,
+		"updatedTo":   &opts.UpdatedTo,
+	}
+	for name, target := range timeParams {
+		v := query.Get(name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			details[name] = "must be an RFC3339 timestamp"
+			continue
+		}
+		*target = &t
+	}
+
+	seenTags := make(map[string]bool)
+	for _, name := range query["tag"] {
+		if name != "" && !seenTags[name] {
+			seenTags[name] = true
+			opts.Tags = append(opts.Tags, name)
+		}
+	}
+
+	switch query.Get("tagMode") {
+	case "", "any":
+	case "all":
+		opts.TagMatchAll = true
+	default:
+		details["tagMode"] = "must be any or all"
+	}
+
+	if v := query.Get("sort"); v != "" {
+		switch field := model.TodoSortField(v); field {
+		case model.TodoSortCreatedAt, model.TodoSortUpdatedAt, model.TodoSortTitle:
+			opts.SortBy = field
+		default:
+			details["sort"] = "must be one of createdAt, updatedAt, title"
+		}
+	}
+
+	switch query.Get("order") {
+	case "", "asc":
+	case "desc":
+		opts.SortDesc = true
+	default:
+		details["order"] = "must be asc or desc"
+	}
+
+	return opts, details
+}
@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/King0625/golang-todolist/internal/authz"
+	"github.com/King0625/golang-todolist/internal/middleware"
+	"github.com/King0625/golang-todolist/internal/service"
+	"github.com/King0625/golang-todolist/pkg/utils"
+)
+
+type RevisionHandler struct {
+	authorizer
+	service     service.RevisionService
+	todoService service.TodoService
+}
+
+func NewRevisionHandler(s service.RevisionService, todoService service.TodoService, policy authz.Policy) *RevisionHandler {
+	return &RevisionHandler{authorizer{policy}, s, todoService}
+}
+
+// respondRevisionNotFound answers with 404 when err is
+// service.ErrRevisionNotFound, and reports whether it did.
+func respondRevisionNotFound(w http.ResponseWriter, err error) bool {
+	if !errors.Is(err, service.ErrRevisionNotFound) {
+		return false
+	}
+
+	message := "revision not found"
+	utils.RespondError(w, http.StatusNotFound, RevisionNotFound, message, nil)
+	return true
+}
+
+func (h *RevisionHandler) GetRevisions(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	todo := h.getTodo(w, r, h.todoService, userID, authz.ActionRead)
+	if todo == nil {
+		return
+	}
+
+	revisions, err := h.service.GetRevisions(r.Context(), todo.ID)
+	if err != nil {
+		fmt.Println(err)
+		message = "cannot get revisions from db"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "fetch revisions successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, revisions)
+}
+
+// DiffRevisions compares the revisions given by the from and to query
+// parameters. Without to, from is compared with the latest revision.
+func (h *RevisionHandler) DiffRevisions(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	query := r.URL.Query()
+	details := make(map[string]string)
+
+	from, err := strconv.Atoi(query.Get("from"))
+	if err != nil || from < 1 {
+		details["from"] = "must be a revision number"
+	}
+	to := 0
+	if v := query.Get("to"); v != "" {
+		if to, err = strconv.Atoi(v); err != nil || to < 1 {
+			details["to"] = "must be a revision number"
+		}
+	}
+	if len(details) > 0 {
+		message = "invalid query parameters"
+		utils.RespondError(w, http.StatusBadRequest, ValidationError, message, details)
+		return
+	}
+
+	todo := h.getTodo(w, r, h.todoService, userID, authz.ActionRead)
+	if todo == nil {
+		return
+	}
+
+	diff, err := h.service.DiffRevisions(r.Context(), todo.ID, from, to)
+	if respondRevisionNotFound(w, err) {
+		return
+	}
+	if err != nil {
+		fmt.Println(err)
+		message = "cannot get revisions from db"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "compare revisions successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, diff)
+}
+
+func (h *RevisionHandler) RestoreRevision(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	revision, err := strconv.Atoi(r.PathValue("rev"))
+	if err != nil || revision < 1 {
+		message = "invalid rev"
+		utils.RespondError(w, http.StatusBadRequest, ValidationError, message, nil)
+		return
+	}
+
+	todo := h.getTodo(w, r, h.todoService, userID, authz.ActionWrite)
+	if todo == nil || !checkIfMatch(w, r, todo.ETag) {
+		return
+	}
+
+	err = h.service.RestoreRevision(r.Context(), todo, revision)
+	if respondRevisionNotFound(w, err) || respondIfVersionConflict(w, err) || respondTodoNotFound(w, err) {
+		return
+	}
+	if err != nil {
+		fmt.Println(err)
+		message = "failed to restore the revision in DB"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	w.Header().Set("ETag", todo.ETag)
+	message = "restore the revision successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, todo)
+}
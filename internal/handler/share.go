@@ -0,0 +1,252 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/King0625/golang-todolist/internal/authz"
+	"github.com/King0625/golang-todolist/internal/dto"
+	"github.com/King0625/golang-todolist/internal/middleware"
+	"github.com/King0625/golang-todolist/internal/model"
+	"github.com/King0625/golang-todolist/internal/service"
+	"github.com/King0625/golang-todolist/pkg/utils"
+)
+
+type ShareHandler struct {
+	authorizer
+	service        service.ShareService
+	todoService    service.TodoService
+	projectService service.ProjectService
+}
+
+func NewShareHandler(s service.ShareService, todoService service.TodoService, projectService service.ProjectService, policy authz.Policy) *ShareHandler {
+	return &ShareHandler{authorizer{policy}, s, todoService, projectService}
+}
+
+// sharedResource is the todo or project the share routes are called on.
+type sharedResource struct {
+	ID      int
+	OwnerID int
+	Title   string
+}
+
+// getSharedResource resolves the todo or project at the path and makes sure
+// userID may share it. It responds with an error and returns nil otherwise.
+func (h *ShareHandler) getSharedResource(w http.ResponseWriter, r *http.Request, userID int, resourceType model.ShareResource) *sharedResource {
+	if resourceType == model.ShareProject {
+		project := h.getProject(w, r, h.projectService, userID, authz.ActionShare)
+		if project == nil {
+			return nil
+		}
+		return &sharedResource{project.ID, project.UserID, project.Name}
+	}
+
+	todo := h.getTodo(w, r, h.todoService, userID, authz.ActionShare)
+	if todo == nil {
+		return nil
+	}
+	return &sharedResource{todo.ID, todo.UserID, todo.Title}
+}
+
+func (h *ShareHandler) createShare(w http.ResponseWriter, r *http.Request, resourceType model.ShareResource) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	resource := h.getSharedResource(w, r, userID, resourceType)
+	if resource == nil {
+		return
+	}
+
+	payload := middleware.GetValidatedRequest[dto.SharePayload](r)
+
+	share := model.Share{
+		ResourceType: resourceType,
+		ResourceID:   resource.ID,
+		OwnerID:      resource.OwnerID,
+		Email:        payload.Email,
+		Permission:   model.SharePermission(payload.Permission),
+	}
+
+	err := h.service.Invite(r.Context(), &share, resource.Title)
+	if errors.Is(err, service.ErrCannotShareWithSelf) {
+		message = "you cannot share with yourself"
+		utils.RespondError(w, http.StatusBadRequest, ValidationError, message, map[string]string{"email": "must not be your own email"})
+		return
+	}
+	if err != nil {
+		fmt.Println(err)
+		message = "cannot share " + string(resourceType)
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "share " + string(resourceType) + " successfully"
+	utils.RespondSuccess(w, http.StatusCreated, message, share)
+}
+
+func (h *ShareHandler) getShares(w http.ResponseWriter, r *http.Request, resourceType model.ShareResource) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	resource := h.getSharedResource(w, r, userID, resourceType)
+	if resource == nil {
+		return
+	}
+
+	shares, err := h.service.GetSharesByResource(r.Context(), resourceType, resource.ID)
+	if err != nil {
+		fmt.Println(err)
+		message = "cannot get shares from db"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "fetch shares successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, shares)
+}
+
+func (h *ShareHandler) deleteShare(w http.ResponseWriter, r *http.Request, resourceType model.ShareResource) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	resource := h.getSharedResource(w, r, userID, resourceType)
+	if resource == nil {
+		return
+	}
+
+	shareID, err := strconv.Atoi(r.PathValue("shareID"))
+	if err != nil {
+		message = "invalid shareID"
+		utils.RespondError(w, http.StatusBadRequest, ValidationError, message, nil)
+		return
+	}
+
+	share, err := h.service.GetShareById(r.Context(), shareID)
+	if share == nil || share.ResourceType != resourceType || share.ResourceID != resource.ID {
+		fmt.Println(err)
+		message = "share not found"
+		utils.RespondError(w, http.StatusNotFound, ShareNotFound, message, nil)
+		return
+	}
+
+	if err := h.service.RevokeShareById(r.Context(), share.ID); err != nil {
+		fmt.Println(err)
+		message = "cannot delete the share from DB"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "delete the share successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, nil)
+}
+
+func (h *ShareHandler) CreateTodoShare(w http.ResponseWriter, r *http.Request) {
+	h.createShare(w, r, model.ShareTodo)
+}
+
+func (h *ShareHandler) GetTodoShares(w http.ResponseWriter, r *http.Request) {
+	h.getShares(w, r, model.ShareTodo)
+}
+
+func (h *ShareHandler) DeleteTodoShare(w http.ResponseWriter, r *http.Request) {
+	h.deleteShare(w, r, model.ShareTodo)
+}
+
+func (h *ShareHandler) CreateProjectShare(w http.ResponseWriter, r *http.Request) {
+	h.createShare(w, r, model.ShareProject)
+}
+
+func (h *ShareHandler) GetProjectShares(w http.ResponseWriter, r *http.Request) {
+	h.getShares(w, r, model.ShareProject)
+}
+
+func (h *ShareHandler) DeleteProjectShare(w http.ResponseWriter, r *http.Request) {
+	h.deleteShare(w, r, model.ShareProject)
+}
+
+func (h *ShareHandler) GetInvitations(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	invitations, err := h.service.GetInvitations(r.Context(), userID)
+	if err != nil {
+		fmt.Println(err)
+		message = "cannot get invitations from db"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "fetch invitations successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, invitations)
+}
+
+func (h *ShareHandler) respondToInvitation(w http.ResponseWriter, r *http.Request, accept bool) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	shareID, err := strconv.Atoi(r.PathValue("shareID"))
+	if err != nil {
+		message = "invalid shareID"
+		utils.RespondError(w, http.StatusBadRequest, ValidationError, message, nil)
+		return
+	}
+
+	err = h.service.RespondToInvitation(r.Context(), userID, shareID, accept)
+	if errors.Is(err, service.ErrInvitationNotFound) {
+		message = "invitation not found"
+		utils.RespondError(w, http.StatusNotFound, InvitationNotFound, message, nil)
+		return
+	}
+	if errors.Is(err, service.ErrEmailNotVerified) {
+		message = "please verify your email address before accepting invitations"
+		utils.RespondError(w, http.StatusForbidden, EmailNotVerified, message, nil)
+		return
+	}
+	if err != nil {
+		fmt.Println(err)
+		message = "cannot respond to the invitation"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "decline the invitation successfully"
+	if accept {
+		message = "accept the invitation successfully"
+	}
+	utils.RespondSuccess(w, http.StatusOK, message, nil)
+}
+
+func (h *ShareHandler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	h.respondToInvitation(w, r, true)
+}
+
+func (h *ShareHandler) DeclineInvitation(w http.ResponseWriter, r *http.Request) {
+	h.respondToInvitation(w, r, false)
+}
@@ -0,0 +1,265 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/King0625/golang-todolist/internal/authz"
+	"github.com/King0625/golang-todolist/internal/dto"
+	"github.com/King0625/golang-todolist/internal/middleware"
+	"github.com/King0625/golang-todolist/internal/model"
+	"github.com/King0625/golang-todolist/internal/repository"
+	"github.com/King0625/golang-todolist/internal/service"
+	"github.com/King0625/golang-todolist/pkg/utils"
+	"github.com/go-playground/validator/v10"
+)
+
+type TagHandler struct {
+	authorizer
+	service     service.TagService
+	todoService service.TodoService
+	validate    *validator.Validate
+}
+
+func NewTagHandler(s service.TagService, todoService service.TodoService, policy authz.Policy) *TagHandler {
+	validate := validator.New()
+	return &TagHandler{authorizer{policy}, s, todoService, validate}
+}
+
+func (h *TagHandler) CreateTag(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	payload := middleware.GetValidatedRequest[dto.TagPayload](r)
+
+	tag := model.Tag{
+		UserID: userID,
+		Name:   payload.Name,
+		Color:  payload.Color,
+	}
+	if workspaceID := middleware.GetWorkspaceID(r); workspaceID != 0 {
+		tag.WorkspaceID = &workspaceID
+	}
+
+	err := h.service.CreateTag(r.Context(), &tag)
+	if errors.Is(err, repository.ErrDuplicateTag) {
+		message = "a tag with this name already exists"
+		utils.RespondError(w, http.StatusConflict, TagAlreadyExists, message, nil)
+		return
+	}
+	if err != nil {
+		message = "cannot insert tag into db"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "create tag successfully"
+	utils.RespondSuccess(w, http.StatusCreated, message, tag)
+}
+
+func (h *TagHandler) GetTags(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	tags, err := h.service.GetTags(r.Context(), tenantOf(r, userID))
+	if err != nil {
+		message = "cannot get tags from db"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "fetch tags successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, tags)
+}
+
+// getTag resolves the tag at the given path parameter and makes sure userID
+// may perform action on it. It responds with an error and returns nil
+// otherwise.
+func (h *TagHandler) getTag(w http.ResponseWriter, r *http.Request, param string, userID int, action authz.Action) *model.Tag {
+	var message string
+
+	tagID, err := strconv.Atoi(r.PathValue(param))
+	if err != nil {
+		message = "invalid tagID"
+		utils.RespondError(w, http.StatusBadRequest, ValidationError, message, nil)
+		return nil
+	}
+
+	tag, err := h.service.GetTagById(r.Context(), tagID)
+	if tag == nil || !inTenant(r, tag) {
+		fmt.Println(err)
+		message = "tag not found"
+		utils.RespondError(w, http.StatusNotFound, TagNotFound, message, nil)
+		return nil
+	}
+
+	if !h.authorize(w, r, userID, action, tag, "tag") {
+		return nil
+	}
+
+	return tag
+}
+
+func (h *TagHandler) GetOneTagByID(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	tag := h.getTag(w, r, "tagID", userID, authz.ActionRead)
+	if tag == nil {
+		return
+	}
+
+	message = "fetch a tag successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, tag)
+}
+
+func (h *TagHandler) UpdateTagById(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	tag := h.getTag(w, r, "tagID", userID, authz.ActionWrite)
+	if tag == nil {
+		return
+	}
+
+	payload := middleware.GetValidatedRequest[dto.TagPayload](r)
+
+	err := h.service.UpdateTagById(r.Context(), tag.ID, payload.Name, payload.Color)
+	if errors.Is(err, repository.ErrDuplicateTag) {
+		message = "a tag with this name already exists"
+		utils.RespondError(w, http.StatusConflict, TagAlreadyExists, message, nil)
+		return
+	}
+	if err != nil {
+		message = "failed to update the tag in DB"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "update the tag successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, nil)
+}
+
+func (h *TagHandler) DeleteTagById(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	tag := h.getTag(w, r, "tagID", userID, authz.ActionDelete)
+	if tag == nil {
+		return
+	}
+
+	err := h.service.DeleteTagById(r.Context(), tag.ID)
+	if err != nil {
+		message = "cannot delete the tag from DB"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "delete the tag successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, nil)
+}
+
+func (h *TagHandler) AttachTagsToTodo(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	todo := h.getTodo(w, r, h.todoService, userID, authz.ActionWrite)
+	if todo == nil {
+		return
+	}
+
+	payload := middleware.GetValidatedRequest[dto.AttachTagsPayload](r)
+
+	for _, tagID := range payload.TagIDs {
+		tag, err := h.service.GetTagById(r.Context(), tagID)
+		if tag != nil {
+			err = h.check(r, userID, authz.ActionRead, tag)
+		}
+		// tags of others are reported as not found; a todo only takes tags
+		// of its own tenant, so on a shared todo only the owner's tags
+		if tag == nil || errors.Is(err, authz.ErrForbidden) || !tag.Tenant().Equal(todo.Tenant()) {
+			fmt.Println(err)
+			message = "tag not found"
+			utils.RespondError(w, http.StatusNotFound, TagNotFound, message, map[string]int{"tagId": tagID})
+			return
+		}
+		if err != nil {
+			message = "cannot check permissions"
+			utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+			return
+		}
+	}
+
+	err := h.service.AttachTagsToTodo(r.Context(), todo.ID, payload.TagIDs)
+	if err != nil {
+		message = "failed to attach tags to the todo in DB"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "attach tags to the todo successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, nil)
+}
+
+func (h *TagHandler) DetachTagFromTodo(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	todo := h.getTodo(w, r, h.todoService, userID, authz.ActionWrite)
+	if todo == nil {
+		return
+	}
+
+	tag := h.getTag(w, r, "tagID", userID, authz.ActionRead)
+	if tag == nil {
+		return
+	}
+
+	err := h.service.DetachTagFromTodo(r.Context(), todo.ID, tag.ID)
+	if err != nil {
+		message = "failed to detach the tag from the todo in DB"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "detach the tag from the todo successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, nil)
+}
@@ -1,26 +1,80 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 
+	"github.com/King0625/golang-todolist/internal/authz"
 	"github.com/King0625/golang-todolist/internal/dto"
 	"github.com/King0625/golang-todolist/internal/middleware"
 	"github.com/King0625/golang-todolist/internal/model"
+	"github.com/King0625/golang-todolist/internal/repository"
 	"github.com/King0625/golang-todolist/internal/service"
+	"github.com/King0625/golang-todolist/pkg/search"
 	"github.com/King0625/golang-todolist/pkg/utils"
 	"github.com/go-playground/validator/v10"
 )
 
+const (
+	defaultUpcomingDays = 7
+	maxUpcomingDays     = 365
+)
+
+// applyTodoPayload copies the user-editable fields of a create/update payload onto todo.
+func applyTodoPayload(todo *model.Todo, payload dto.CreateTodoPayload) error {
+	priority, err := model.ParsePriority(payload.Priority)
+	if err != nil {
+		return err
+	}
+	if err := todo.SetDue(payload.DueDate, payload.DueTime, payload.Timezone); err != nil {
+		return err
+	}
+
+	todo.Title = payload.Title
+	todo.Content = payload.Content
+	todo.Priority = priority
+	todo.ProjectID = payload.ProjectID
+	todo.AssigneeID = payload.AssigneeID
+	return todo.SetRecurrence(payload.Recurrence)
+}
+
+// respondInvalidAssignee answers with 400 when err is
+// service.ErrInvalidAssignee, and reports whether it did.
+func respondInvalidAssignee(w http.ResponseWriter, err error) bool {
+	if !errors.Is(err, service.ErrInvalidAssignee) {
+		return false
+	}
+
+	message := "validation failed"
+	utils.RespondError(w, http.StatusBadRequest, ValidationError, message, map[string]string{"assigneeId": "must be a member of the todo's workspace"})
+	return true
+}
+
+// respondTodoNotFound answers with 404 when err is service.ErrTodoNotFound,
+// which means the todo was trashed or purged after it was read, and reports
+// whether it did.
+func respondTodoNotFound(w http.ResponseWriter, err error) bool {
+	if !errors.Is(err, service.ErrTodoNotFound) {
+		return false
+	}
+
+	message := "todo not found"
+	utils.RespondError(w, http.StatusNotFound, TodoNotFound, message, nil)
+	return true
+}
+
 type TodoHandler struct {
-	service  service.TodoService
-	validate *validator.Validate
+	authorizer
+	service        service.TodoService
+	projectService service.ProjectService
+	validate       *validator.Validate
 }
 
-func NewTodoHandler(s service.TodoService) *TodoHandler {
+func NewTodoHandler(s service.TodoService, projectService service.ProjectService, policy authz.Policy) *TodoHandler {
 	validate := validator.New()
-	return &TodoHandler{s, validate}
+	return &TodoHandler{authorizer{policy}, s, projectService, validate}
 }
 
 func (h *TodoHandler) CreateTodo(w http.ResponseWriter, r *http.Request) {
@@ -34,13 +88,28 @@ func (h *TodoHandler) CreateTodo(w http.ResponseWriter, r *http.Request) {
 
 	payload := middleware.GetValidatedRequest[dto.CreateTodoPayload](r)
 
-	todo := model.Todo{
-		UserID:  userID,
-		Title:   payload.Title,
-		Content: payload.Content,
+	project, ok := h.getTargetProject(w, r, h.projectService, userID, payload.ProjectID)
+	if !ok {
+		return
+	}
+
+	todo := model.Todo{UserID: userID}
+	if workspaceID := middleware.GetWorkspaceID(r); workspaceID != 0 {
+		todo.WorkspaceID = &workspaceID
+	} else if project != nil {
+		// todos added to a shared project belong to the project's owner
+		todo.UserID = project.UserID
+	}
+	if err := applyTodoPayload(&todo, payload); err != nil {
+		message = "validation failed"
+		utils.RespondError(w, http.StatusBadRequest, ValidationError, message, err.Error())
+		return
 	}
 
 	err := h.service.CreateTodo(r.Context(), &todo)
+	if respondInvalidAssignee(w, err) {
+		return
+	}
 	if err != nil {
 		message = "cannot insert todo into db"
 		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
@@ -60,7 +129,19 @@ func (h *TodoHandler) GetTodos(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	todos, err := h.service.GetTodosByUserId(r.Context(), userID)
+	opts, details := parseTodoQueryOptions(r.URL.Query())
+	if len(details) > 0 {
+		message = "invalid query parameters"
+		utils.RespondError(w, http.StatusBadRequest, ValidationError, message, details)
+		return
+	}
+
+	page, err := h.service.ListTodos(r.Context(), tenantOf(r, userID), opts)
+	if errors.Is(err, model.ErrInvalidCursor) {
+		message = "invalid query parameters"
+		utils.RespondError(w, http.StatusBadRequest, ValidationError, message, map[string]string{"cursor": "invalid cursor"})
+		return
+	}
 	if err != nil {
 		message = "cannot get todos from db"
 		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
@@ -68,10 +149,35 @@ func (h *TodoHandler) GetTodos(w http.ResponseWriter, r *http.Request) {
 	}
 
 	message = "fetch todos successfully"
+	meta := dto.PaginationMeta{
+		Limit:      opts.Limit,
+		HasMore:    page.HasMore,
+		NextCursor: page.NextCursor,
+	}
+	utils.RespondSuccessWithMeta(w, http.StatusOK, message, page.Todos, meta)
+}
+
+func (h *TodoHandler) GetOverdueTodos(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	todos, err := h.service.GetOverdueTodos(r.Context(), tenantOf(r, userID))
+	if err != nil {
+		message = "cannot get overdue todos from db"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "fetch overdue todos successfully"
 	utils.RespondSuccess(w, http.StatusOK, message, todos)
 }
 
-func (h *TodoHandler) GetOneTodoByID(w http.ResponseWriter, r *http.Request) {
+func (h *TodoHandler) GetUpcomingTodos(w http.ResponseWriter, r *http.Request) {
 	var message string
 	userID, ok := middleware.GetUserID(r)
 	if !ok {
@@ -80,27 +186,81 @@ func (h *TodoHandler) GetOneTodoByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	todoIDString := r.PathValue("todoID")
-	todoID, err := strconv.Atoi(todoIDString)
+	days := defaultUpcomingDays
+	if v := r.URL.Query().Get("days"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed < 1 || parsed > maxUpcomingDays {
+			message = "invalid query parameters"
+			details := map[string]string{"days": "must be an integer between 1 and " + strconv.Itoa(maxUpcomingDays)}
+			utils.RespondError(w, http.StatusBadRequest, ValidationError, message, details)
+			return
+		}
+		days = parsed
+	}
 
+	todos, err := h.service.GetUpcomingTodos(r.Context(), tenantOf(r, userID), days)
 	if err != nil {
-		message = "invalid todoID"
-		utils.RespondError(w, http.StatusBadRequest, ValidationError, message, nil)
+		message = "cannot get upcoming todos from db"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
 		return
 	}
 
-	todo, err := h.service.GetTodoById(r.Context(), todoID)
-	if todo == nil {
-		fmt.Println(err)
-		message = "todo not found"
-		utils.RespondError(w, http.StatusNotFound, TodoNotFound, message, nil)
+	message = "fetch upcoming todos successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, todos)
+}
+
+func (h *TodoHandler) SearchTodos(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	details := make(map[string]string)
+
+	query, err := search.Parse(r.URL.Query().Get("q"))
+	if err != nil {
+		details["q"] = "must contain at least one word or phrase to search for"
+	}
+
+	limit := defaultTodoLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxTodoLimit {
+			details["limit"] = "must be an integer between 1 and " + strconv.Itoa(maxTodoLimit)
+		}
+	}
+
+	if len(details) > 0 {
+		message = "invalid query parameters"
+		utils.RespondError(w, http.StatusBadRequest, ValidationError, message, details)
+		return
+	}
+
+	results, err := h.service.SearchTodos(r.Context(), tenantOf(r, userID), query, limit)
+	if err != nil {
+		message = "cannot search todos in db"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "search todos successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, results)
+}
+
+func (h *TodoHandler) GetOneTodoByID(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
 		return
 	}
 
-	userIDInTodo := todo.UserID
-	if userID != userIDInTodo {
-		message = "this is not your todo"
-		utils.RespondError(w, http.StatusForbidden, PermissionDenied, message, nil)
+	todo := h.getTodo(w, r, h.service, userID, authz.ActionRead)
+	if todo == nil || notModified(w, r, todo.ETag) {
 		return
 	}
 
@@ -117,40 +277,85 @@ func (h *TodoHandler) UpdateTodoById(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	todoIDString := r.PathValue("todoID")
-	todoID, err := strconv.Atoi(todoIDString)
-
-	if err != nil {
-		message = "invalid todoID"
-		utils.RespondError(w, http.StatusBadRequest, ValidationError, message, nil)
+	todo := h.getTodo(w, r, h.service, userID, authz.ActionWrite)
+	if todo == nil || !checkIfMatch(w, r, todo.ETag) {
 		return
 	}
 
 	payload := middleware.GetValidatedRequest[dto.UpdateTodoPayload](r)
+	h.saveTodo(w, r, userID, todo, payload)
+}
 
-	todo, err := h.service.GetTodoById(r.Context(), todoID)
-	if todo == nil {
-		fmt.Println(err)
-		message = "todo not found"
-		utils.RespondError(w, http.StatusNotFound, TodoNotFound, message, nil)
+// PatchTodoById changes the todo with a JSON merge patch (RFC 7396) or a JSON
+// patch (RFC 6902), as told by the Content-Type. The patch applies to the todo
+// in the shape of dto.UpdateTodoPayload, and the result is validated like a
+// full update.
+func (h *TodoHandler) PatchTodoById(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
 		return
 	}
 
-	userIDInTodo := todo.UserID
-	if userIDInTodo != userID {
-		message = "this is not your todo"
-		utils.RespondError(w, http.StatusForbidden, PermissionDenied, message, nil)
+	todo := h.getTodo(w, r, h.service, userID, authz.ActionWrite)
+	if todo == nil || !checkIfMatch(w, r, todo.ETag) {
 		return
 	}
 
-	err = h.service.UpdateTodoById(r.Context(), todoID, payload.Title, payload.Title, payload.Done)
+	payload, ok := patchTodoPayload(w, r, todo)
+	if !ok {
+		return
+	}
+
+	if details := middleware.ValidateStruct(payload); details != nil {
+		message = "validation failed"
+		utils.RespondError(w, http.StatusBadRequest, ValidationError, message, details)
+		return
+	}
+
+	h.saveTodo(w, r, userID, todo, payload)
+}
+
+// saveTodo applies a validated update payload to todo and saves it.
+func (h *TodoHandler) saveTodo(w http.ResponseWriter, r *http.Request, userID int, todo *model.Todo, payload dto.UpdateTodoPayload) {
+	var message string
+
+	project, ok := h.getTargetProject(w, r, h.projectService, userID, payload.ProjectID)
+	if !ok || !canMoveTodo(w, todo, project) {
+		return
+	}
+
+	if err := applyTodoPayload(todo, payload.CreateTodoPayload); err != nil {
+		message = "validation failed"
+		utils.RespondError(w, http.StatusBadRequest, ValidationError, message, err.Error())
+		return
+	}
+	todo.Done = payload.Done
+
+	next, err := h.service.UpdateTodoById(r.Context(), todo.ID, todo)
+	if respondInvalidAssignee(w, err) || respondIfVersionConflict(w, err) || respondTodoNotFound(w, err) {
+		return
+	}
+	if errors.Is(err, repository.ErrOpenChecklistItems) {
+		message = "the todo still has open checklist items"
+		utils.RespondError(w, http.StatusConflict, TodoHasOpenItems, message, nil)
+		return
+	}
 	if err != nil {
 		message = "failed to update the todo in DB"
 		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
 		return
 	}
 
+	w.Header().Set("ETag", todo.ETag)
 	message = "update the todo successfully"
+	if next != nil {
+		data := dto.MarkDoneData{NextOccurrence: next}
+		utils.RespondSuccess(w, http.StatusOK, message, data)
+		return
+	}
 	utils.RespondSuccess(w, http.StatusOK, message, nil)
 }
 
@@ -163,43 +368,64 @@ func (h *TodoHandler) MarkTodoDoneById(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	todoIDString := r.PathValue("todoID")
-	todoID, err := strconv.Atoi(todoIDString)
+	todo := h.getTodo(w, r, h.service, userID, authz.ActionWrite)
+	if todo == nil || !checkIfMatch(w, r, todo.ETag) {
+		return
+	}
 
+	next, err := h.service.MarkTodoDoneById(r.Context(), todo.ID, todo.Version)
+	if respondIfVersionConflict(w, err) {
+		return
+	}
+	if errors.Is(err, repository.ErrOpenChecklistItems) {
+		message = "the todo still has open checklist items"
+		utils.RespondError(w, http.StatusConflict, TodoHasOpenItems, message, nil)
+		return
+	}
 	if err != nil {
-		message = "invalid todoID"
-		utils.RespondError(w, http.StatusBadRequest, ValidationError, message, nil)
+		message = "failed to mark the todo done in DB"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
 		return
 	}
 
-	todo, err := h.service.GetTodoById(r.Context(), todoID)
-	if todo == nil {
-		fmt.Println(err)
-		message = "todo not found"
-		utils.RespondError(w, http.StatusNotFound, TodoNotFound, message, nil)
+	message = "mark the todo done successfully"
+	if next != nil {
+		data := dto.MarkDoneData{NextOccurrence: next}
+		utils.RespondSuccess(w, http.StatusOK, message, data)
 		return
 	}
+	utils.RespondSuccess(w, http.StatusOK, message, nil)
+}
 
-	userIDInTodo := todo.UserID
-	if userIDInTodo != userID {
-		message = "this is not your todo"
-		utils.RespondError(w, http.StatusForbidden, PermissionDenied, message, nil)
+func (h *TodoHandler) DeleteTodoById(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
 		return
 	}
 
-	err = h.service.MarkTodoDoneById(r.Context(), todoID)
+	todo := h.getTodo(w, r, h.service, userID, authz.ActionDelete)
+	if todo == nil || !checkIfMatch(w, r, todo.ETag) {
+		return
+	}
 
+	err := h.service.DeleteTodoById(r.Context(), todo.ID, todo.Version)
+	if respondIfVersionConflict(w, err) {
+		return
+	}
 	if err != nil {
-		message = "failed to mark the todo done in DB"
+		message = "cannot delete the todo from DB"
 		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
 		return
 	}
 
-	message = "mark the todo done successfully"
+	message = "move the todo to the trash successfully"
 	utils.RespondSuccess(w, http.StatusOK, message, nil)
 }
 
-func (h *TodoHandler) DeleteTodoById(w http.ResponseWriter, r *http.Request) {
+func (h *TodoHandler) GetTrash(w http.ResponseWriter, r *http.Request) {
 	var message string
 	userID, ok := middleware.GetUserID(r)
 	if !ok {
@@ -208,37 +434,95 @@ func (h *TodoHandler) DeleteTodoById(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	todoIDString := r.PathValue("todoID")
-	todoID, err := strconv.Atoi(todoIDString)
+	todos, err := h.service.GetTrash(r.Context(), tenantOf(r, userID))
+	if err != nil {
+		message = "cannot get trashed todos from db"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "fetch trashed todos successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, todos)
+}
+
+// getTrashedTodo is getTodo for todos in the trash.
+func (h *TodoHandler) getTrashedTodo(w http.ResponseWriter, r *http.Request, userID int, action authz.Action) *model.Todo {
+	var message string
 
+	todoID, err := strconv.Atoi(r.PathValue("todoID"))
 	if err != nil {
 		message = "invalid todoID"
 		utils.RespondError(w, http.StatusBadRequest, ValidationError, message, nil)
-		return
+		return nil
 	}
 
-	todo, err := h.service.GetTodoById(r.Context(), todoID)
-	if todo == nil {
+	todo, err := h.service.GetTrashedTodoById(r.Context(), todoID)
+	if todo == nil || !inTenant(r, todo) {
 		fmt.Println(err)
-		message = "todo not found"
+		message = "todo not found in trash"
 		utils.RespondError(w, http.StatusNotFound, TodoNotFound, message, nil)
+		return nil
+	}
+
+	if !h.authorize(w, r, userID, action, todo, "todo") {
+		return nil
+	}
+
+	return todo
+}
+
+func (h *TodoHandler) RestoreTodoById(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	todo := h.getTrashedTodo(w, r, userID, authz.ActionWrite)
+	if todo == nil || !checkIfMatch(w, r, todo.ETag) {
+		return
+	}
+
+	err := h.service.RestoreTodoById(r.Context(), todo.ID, todo.Version)
+	if respondIfVersionConflict(w, err) {
+		return
+	}
+	if err != nil {
+		message = "cannot restore the todo in DB"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "restore the todo successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, nil)
+}
+
+func (h *TodoHandler) PurgeTodoById(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
 		return
 	}
 
-	userIDInTodo := todo.UserID
-	if userIDInTodo != userID {
-		message = "this is not your todo"
-		utils.RespondError(w, http.StatusForbidden, PermissionDenied, message, nil)
+	todo := h.getTrashedTodo(w, r, userID, authz.ActionDelete)
+	if todo == nil || !checkIfMatch(w, r, todo.ETag) {
 		return
 	}
 
-	err = h.service.DeleteTodoById(r.Context(), todoID)
+	err := h.service.PurgeTodoById(r.Context(), todo.ID, todo.Version)
+	if respondIfVersionConflict(w, err) {
+		return
+	}
 	if err != nil {
 		message = "cannot delete the todo from DB"
 		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
 		return
 	}
 
-	message = "delete the todo successfully"
+	message = "delete the todo permanently successfully"
 	utils.RespondSuccess(w, http.StatusOK, message, nil)
 }
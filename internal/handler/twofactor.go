@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/King0625/golang-todolist/internal/dto"
+	"github.com/King0625/golang-todolist/internal/middleware"
+	"github.com/King0625/golang-todolist/internal/service"
+	"github.com/King0625/golang-todolist/pkg/utils"
+)
+
+type TwoFactorHandler struct {
+	service service.TwoFactorService
+}
+
+func NewTwoFactorHandler(s service.TwoFactorService) *TwoFactorHandler {
+	return &TwoFactorHandler{s}
+}
+
+// respondTwoFactorError maps the errors shared by the 2FA endpoints.
+func respondTwoFactorError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrTwoFactorAlreadyEnabled):
+		utils.RespondError(w, http.StatusConflict, TwoFactorAlreadyEnabled, "two-factor authentication is already enabled", nil)
+	case errors.Is(err, service.ErrTwoFactorNotEnabled):
+		utils.RespondError(w, http.StatusConflict, TwoFactorNotEnabled, "two-factor authentication is not enabled", nil)
+	case errors.Is(err, service.ErrTwoFactorSetupMissing):
+		utils.RespondError(w, http.StatusConflict, TwoFactorSetupMissing, "start two-factor setup first", nil)
+	case errors.Is(err, service.ErrInvalidTwoFactorCode):
+		utils.RespondError(w, http.StatusBadRequest, InvalidTwoFactorCode, "invalid two-factor code", nil)
+	case errors.Is(err, service.ErrWrongPassword):
+		utils.RespondError(w, http.StatusForbidden, WrongPassword, "wrong password", nil)
+	default:
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, fallback, nil)
+	}
+}
+
+func (h *TwoFactorHandler) BeginSetup(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	setup, err := h.service.BeginSetup(r.Context(), userID)
+	if err != nil {
+		respondTwoFactorError(w, err, "failed to start two-factor setup")
+		return
+	}
+
+	message = "scan the otpauth uri with your authenticator app, then confirm a code"
+	utils.RespondSuccess(w, http.StatusOK, message, setup)
+}
+
+func (h *TwoFactorHandler) ConfirmSetup(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	payload := middleware.GetValidatedRequest[dto.TwoFactorCodePayload](r)
+
+	codes, err := h.service.ConfirmSetup(r.Context(), userID, payload.Code)
+	if err != nil {
+		respondTwoFactorError(w, err, "failed to enable two-factor authentication")
+		return
+	}
+
+	message = "two-factor authentication enabled, store your recovery codes safely"
+	utils.RespondSuccess(w, http.StatusOK, message, dto.RecoveryCodesData{RecoveryCodes: codes})
+}
+
+func (h *TwoFactorHandler) Disable(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	payload := middleware.GetValidatedRequest[dto.DisableTwoFactorPayload](r)
+
+	if err := h.service.Disable(r.Context(), userID, payload.Password, payload.Code); err != nil {
+		respondTwoFactorError(w, err, "failed to disable two-factor authentication")
+		return
+	}
+
+	message = "two-factor authentication disabled"
+	utils.RespondSuccess(w, http.StatusOK, message, nil)
+}
+
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	payload := middleware.GetValidatedRequest[dto.TwoFactorCodePayload](r)
+
+	codes, err := h.service.RegenerateRecoveryCodes(r.Context(), userID, payload.Code)
+	if err != nil {
+		respondTwoFactorError(w, err, "failed to regenerate recovery codes")
+		return
+	}
+
+	message = "recovery codes regenerated, the old ones no longer work"
+	utils.RespondSuccess(w, http.StatusOK, message, dto.RecoveryCodesData{RecoveryCodes: codes})
+}
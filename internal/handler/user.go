@@ -1,8 +1,11 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/King0625/golang-todolist/internal/dto"
@@ -14,13 +17,15 @@ import (
 )
 
 type UserHandler struct {
-	service  service.UserService
-	validate *validator.Validate
+	service          service.UserService
+	authService      service.AuthService
+	twoFactorService service.TwoFactorService
+	validate         *validator.Validate
 }
 
-func NewUserHandler(s service.UserService) *UserHandler {
+func NewUserHandler(s service.UserService, authService service.AuthService, twoFactorService service.TwoFactorService) *UserHandler {
 	validate := validator.New()
-	return &UserHandler{s, validate}
+	return &UserHandler{s, authService, twoFactorService, validate}
 }
 
 func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
@@ -55,14 +60,56 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 	var message string
 	payload := middleware.GetValidatedRequest[dto.LoginPayload](r)
 
-	user, err := h.service.Login(r.Context(), payload.Email, payload.Password)
+	user, err := h.service.Login(r.Context(), payload.Email, payload.Password, middleware.ClientIP(r))
+	if respondLocked(w, err) {
+		return
+	}
+	if errors.Is(err, service.ErrEmailNotVerified) {
+		message = "please verify your email address before logging in"
+		utils.RespondError(w, http.StatusForbidden, EmailNotVerified, message, nil)
+		return
+	}
 	if err != nil {
 		message = "login failed"
 		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
 		return
 	}
 
-	jwtToken, err := utils.NewToken(user.FirstName+user.LastName, user.ID)
+	respondLogin(w, r, h.authService, h.twoFactorService, user)
+}
+
+// respondLogin finishes a login whose first factor checked out: it issues
+// tokens, or a two-factor challenge for users with 2FA enabled.
+func respondLogin(w http.ResponseWriter, r *http.Request, authService service.AuthService, twoFactorService service.TwoFactorService, user *model.User) {
+	var message string
+
+	// checked before 2FA too, so a disabled user is not asked for a code first
+	if user.Disabled() {
+		respondDisabled(w, service.ErrAccountDisabled)
+		return
+	}
+
+	if user.TwoFactorEnabled() {
+		challenge, err := twoFactorService.StartLogin(r.Context(), user)
+		if err != nil {
+			message = "failed to start two-factor login"
+			utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+			return
+		}
+
+		message = "two-factor authentication required"
+		utils.RespondSuccess(w, http.StatusOK, message, dto.TwoFactorChallengeData{
+			TwoFactorRequired: true,
+			ChallengeToken:    challenge,
+			ExpiresIn:         int(service.TwoFactorChallengeTTL.Seconds()),
+		})
+		return
+	}
+
+	tokens, err := authService.IssueTokens(r.Context(), user)
+	if respondDisabled(w, err) {
+		return
+	}
 	if err != nil {
 		message = "failed to issue jwt token from server"
 		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
@@ -70,8 +117,135 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	message = "login successfully"
-	data := dto.LoginSuccessData{Token: jwtToken}
-	utils.RespondSuccess(w, http.StatusOK, message, data)
+	utils.RespondSuccess(w, http.StatusOK, message, loginSuccessData(tokens))
+}
+
+func (h *UserHandler) CompleteTwoFactorLogin(w http.ResponseWriter, r *http.Request) {
+	var message string
+	payload := middleware.GetValidatedRequest[dto.TwoFactorLoginPayload](r)
+
+	user, err := h.twoFactorService.CompleteLogin(r.Context(), payload.ChallengeToken, payload.Code, middleware.ClientIP(r))
+	if respondLocked(w, err) {
+		return
+	}
+	if errors.Is(err, service.ErrInvalidTwoFactorChallenge) {
+		message = "invalid or expired challenge token, please log in again"
+		utils.RespondError(w, http.StatusUnauthorized, TwoFactorChallengeInvalid, message, nil)
+		return
+	}
+	if errors.Is(err, service.ErrInvalidTwoFactorCode) {
+		message = "invalid two-factor code"
+		utils.RespondError(w, http.StatusUnauthorized, InvalidTwoFactorCode, message, nil)
+		return
+	}
+	if err != nil {
+		message = "login failed"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	tokens, err := h.authService.IssueTokens(r.Context(), user)
+	if respondDisabled(w, err) {
+		return
+	}
+	if err != nil {
+		message = "failed to issue jwt token from server"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "login successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, loginSuccessData(tokens))
+}
+
+// respondLocked answers with 429 and a Retry-After header when err is a
+// lockout, and reports whether it did.
+func respondLocked(w http.ResponseWriter, err error) bool {
+	var locked *service.LockedError
+	if !errors.As(err, &locked) {
+		return false
+	}
+
+	retryAfter := int(math.Ceil(locked.RetryAfter.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	message := "too many failed login attempts, try again later"
+	utils.RespondError(w, http.StatusTooManyRequests, AccountLocked, message, map[string]int{"retryAfter": retryAfter})
+	return true
+}
+
+// respondDisabled answers with 403 when err is service.ErrAccountDisabled,
+// and reports whether it did.
+func respondDisabled(w http.ResponseWriter, err error) bool {
+	if !errors.Is(err, service.ErrAccountDisabled) {
+		return false
+	}
+
+	message := "this account has been disabled"
+	utils.RespondError(w, http.StatusForbidden, AccountDisabled, message, nil)
+	return true
+}
+
+func loginSuccessData(tokens *model.TokenPair) dto.LoginSuccessData {
+	return dto.LoginSuccessData{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+	}
+}
+
+func (h *UserHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	var message string
+	payload := middleware.GetValidatedRequest[dto.RefreshTokenPayload](r)
+
+	tokens, err := h.authService.RefreshTokens(r.Context(), payload.RefreshToken)
+	if respondDisabled(w, err) {
+		return
+	}
+	if errors.Is(err, service.ErrRefreshTokenReused) {
+		message = "refresh token was already used, all sessions of this login have been revoked"
+		utils.RespondError(w, http.StatusUnauthorized, RefreshTokenReused, message, nil)
+		return
+	}
+	if errors.Is(err, service.ErrInvalidRefreshToken) {
+		message = "invalid or expired refresh token"
+		utils.RespondError(w, http.StatusUnauthorized, RefreshTokenInvalid, message, nil)
+		return
+	}
+	if err != nil {
+		message = "failed to refresh tokens"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "refresh tokens successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, loginSuccessData(tokens))
+}
+
+func (h *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	var message string
+	claims, ok := middleware.GetAccessClaims(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	payload := middleware.GetValidatedRequest[dto.LogoutPayload](r)
+
+	err := h.authService.Logout(r.Context(), claims, payload.RefreshToken)
+	if errors.Is(err, service.ErrInvalidRefreshToken) {
+		message = "invalid refresh token"
+		utils.RespondError(w, http.StatusBadRequest, RefreshTokenInvalid, message, nil)
+		return
+	}
+	if err != nil {
+		message = "failed to logout"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "logout successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, nil)
 }
 
 func (h *UserHandler) GetUserData(w http.ResponseWriter, r *http.Request) {
@@ -94,3 +268,290 @@ func (h *UserHandler) GetUserData(w http.ResponseWriter, r *http.Request) {
 	message = "get user data successfully"
 	utils.RespondSuccess(w, http.StatusOK, message, user)
 }
+
+func (h *UserHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var message string
+	payload := middleware.GetValidatedRequest[dto.ForgotPasswordPayload](r)
+
+	err := h.service.RequestPasswordReset(r.Context(), payload.Email)
+	if err != nil {
+		fmt.Println(err)
+		message = "failed to send the password reset email"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "if the email is registered, a password reset link has been sent"
+	utils.RespondSuccess(w, http.StatusOK, message, nil)
+}
+
+func (h *UserHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var message string
+	payload := middleware.GetValidatedRequest[dto.ResetPasswordPayload](r)
+
+	err := h.service.ResetPassword(r.Context(), payload.Token, payload.Password)
+	if errors.Is(err, service.ErrInvalidUserToken) {
+		message = "invalid or expired password reset token"
+		utils.RespondError(w, http.StatusBadRequest, InvalidUserToken, message, nil)
+		return
+	}
+	if err != nil {
+		message = "failed to reset the password"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "reset the password successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, nil)
+}
+
+func (h *UserHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var message string
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		message = "missing token"
+		utils.RespondError(w, http.StatusBadRequest, ValidationError, message, map[string]string{"token": "required"})
+		return
+	}
+
+	err := h.service.VerifyEmail(r.Context(), token)
+	if errors.Is(err, service.ErrInvalidUserToken) {
+		message = "invalid or expired verification token"
+		utils.RespondError(w, http.StatusBadRequest, InvalidUserToken, message, nil)
+		return
+	}
+	if err != nil {
+		message = "failed to verify the email address"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "verify the email address successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, nil)
+}
+
+func (h *UserHandler) UnlockAccount(w http.ResponseWriter, r *http.Request) {
+	var message string
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		message = "missing token"
+		utils.RespondError(w, http.StatusBadRequest, ValidationError, message, map[string]string{"token": "required"})
+		return
+	}
+
+	err := h.service.UnlockAccount(r.Context(), token)
+	if errors.Is(err, service.ErrInvalidUserToken) {
+		message = "invalid or expired unlock token"
+		utils.RespondError(w, http.StatusBadRequest, InvalidUserToken, message, nil)
+		return
+	}
+	if err != nil {
+		message = "failed to unlock the account"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "unlock the account successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, nil)
+}
+
+func (h *UserHandler) ResendVerificationEmail(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	user, err := h.service.GetUserDataById(r.Context(), userID)
+	if user == nil {
+		fmt.Println(err)
+		message = "user not found"
+		utils.RespondError(w, http.StatusNotFound, UserNotFound, message, nil)
+		return
+	}
+
+	if user.EmailVerifiedAt != nil {
+		message = "the email address is already verified"
+		utils.RespondSuccess(w, http.StatusOK, message, nil)
+		return
+	}
+
+	err = h.service.SendVerificationEmail(r.Context(), user)
+	if err != nil {
+		fmt.Println(err)
+		message = "failed to send the verification email"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "send the verification email successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, nil)
+}
+
+func (h *UserHandler) UpdateUserData(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	payload := middleware.GetValidatedRequest[dto.UpdateProfilePayload](r)
+
+	if payload.FirstName != nil || payload.LastName != nil {
+		err := h.service.UpdateProfile(r.Context(), userID, payload.FirstName, payload.LastName)
+		if err != nil {
+			fmt.Println(err)
+			message = "cannot update user data"
+			utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+			return
+		}
+	}
+
+	message = "update user data successfully"
+	if payload.Email != nil {
+		err := h.service.RequestEmailChange(r.Context(), userID, *payload.Email)
+		if errors.Is(err, service.ErrEmailTaken) {
+			message = "the email address is already in use"
+			utils.RespondError(w, http.StatusConflict, EmailAlreadyExists, message, nil)
+			return
+		}
+		if err != nil {
+			fmt.Println(err)
+			message = "cannot change the email address"
+			utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+			return
+		}
+		message = "update user data successfully, confirm the new email address from your inbox"
+	}
+
+	user, err := h.service.GetUserDataById(r.Context(), userID)
+	if user == nil {
+		fmt.Println(err)
+		message = "user not found"
+		utils.RespondError(w, http.StatusNotFound, UserNotFound, message, nil)
+		return
+	}
+
+	utils.RespondSuccess(w, http.StatusOK, message, user)
+}
+
+func (h *UserHandler) ConfirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	var message string
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		message = "missing token"
+		utils.RespondError(w, http.StatusBadRequest, ValidationError, message, map[string]string{"token": "required"})
+		return
+	}
+
+	err := h.service.ConfirmEmailChange(r.Context(), token)
+	if errors.Is(err, service.ErrInvalidUserToken) {
+		message = "invalid or expired confirmation token"
+		utils.RespondError(w, http.StatusBadRequest, InvalidUserToken, message, nil)
+		return
+	}
+	if errors.Is(err, service.ErrEmailTaken) {
+		message = "the email address is already in use"
+		utils.RespondError(w, http.StatusConflict, EmailAlreadyExists, message, nil)
+		return
+	}
+	if err != nil {
+		message = "failed to change the email address"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "change the email address successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, nil)
+}
+
+// ChangePassword ends every session of the user and answers with new tokens
+// for the one that made the change.
+func (h *UserHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	payload := middleware.GetValidatedRequest[dto.ChangePasswordPayload](r)
+
+	err := h.service.ChangePassword(r.Context(), userID, payload.CurrentPassword, payload.NewPassword, middleware.ClientIP(r))
+	if respondLocked(w, err) {
+		return
+	}
+	if errors.Is(err, service.ErrWrongPassword) {
+		message = "wrong password"
+		utils.RespondError(w, http.StatusForbidden, WrongPassword, message, nil)
+		return
+	}
+	if err != nil {
+		fmt.Println(err)
+		message = "cannot change the password"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	user, err := h.service.GetUserDataById(r.Context(), userID)
+	if user == nil {
+		fmt.Println(err)
+		message = "user not found"
+		utils.RespondError(w, http.StatusNotFound, UserNotFound, message, nil)
+		return
+	}
+
+	tokens, err := h.authService.IssueTokens(r.Context(), user)
+	if respondDisabled(w, err) {
+		return
+	}
+	if err != nil {
+		message = "failed to issue jwt token from server"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "change the password successfully, other sessions have been signed out"
+	utils.RespondSuccess(w, http.StatusOK, message, loginSuccessData(tokens))
+}
+
+func (h *UserHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	var message string
+	claims, ok := middleware.GetAccessClaims(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	payload := middleware.GetValidatedRequest[dto.DeleteAccountPayload](r)
+
+	err := h.service.DeleteAccount(r.Context(), claims.UserID, payload.Password, middleware.ClientIP(r))
+	if respondLocked(w, err) {
+		return
+	}
+	if errors.Is(err, service.ErrWrongPassword) {
+		message = "wrong password"
+		utils.RespondError(w, http.StatusForbidden, WrongPassword, message, nil)
+		return
+	}
+	if err != nil {
+		fmt.Println(err)
+		message = "cannot delete the account"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	// the account is gone either way; this only stops the current token early
+	if err := h.authService.Logout(r.Context(), claims, ""); err != nil {
+		fmt.Println(err)
+	}
+
+	message = "delete the account successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, nil)
+}
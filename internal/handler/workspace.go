@@ -0,0 +1,372 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/King0625/golang-todolist/internal/dto"
+	"github.com/King0625/golang-todolist/internal/middleware"
+	"github.com/King0625/golang-todolist/internal/model"
+	"github.com/King0625/golang-todolist/internal/repository"
+	"github.com/King0625/golang-todolist/internal/service"
+	"github.com/King0625/golang-todolist/pkg/utils"
+)
+
+type WorkspaceHandler struct {
+	service service.WorkspaceService
+}
+
+func NewWorkspaceHandler(s service.WorkspaceService) *WorkspaceHandler {
+	return &WorkspaceHandler{s}
+}
+
+// getWorkspace resolves the workspace at the workspaceID path parameter, with
+// the role userID has in it. It responds with an error and returns nil when
+// there is none or userID is not a member.
+func (h *WorkspaceHandler) getWorkspace(w http.ResponseWriter, r *http.Request, userID int) *model.Workspace {
+	var message string
+
+	workspaceID, err := strconv.Atoi(r.PathValue("workspaceID"))
+	if err != nil {
+		message = "invalid workspaceID"
+		utils.RespondError(w, http.StatusBadRequest, ValidationError, message, nil)
+		return nil
+	}
+
+	role, err := h.service.GetMemberRole(r.Context(), workspaceID, userID)
+	if err != nil {
+		fmt.Println(err)
+		message = "cannot check workspace membership"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return nil
+	}
+
+	var workspace *model.Workspace
+	if role != "" {
+		workspace, err = h.service.GetWorkspaceById(r.Context(), workspaceID)
+	}
+	if workspace == nil {
+		fmt.Println(err)
+		message = "workspace not found"
+		utils.RespondError(w, http.StatusNotFound, WorkspaceNotFound, message, nil)
+		return nil
+	}
+
+	workspace.Role = role
+	return workspace
+}
+
+// requireRole reports whether the requesting member may do what is described
+// by action. Managing a workspace takes an owner or admin, deleting it the
+// owner. It responds with an error when it returns false.
+func requireRole(w http.ResponseWriter, workspace *model.Workspace, ownerOnly bool, action string) bool {
+	allowed := workspace.Role.CanManage()
+	if ownerOnly {
+		allowed = workspace.Role == model.WorkspaceRoleOwner
+	}
+
+	if !allowed {
+		message := fmt.Sprintf("your role in this workspace does not allow you to %s", action)
+		utils.RespondError(w, http.StatusForbidden, PermissionDenied, message, nil)
+		return false
+	}
+
+	return true
+}
+
+// respondMemberError answers errors about workspace members with the matching
+// status, and reports whether it did.
+func respondMemberError(w http.ResponseWriter, err error) bool {
+	var message string
+
+	switch {
+	case errors.Is(err, service.ErrMemberNotFound):
+		message = "member not found"
+		utils.RespondError(w, http.StatusNotFound, MemberNotFound, message, nil)
+	case errors.Is(err, service.ErrWorkspaceOwner):
+		message = "the owner of the workspace cannot be changed or removed"
+		utils.RespondError(w, http.StatusConflict, CannotModifyOwner, message, nil)
+	default:
+		return false
+	}
+
+	return true
+}
+
+func (h *WorkspaceHandler) CreateWorkspace(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	payload := middleware.GetValidatedRequest[dto.WorkspacePayload](r)
+
+	workspace := model.Workspace{
+		OwnerID: userID,
+		Name:    payload.Name,
+	}
+
+	err := h.service.CreateWorkspace(r.Context(), &workspace)
+	if err != nil {
+		fmt.Println(err)
+		message = "cannot insert workspace into db"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "create workspace successfully"
+	utils.RespondSuccess(w, http.StatusCreated, message, workspace)
+}
+
+func (h *WorkspaceHandler) GetWorkspaces(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	workspaces, err := h.service.GetWorkspaces(r.Context(), userID)
+	if err != nil {
+		fmt.Println(err)
+		message = "cannot get workspaces from db"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "fetch workspaces successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, workspaces)
+}
+
+func (h *WorkspaceHandler) GetOneWorkspaceByID(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	workspace := h.getWorkspace(w, r, userID)
+	if workspace == nil {
+		return
+	}
+
+	message = "fetch a workspace successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, workspace)
+}
+
+func (h *WorkspaceHandler) UpdateWorkspaceById(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	workspace := h.getWorkspace(w, r, userID)
+	if workspace == nil || !requireRole(w, workspace, false, "rename it") {
+		return
+	}
+
+	payload := middleware.GetValidatedRequest[dto.WorkspacePayload](r)
+
+	err := h.service.RenameWorkspace(r.Context(), workspace.ID, payload.Name)
+	if err != nil {
+		fmt.Println(err)
+		message = "failed to update the workspace in DB"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "update the workspace successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, nil)
+}
+
+func (h *WorkspaceHandler) DeleteWorkspaceById(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	workspace := h.getWorkspace(w, r, userID)
+	if workspace == nil || !requireRole(w, workspace, true, "delete it") {
+		return
+	}
+
+	err := h.service.DeleteWorkspace(r.Context(), workspace.ID)
+	if err != nil {
+		fmt.Println(err)
+		message = "cannot delete the workspace from DB"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "delete the workspace successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, nil)
+}
+
+func (h *WorkspaceHandler) GetMembers(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	workspace := h.getWorkspace(w, r, userID)
+	if workspace == nil {
+		return
+	}
+
+	members, err := h.service.GetMembers(r.Context(), workspace.ID)
+	if err != nil {
+		fmt.Println(err)
+		message = "cannot get members from db"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "fetch members successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, members)
+}
+
+func (h *WorkspaceHandler) AddMember(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	workspace := h.getWorkspace(w, r, userID)
+	if workspace == nil || !requireRole(w, workspace, false, "add members") {
+		return
+	}
+
+	payload := middleware.GetValidatedRequest[dto.AddMemberPayload](r)
+
+	err := h.service.AddMember(r.Context(), workspace.ID, payload.Email, model.WorkspaceRole(payload.Role))
+	if errors.Is(err, service.ErrUserNotFound) {
+		message = "no user is registered with this email"
+		utils.RespondError(w, http.StatusNotFound, UserNotFound, message, nil)
+		return
+	}
+	if errors.Is(err, repository.ErrAlreadyMember) {
+		message = "the user is already a member of this workspace"
+		utils.RespondError(w, http.StatusConflict, AlreadyMember, message, nil)
+		return
+	}
+	if respondMemberError(w, err) {
+		return
+	}
+	if err != nil {
+		fmt.Println(err)
+		message = "cannot add the member to the workspace"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "add the member successfully"
+	utils.RespondSuccess(w, http.StatusCreated, message, nil)
+}
+
+// getMemberID parses the userID path parameter. It responds with an error and
+// returns false when it is invalid.
+func getMemberID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	memberID, err := strconv.Atoi(r.PathValue("userID"))
+	if err != nil {
+		message := "invalid userID"
+		utils.RespondError(w, http.StatusBadRequest, ValidationError, message, nil)
+		return 0, false
+	}
+
+	return memberID, true
+}
+
+func (h *WorkspaceHandler) SetMemberRole(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	workspace := h.getWorkspace(w, r, userID)
+	if workspace == nil || !requireRole(w, workspace, false, "change roles") {
+		return
+	}
+
+	memberID, ok := getMemberID(w, r)
+	if !ok {
+		return
+	}
+
+	payload := middleware.GetValidatedRequest[dto.SetMemberRolePayload](r)
+
+	err := h.service.SetMemberRole(r.Context(), workspace.ID, memberID, model.WorkspaceRole(payload.Role))
+	if respondMemberError(w, err) {
+		return
+	}
+	if err != nil {
+		fmt.Println(err)
+		message = "failed to update the role in DB"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "update the role successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, nil)
+}
+
+// RemoveMember removes a member from the workspace. Members may remove
+// themselves to leave it.
+func (h *WorkspaceHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	var message string
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		message = "failed to fetch user identity from parsed jwt token"
+		utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+		return
+	}
+
+	workspace := h.getWorkspace(w, r, userID)
+	if workspace == nil {
+		return
+	}
+
+	memberID, ok := getMemberID(w, r)
+	if !ok {
+		return
+	}
+	if memberID != userID && !requireRole(w, workspace, false, "remove members") {
+		return
+	}
+
+	err := h.service.RemoveMember(r.Context(), workspace.ID, memberID)
+	if respondMemberError(w, err) {
+		return
+	}
+	if err != nil {
+		fmt.Println(err)
+		message = "cannot remove the member from the workspace"
+		utils.RespondError(w, http.StatusInternalServerError, InternalError, message, nil)
+		return
+	}
+
+	message = "remove the member successfully"
+	utils.RespondSuccess(w, http.StatusOK, message, nil)
+}
@@ -2,16 +2,25 @@ package middleware
 
 import (
 	"context"
+	"log"
 	"net/http"
 	"strings"
 
+	"github.com/King0625/golang-todolist/internal/actor"
+	"github.com/King0625/golang-todolist/internal/model"
 	"github.com/King0625/golang-todolist/pkg/utils"
 )
 
 type contextKey string
 
 const Unauthorized = "UNAUTHORIZED"
+const TokenRevoked = "TOKEN_REVOKED"
+const InsufficientScope = "INSUFFICIENT_SCOPE"
+const SessionRequired = "SESSION_REQUIRED"
+const InsufficientRole = "INSUFFICIENT_ROLE"
 const userIDKey contextKey = "userID"
+const accessClaimsKey contextKey = "accessClaims"
+const personalAccessTokenKey contextKey = "personalAccessToken"
 
 type JsonResponse struct {
 	Message string `json:"message"`
@@ -19,11 +28,102 @@ type JsonResponse struct {
 	Data    any    `json:"data"`
 }
 
+// AccessTokenParser verifies access tokens and returns their claims.
+type AccessTokenParser interface {
+	ParseJWT(tokenStr string) (*utils.AccessClaims, error)
+}
+
+var accessTokenParser AccessTokenParser
+
+// UseAccessTokenParser sets how JWTAuth verifies access tokens. Until it is
+// called every access token is rejected.
+func UseAccessTokenParser(p AccessTokenParser) {
+	accessTokenParser = p
+}
+
+// TokenDenylist reports access tokens revoked before their expiry, e.g. on logout.
+type TokenDenylist interface {
+	IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+var tokenDenylist TokenDenylist
+
+// UseTokenDenylist makes JWTAuth reject tokens revoked in d.
+func UseTokenDenylist(d TokenDenylist) {
+	tokenDenylist = d
+}
+
+// TokenGenerations reports the generation the access tokens of a user must
+// carry. It changes whenever the tokens issued so far are invalidated, e.g.
+// since the user changed their password.
+type TokenGenerations interface {
+	TokenGeneration(ctx context.Context, userID int) (int, error)
+}
+
+var tokenGenerations TokenGenerations
+
+// UseTokenGenerations makes JWTAuth reject access tokens that do not carry
+// the current generation of their user in g.
+func UseTokenGenerations(g TokenGenerations) {
+	tokenGenerations = g
+}
+
+// PersonalAccessTokenVerifier resolves the raw personal access tokens
+// presented in the Authorization header.
+type PersonalAccessTokenVerifier interface {
+	VerifyPersonalAccessToken(ctx context.Context, raw string) (*model.PersonalAccessToken, error)
+}
+
+var personalAccessTokens PersonalAccessTokenVerifier
+
+// UsePersonalAccessTokens makes JWTAuth also accept personal access tokens
+// verified by v.
+func UsePersonalAccessTokens(v PersonalAccessTokenVerifier) {
+	personalAccessTokens = v
+}
+
+// RoleResolver looks up the current role of a user. It returns an empty role
+// for users that no longer exist or are disabled.
+type RoleResolver interface {
+	GetUserRole(ctx context.Context, userID int) (model.Role, error)
+}
+
+var roleResolver RoleResolver
+
+// UseRoleResolver makes RequireRole confirm the role in the access token
+// against rr, so that demoting or disabling a user takes effect before their
+// token expires.
+func UseRoleResolver(rr RoleResolver) {
+	roleResolver = rr
+}
+
 func GetUserID(r *http.Request) (int, bool) {
 	id, ok := r.Context().Value(userIDKey).(int)
 	return id, ok
 }
 
+func GetAccessClaims(r *http.Request) (*utils.AccessClaims, bool) {
+	claims, ok := r.Context().Value(accessClaimsKey).(*utils.AccessClaims)
+	return claims, ok
+}
+
+// GetRole returns the role carried in the access token. Personal access
+// tokens, and access tokens issued before roles existed, act as plain users.
+func GetRole(r *http.Request) model.Role {
+	claims, ok := GetAccessClaims(r)
+	if !ok || claims.Role == "" {
+		return model.RoleUser
+	}
+	return model.Role(claims.Role)
+}
+
+// GetPersonalAccessToken returns the token the request was authenticated
+// with, if it was not a login session.
+func GetPersonalAccessToken(r *http.Request) (*model.PersonalAccessToken, bool) {
+	token, ok := r.Context().Value(personalAccessTokenKey).(*model.PersonalAccessToken)
+	return token, ok
+}
+
 func JWTAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		var message string
@@ -36,14 +136,134 @@ func JWTAuth(next http.Handler) http.Handler {
 
 		tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
 
-		userID, err := utils.ParseJWT(tokenStr)
+		if personalAccessTokens != nil && strings.HasPrefix(tokenStr, model.PersonalAccessTokenPrefix) {
+			token, err := personalAccessTokens.VerifyPersonalAccessToken(r.Context(), tokenStr)
+			if err != nil {
+				log.Println(err)
+				message = "invalid token"
+				utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), userIDKey, token.UserID)
+			ctx = context.WithValue(ctx, personalAccessTokenKey, token)
+			ctx = actor.WithUserID(ctx, token.UserID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
+		if accessTokenParser == nil {
+			log.Println("JWTAuth: no access token parser configured")
+			message = "invalid token"
+			utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
+			return
+		}
+
+		claims, err := accessTokenParser.ParseJWT(tokenStr)
 		if err != nil {
 			message = "invalid token"
 			utils.RespondError(w, http.StatusUnauthorized, Unauthorized, message, nil)
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), userIDKey, userID)
+		if tokenDenylist != nil && claims.ID != "" {
+			revoked, err := tokenDenylist.IsAccessTokenRevoked(r.Context(), claims.ID)
+			if err != nil {
+				log.Println(err)
+			}
+			// fail closed: a token we cannot check is treated as revoked
+			if revoked || err != nil {
+				message = "token has been revoked"
+				utils.RespondError(w, http.StatusUnauthorized, TokenRevoked, message, nil)
+				return
+			}
+		}
+
+		if tokenGenerations != nil {
+			generation, err := tokenGenerations.TokenGeneration(r.Context(), claims.UserID)
+			if err != nil {
+				log.Println(err)
+			}
+			// fail closed like the denylist
+			if err != nil || claims.Generation != generation {
+				message = "token has been revoked"
+				utils.RespondError(w, http.StatusUnauthorized, TokenRevoked, message, nil)
+				return
+			}
+		}
+
+		ctx := context.WithValue(r.Context(), userIDKey, claims.UserID)
+		ctx = context.WithValue(ctx, accessClaimsKey, claims)
+		ctx = actor.WithUserID(ctx, claims.UserID)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// RequireScope rejects requests made with a personal access token lacking
+// scope. Login sessions have every scope. It must run after JWTAuth.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token, ok := GetPersonalAccessToken(r); ok && !token.HasScope(scope) {
+				message := "token is missing the " + scope + " scope"
+				utils.RespondError(w, http.StatusForbidden, InsufficientScope, message, nil)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireSession rejects requests made with a personal access token, for
+// routes that manage the account or its credentials. It must run after JWTAuth.
+func RequireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := GetPersonalAccessToken(r); ok {
+			message := "this endpoint requires a login session"
+			utils.RespondError(w, http.StatusForbidden, SessionRequired, message, nil)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireRole rejects requests from users whose role is not one of roles. It
+// must run after JWTAuth.
+func RequireRole(roles ...model.Role) func(http.Handler) http.Handler {
+	allowed := func(role model.Role) bool {
+		for _, r := range roles {
+			if r == role {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			message := "your role does not allow this"
+
+			if !allowed(GetRole(r)) {
+				utils.RespondError(w, http.StatusForbidden, InsufficientRole, message, nil)
+				return
+			}
+
+			if roleResolver != nil {
+				userID, _ := GetUserID(r)
+				role, err := roleResolver.GetUserRole(r.Context(), userID)
+				if err != nil {
+					log.Println(err)
+				}
+				// fail closed like the token denylist
+				if err != nil || !allowed(role) {
+					utils.RespondError(w, http.StatusForbidden, InsufficientRole, message, nil)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"net/http"
 
+	"github.com/King0625/golang-todolist/pkg/recurrence"
 	"github.com/King0625/golang-todolist/pkg/utils"
 	"github.com/go-playground/validator/v10"
 )
@@ -17,10 +18,35 @@ const (
 
 var validate = validator.New()
 
+func init() {
+	// rrule accepts recurrence rules understood by pkg/recurrence
+	validate.RegisterValidation("rrule", func(fl validator.FieldLevel) bool {
+		_, err := recurrence.Parse(fl.Field().String())
+		return err == nil
+	})
+}
+
 func GetValidatedRequest[T any](r *http.Request) T {
 	return r.Context().Value(requestDataKey).(T)
 }
 
+// ValidateStruct checks v against its validate tags, with the same rules as
+// ValidationMiddleware. It returns the failed tag of every invalid field, or
+// nil when v is valid.
+func ValidateStruct(v any) map[string]string {
+	err := validate.Struct(v)
+	if err == nil {
+		return nil
+	}
+
+	details := make(map[string]string)
+	errs := err.(validator.ValidationErrors)
+	for _, e := range errs {
+		details[e.Field()] = e.ActualTag()
+	}
+	return details
+}
+
 func ValidationMiddleware[T any](next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		var req T
@@ -34,13 +60,8 @@ func ValidationMiddleware[T any](next http.Handler) http.Handler {
 			return
 		}
 
-		if err = validate.Struct(req); err != nil {
+		if details := ValidateStruct(req); details != nil {
 			message = "validation failed"
-			details := make(map[string]string)
-			errs := err.(validator.ValidationErrors)
-			for _, e := range errs {
-				details[e.Field()] = e.ActualTag()
-			}
 			utils.RespondError(w, http.StatusBadRequest, validationError, message, details)
 			return
 		}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/King0625/golang-todolist/internal/model"
+	"github.com/King0625/golang-todolist/pkg/utils"
+)
+
+const WorkspaceNotFound = "WORKSPACE_NOT_FOUND"
+const workspaceIDKey contextKey = "workspaceID"
+
+// WorkspaceHeader names the workspace a request works in.
+const WorkspaceHeader = "X-Workspace-ID"
+
+// WorkspaceResolver looks up the role of a user in a workspace. It returns an
+// empty role for users that are not members.
+type WorkspaceResolver interface {
+	GetMemberRole(ctx context.Context, workspaceID, userID int) (model.WorkspaceRole, error)
+}
+
+var workspaceResolver WorkspaceResolver
+
+// UseWorkspaceResolver sets how WorkspaceContext checks memberships. Until it
+// is called every request naming a workspace is rejected.
+func UseWorkspaceResolver(wr WorkspaceResolver) {
+	workspaceResolver = wr
+}
+
+// GetWorkspaceID returns the workspace the request works in, or 0 for the
+// personal todos and projects of the user.
+func GetWorkspaceID(r *http.Request) int {
+	id, _ := r.Context().Value(workspaceIDKey).(int)
+	return id
+}
+
+// WorkspaceContext moves the request into the workspace named by the
+// X-Workspace-ID header, after making sure the user is a member of it.
+// Requests without the header work on the personal todos and projects of the
+// user. It must run after JWTAuth.
+func WorkspaceContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get(WorkspaceHeader)
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		message := "workspace not found"
+
+		workspaceID, err := strconv.Atoi(header)
+		if err != nil || workspaceID <= 0 {
+			utils.RespondError(w, http.StatusNotFound, WorkspaceNotFound, message, nil)
+			return
+		}
+
+		var role model.WorkspaceRole
+		if workspaceResolver == nil {
+			log.Println("WorkspaceContext: no workspace resolver configured")
+		} else {
+			userID, _ := GetUserID(r)
+			role, err = workspaceResolver.GetMemberRole(r.Context(), workspaceID, userID)
+			if err != nil {
+				log.Println(err)
+			}
+		}
+		// fail closed; workspaces of others look just like missing ones
+		if role == "" {
+			utils.RespondError(w, http.StatusNotFound, WorkspaceNotFound, message, nil)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), workspaceIDKey, workspaceID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
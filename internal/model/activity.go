@@ -0,0 +1,62 @@
+package model
+
+import "time"
+
+// Comment is a remark on a todo. Content is Markdown, rendered by clients.
+type Comment struct {
+	ID        int        `json:"id"`
+	TodoID    int        `json:"todoId"`
+	UserID    int        `json:"userId"`
+	Content   string     `json:"content"`
+	CreatedAt time.Time  `json:"createdAt"`
+	EditedAt  *time.Time `json:"editedAt,omitempty"`
+	// Mentions are the ids of the users @mentioned in Content who can see
+	// the todo.
+	Mentions []int `json:"mentions"`
+}
+
+type TodoEventType string
+
+const (
+	EventCreated           TodoEventType = "created"
+	EventTitleChanged      TodoEventType = "title_changed"
+	EventContentChanged    TodoEventType = "content_changed"
+	EventMarkedDone        TodoEventType = "marked_done"
+	EventReopened          TodoEventType = "reopened"
+	EventDueChanged        TodoEventType = "due_changed"
+	EventPriorityChanged   TodoEventType = "priority_changed"
+	EventRecurrenceChanged TodoEventType = "recurrence_changed"
+	EventProjectChanged    TodoEventType = "project_changed"
+	EventAssigneeChanged   TodoEventType = "assignee_changed"
+	EventTrashed           TodoEventType = "trashed"
+	EventRestored          TodoEventType = "restored"
+)
+
+// TodoEvent records a change of a todo. UserID is nil for changes the system
+// made on its own. From and To hold the old and new value where that is
+// meaningful.
+type TodoEvent struct {
+	ID        int           `json:"id"`
+	TodoID    int           `json:"todoId"`
+	UserID    *int          `json:"userId"`
+	Type      TodoEventType `json:"type"`
+	From      string        `json:"from,omitempty"`
+	To        string        `json:"to,omitempty"`
+	CreatedAt time.Time     `json:"createdAt"`
+}
+
+type ActivityKind string
+
+const (
+	ActivityComment ActivityKind = "comment"
+	ActivityEvent   ActivityKind = "event"
+)
+
+// ActivityEntry is one entry of the activity feed of a todo, which merges its
+// comments and events in chronological order.
+type ActivityEntry struct {
+	Kind      ActivityKind `json:"kind"`
+	CreatedAt time.Time    `json:"createdAt"`
+	Comment   *Comment     `json:"comment,omitempty"`
+	Event     *TodoEvent   `json:"event,omitempty"`
+}
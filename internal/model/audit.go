@@ -0,0 +1,123 @@
+package model
+
+import (
+	"encoding/json"
+	"reflect"
+	"time"
+)
+
+type AuditAction string
+
+const (
+	AuditTodoCreated  AuditAction = "todo.created"
+	AuditTodoUpdated  AuditAction = "todo.updated"
+	AuditTodoMoved    AuditAction = "todo.moved"
+	AuditTodoDone     AuditAction = "todo.done"
+	AuditTodoDeleted  AuditAction = "todo.deleted"
+	AuditTodoRestored AuditAction = "todo.restored"
+	AuditTodoPurged   AuditAction = "todo.purged"
+
+	AuditLoginSucceeded  AuditAction = "user.login_succeeded"
+	AuditLoginFailed     AuditAction = "user.login_failed"
+	AuditPasswordChanged AuditAction = "user.password_changed"
+	AuditPasswordReset   AuditAction = "user.password_reset"
+	AuditEmailChanged    AuditAction = "user.email_changed"
+	AuditAccountDeleted  AuditAction = "user.deleted"
+)
+
+const (
+	AuditTargetTodo = "todo"
+	AuditTargetUser = "user"
+)
+
+// Change is a field of a record before and after a mutation.
+type Change struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+// AuditEvent is an entry of the append-only audit log. ActorID is nil when
+// nobody was signed in, like for failed logins, or for work the system did on
+// its own. Changes holds the fields a mutation changed, keyed by their JSON
+// names.
+type AuditEvent struct {
+	ID         int64             `json:"id"`
+	ActorID    *int              `json:"actorId"`
+	Action     AuditAction       `json:"action"`
+	TargetType string            `json:"targetType"`
+	TargetID   *int              `json:"targetId"`
+	Detail     string            `json:"detail,omitempty"`
+	Changes    map[string]Change `json:"changes,omitempty"`
+	IP         string            `json:"ip"`
+	UserAgent  string            `json:"userAgent"`
+	RequestID  string            `json:"requestId"`
+	CreatedAt  time.Time         `json:"createdAt"`
+}
+
+// AuditQuery filters the audit log, which is listed newest first and
+// continues before BeforeID. SubjectID keeps the events made by a user or
+// about their account.
+type AuditQuery struct {
+	SubjectID  *int
+	ActorID    *int
+	Action     AuditAction
+	TargetType string
+	TargetID   *int
+	From       *time.Time
+	To         *time.Time
+	BeforeID   int64
+	Limit      int
+}
+
+type AuditPage struct {
+	Events  []*AuditEvent
+	HasMore bool
+}
+
+// Diff compares the JSON forms of before and after and returns the fields
+// that differ, leaving out ignored ones. Fields omitted from the JSON form
+// count as nil. Either side may be nil, for records that are created or
+// removed.
+func Diff(before, after any, ignored ...string) (map[string]Change, error) {
+	from, err := jsonFields(before)
+	if err != nil {
+		return nil, err
+	}
+	to, err := jsonFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]Change)
+	for key, value := range from {
+		if !reflect.DeepEqual(value, to[key]) {
+			changes[key] = Change{From: value, To: to[key]}
+		}
+	}
+	for key, value := range to {
+		if _, ok := from[key]; !ok && value != nil {
+			changes[key] = Change{From: nil, To: value}
+		}
+	}
+
+	for _, key := range ignored {
+		delete(changes, key)
+	}
+
+	return changes, nil
+}
+
+func jsonFields(v any) (map[string]any, error) {
+	var fields map[string]any
+	if rv := reflect.ValueOf(v); !rv.IsValid() || rv.Kind() == reflect.Pointer && rv.IsNil() {
+		return fields, nil
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(b, &fields)
+
+	return fields, err
+}
@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+type ExportStatus string
+
+const (
+	ExportPending ExportStatus = "pending"
+	ExportRunning ExportStatus = "running"
+	ExportReady   ExportStatus = "ready"
+	ExportFailed  ExportStatus = "failed"
+)
+
+// DataExport is a user's request for an archive of all their data. The
+// archive itself is loaded separately, only for downloads.
+type DataExport struct {
+	ID          int          `json:"id"`
+	UserID      int          `json:"userId"`
+	Status      ExportStatus `json:"status"`
+	Error       string       `json:"error,omitempty"`
+	Size        int64        `json:"size,omitempty"`
+	CreatedAt   time.Time    `json:"createdAt"`
+	CompletedAt *time.Time   `json:"completedAt,omitempty"`
+	ExpiresAt   *time.Time   `json:"expiresAt,omitempty"`
+}
+
+func (e *DataExport) OwnerID() int {
+	return e.UserID
+}
+
+func (e *DataExport) Expired(now time.Time) bool {
+	return e.ExpiresAt != nil && now.After(*e.ExpiresAt)
+}
@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// UserIdentity links a user to their account at an OpenID Connect provider.
+// Subject is the provider's stable id for the account; the email there may
+// change.
+type UserIdentity struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"userId"`
+	Provider  string    `json:"provider"`
+	Subject   string    `json:"-"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// OIDCLoginState is kept between sending a user to a provider and their
+// return to the callback.
+type OIDCLoginState struct {
+	ID           int
+	StateHash    string
+	Provider     string
+	Nonce        string
+	CodeVerifier string
+	ExpiresAt    time.Time
+}
@@ -0,0 +1,31 @@
+package model
+
+import (
+	"time"
+)
+
+// ChecklistItem is one step of a multi-step todo.
+type ChecklistItem struct {
+	ID        int       `json:"id"`
+	TodoID    int       `json:"todoId"`
+	Title     string    `json:"title"`
+	Done      bool      `json:"done"`
+	Position  int       `json:"position"`
+	CreatedAt time.Time `json:"createdAt,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt,omitempty"`
+}
+
+// Progress summarizes the checklist of a todo, e.g. 3 of 5 items done.
+type Progress struct {
+	Done  int `json:"done"`
+	Total int `json:"total"`
+}
+
+// DonePolicy decides what happens to open checklist items when their todo is
+// marked done.
+type DonePolicy string
+
+const (
+	DonePolicyCompleteItems DonePolicy = "complete"
+	DonePolicyRefuseOpen    DonePolicy = "refuse"
+)
@@ -0,0 +1,52 @@
+package model
+
+import "time"
+
+// LockoutPolicy slows down password guessing and then stops it. After
+// FreeAttempts failures every further failure delays the next attempt
+// exponentially, up to MaxDelay. At AccountThreshold failures for an email,
+// or IPThreshold for a client address, logins are locked for
+// LockoutDuration. Failures are forgotten after Window without a new one.
+type LockoutPolicy struct {
+	FreeAttempts     int
+	MaxDelay         time.Duration
+	AccountThreshold int
+	IPThreshold      int
+	LockoutDuration  time.Duration
+	Window           time.Duration
+}
+
+var DefaultLockoutPolicy = LockoutPolicy{
+	FreeAttempts:     3,
+	MaxDelay:         5 * time.Minute,
+	AccountThreshold: 10,
+	IPThreshold:      100,
+	LockoutDuration:  time.Hour,
+	Window:           time.Hour,
+}
+
+// Delay returns how long logins are refused after the given number of
+// consecutive failures, counted against threshold.
+func (p LockoutPolicy) Delay(failures, threshold int) time.Duration {
+	switch {
+	case failures >= threshold:
+		return p.LockoutDuration
+	case failures <= p.FreeAttempts:
+		return 0
+	}
+
+	delay := time.Second << (failures - p.FreeAttempts - 1)
+	if delay > p.MaxDelay || delay <= 0 {
+		return p.MaxDelay
+	}
+	return delay
+}
+
+// LoginAttempts tracks the recent failed logins of one key, an email or a
+// client address.
+type LoginAttempts struct {
+	Key           string
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   *time.Time
+}
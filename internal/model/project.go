@@ -0,0 +1,35 @@
+package model
+
+import (
+	"time"
+)
+
+type Project struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"userId"`
+	Name      string    `json:"name"`
+	Color     string    `json:"color,omitempty"`
+	Archived  bool      `json:"archived"`
+	Position  int       `json:"position"`
+	CreatedAt time.Time `json:"createdAt,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt,omitempty"`
+	// WorkspaceID is nil for personal projects.
+	WorkspaceID *int `json:"workspaceId,omitempty"`
+}
+
+func (p *Project) OwnerID() int {
+	return p.UserID
+}
+
+func (p *Project) Tenant() Tenant {
+	return workspaceTenant(p.UserID, p.WorkspaceID)
+}
+
+type ProjectDeleteMode string
+
+const (
+	// ProjectDeleteMoveToInbox keeps the todos of a deleted project without a project.
+	ProjectDeleteMoveToInbox ProjectDeleteMode = "inbox"
+	// ProjectDeleteCascade deletes the todos together with the project.
+	ProjectDeleteCascade ProjectDeleteMode = "cascade"
+)
@@ -0,0 +1,29 @@
+package model
+
+import (
+	"time"
+
+	"github.com/King0625/golang-todolist/pkg/textdiff"
+)
+
+// TodoRevision is the title and content of a todo as saved by one change.
+// Revisions of a todo are numbered from 1, its creation. UserID is nil for
+// revisions whose author was deleted.
+type TodoRevision struct {
+	ID        int       `json:"id"`
+	TodoID    int       `json:"todoId"`
+	Revision  int       `json:"revision"`
+	UserID    *int      `json:"userId"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// RevisionDiff is how a todo changed from one revision to another. Title is
+// nil when it stayed the same.
+type RevisionDiff struct {
+	From    int             `json:"from"`
+	To      int             `json:"to"`
+	Title   *Change         `json:"title,omitempty"`
+	Content []textdiff.Line `json:"content"`
+}
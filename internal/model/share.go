@@ -0,0 +1,53 @@
+package model
+
+import "time"
+
+type ShareResource string
+
+const (
+	ShareTodo    ShareResource = "todo"
+	ShareProject ShareResource = "project"
+)
+
+// SharePermission is what a user may do with a todo or project shared with
+// them. Sharing a project shares every todo in it.
+type SharePermission string
+
+const (
+	PermissionNone   SharePermission = ""
+	PermissionViewer SharePermission = "viewer"
+	PermissionEditor SharePermission = "editor"
+)
+
+func (p SharePermission) Valid() bool {
+	return p == PermissionViewer || p == PermissionEditor
+}
+
+// Includes reports whether p grants at least what other does.
+func (p SharePermission) Includes(other SharePermission) bool {
+	rank := map[SharePermission]int{PermissionNone: 0, PermissionViewer: 1, PermissionEditor: 2}
+	return rank[p] >= rank[other]
+}
+
+type ShareStatus string
+
+const (
+	SharePending  ShareStatus = "pending"
+	ShareAccepted ShareStatus = "accepted"
+	ShareDeclined ShareStatus = "declined"
+)
+
+// Share is an invitation of Email to a todo or project of OwnerID. UserID is
+// set once the invitation is accepted.
+type Share struct {
+	ID           int             `json:"id"`
+	ResourceType ShareResource   `json:"resourceType"`
+	ResourceID   int             `json:"resourceId"`
+	OwnerID      int             `json:"ownerId"`
+	Email        string          `json:"email"`
+	UserID       *int            `json:"userId,omitempty"`
+	Permission   SharePermission `json:"permission"`
+	Status       ShareStatus     `json:"status"`
+	CreatedAt    time.Time       `json:"createdAt"`
+	RespondedAt  *time.Time      `json:"respondedAt,omitempty"`
+}
@@ -0,0 +1,24 @@
+package model
+
+import (
+	"time"
+)
+
+type Tag struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"userId"`
+	Name      string    `json:"name"`
+	Color     string    `json:"color,omitempty"`
+	CreatedAt time.Time `json:"createdAt,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt,omitempty"`
+	// WorkspaceID is nil for personal tags.
+	WorkspaceID *int `json:"workspaceId,omitempty"`
+}
+
+func (t *Tag) OwnerID() int {
+	return t.UserID
+}
+
+func (t *Tag) Tenant() Tenant {
+	return workspaceTenant(t.UserID, t.WorkspaceID)
+}
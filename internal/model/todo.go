@@ -1,15 +1,288 @@
 package model
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"time"
 )
 
 type Todo struct {
-	ID        int       `json:"id"`
-	UserID    int       `json:"userId"`
-	Title     string    `json:"title"`
-	Content   string    `json:"content"`
-	CreatedAt time.Time `json:"createdAt,omitempty"`
-	UpdatedAt time.Time `json:"updatedAt,omitempty"`
-	Done      bool      `json:"done,omitempty"`
+	ID        int        `json:"id"`
+	UserID    int        `json:"userId"`
+	ProjectID *int       `json:"projectId"`
+	Title     string     `json:"title"`
+	Content   string     `json:"content"`
+	CreatedAt time.Time  `json:"createdAt,omitempty"`
+	UpdatedAt time.Time  `json:"updatedAt,omitempty"`
+	Done      bool       `json:"done,omitempty"`
+	DueDate   string     `json:"dueDate,omitempty"`
+	DueTime   string     `json:"dueTime,omitempty"`
+	Timezone  string     `json:"timezone,omitempty"`
+	DueAt     *time.Time `json:"dueAt,omitempty"`
+	Priority  Priority   `json:"priority"`
+	DueState  DueState   `json:"dueState,omitempty"`
+	// Recurrence is an RRULE such as FREQ=WEEKLY;BYDAY=MO and RecurrenceStart
+	// the due date and time (DTSTART) of the first todo of the series.
+	Recurrence      string     `json:"recurrence,omitempty"`
+	RecurrenceStart *time.Time `json:"recurrenceStart,omitempty"`
+	// WorkspaceID is nil for personal todos. AssigneeID is the workspace
+	// member the todo is assigned to.
+	WorkspaceID *int `json:"workspaceId,omitempty"`
+	AssigneeID  *int `json:"assigneeId,omitempty"`
+	// Version counts the changes of the todo, including its checklist and
+	// tags. ETag is derived from it.
+	Version int    `json:"-"`
+	ETag    string `json:"etag"`
+	// DeletedAt is set while the todo is in the trash.
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+	Tags      []*Tag     `json:"tags"`
+	Progress  *Progress  `json:"progress,omitempty"`
+}
+
+func (t *Todo) OwnerID() int {
+	return t.UserID
+}
+
+func (t *Todo) Tenant() Tenant {
+	return workspaceTenant(t.UserID, t.WorkspaceID)
+}
+
+// SetVersion sets Version and the ETag that goes with it.
+func (t *Todo) SetVersion(version int) {
+	t.Version = version
+	t.ETag = fmt.Sprintf(`"%d-%d"`, t.ID, version)
+}
+
+const (
+	DueDateLayout = "2006-01-02"
+	DueTimeLayout = "15:04"
+
+	// DueSoonWindow is how close to its deadline an open todo is considered due soon.
+	DueSoonWindow = 24 * time.Hour
+)
+
+type DueState string
+
+const (
+	DueStateNone    DueState = ""
+	DueStateDueSoon DueState = "dueSoon"
+	DueStateOverdue DueState = "overdue"
+)
+
+// SetDue sets the due date of the todo and computes DueAt, the instant the
+// todo becomes overdue. A todo without a due time is due at the end of the day
+// in its timezone. An empty date clears the due date.
+func (t *Todo) SetDue(date, clock, timezone string) error {
+	if date == "" {
+		t.DueDate, t.DueTime, t.Timezone, t.DueAt = "", "", "", nil
+		return nil
+	}
+
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return fmt.Errorf("invalid timezone: %w", err)
+	}
+
+	day, err := time.ParseInLocation(DueDateLayout, date, loc)
+	if err != nil {
+		return fmt.Errorf("invalid due date: %w", err)
+	}
+
+	dueAt := day.AddDate(0, 0, 1)
+	if clock != "" {
+		c, err := time.Parse(DueTimeLayout, clock)
+		if err != nil {
+			return fmt.Errorf("invalid due time: %w", err)
+		}
+		dueAt = time.Date(day.Year(), day.Month(), day.Day(), c.Hour(), c.Minute(), 0, 0, loc)
+	}
+	dueAt = dueAt.UTC()
+
+	t.DueDate, t.DueTime, t.Timezone, t.DueAt = date, clock, timezone, &dueAt
+	return nil
+}
+
+// DueAnchor returns the due date and time in the todo's timezone, with
+// midnight for todos without a due time. It reports false without a due date.
+func (t *Todo) DueAnchor() (time.Time, bool) {
+	if t.DueDate == "" {
+		return time.Time{}, false
+	}
+
+	loc, err := time.LoadLocation(t.Timezone)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	layout, value := DueDateLayout, t.DueDate
+	if t.DueTime != "" {
+		layout, value = DueDateLayout+" "+DueTimeLayout, t.DueDate+" "+t.DueTime
+	}
+
+	anchor, err := time.ParseInLocation(layout, value, loc)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return anchor, true
+}
+
+// SetRecurrence sets the recurrence rule of the todo. A new or changed rule
+// starts its series at the current due date, so SetDue must be called first.
+func (t *Todo) SetRecurrence(rule string) error {
+	if rule == "" {
+		t.Recurrence, t.RecurrenceStart = "", nil
+		return nil
+	}
+	if rule == t.Recurrence && t.RecurrenceStart != nil {
+		return nil
+	}
+
+	anchor, ok := t.DueAnchor()
+	if !ok {
+		return errors.New("a recurring todo needs a due date")
+	}
+
+	start := anchor.UTC()
+	t.Recurrence, t.RecurrenceStart = rule, &start
+	return nil
+}
+
+// ComputeDueState fills DueState relative to now. Done todos are never overdue.
+func (t *Todo) ComputeDueState(now time.Time) {
+	switch {
+	case t.Done || t.DueAt == nil:
+		t.DueState = DueStateNone
+	case !now.Before(*t.DueAt):
+		t.DueState = DueStateOverdue
+	case t.DueAt.Sub(now) <= DueSoonWindow:
+		t.DueState = DueStateDueSoon
+	default:
+		t.DueState = DueStateNone
+	}
+}
+
+type Priority int
+
+const (
+	PriorityNone Priority = iota
+	PriorityLow
+	PriorityMedium
+	PriorityHigh
+	PriorityUrgent
+)
+
+var priorityNames = []string{"none", "low", "medium", "high", "urgent"}
+
+func ParsePriority(s string) (Priority, error) {
+	if s == "" {
+		return PriorityNone, nil
+	}
+	for i, name := range priorityNames {
+		if name == s {
+			return Priority(i), nil
+		}
+	}
+	return PriorityNone, fmt.Errorf("unknown priority %q", s)
+}
+
+func (p Priority) String() string {
+	if p < 0 || int(p) >= len(priorityNames) {
+		return priorityNames[PriorityNone]
+	}
+	return priorityNames[p]
+}
+
+func (p Priority) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+func (p *Priority) UnmarshalText(b []byte) error {
+	parsed, err := ParsePriority(string(b))
+	if err != nil {
+		return err
+	}
+	*p = parsed
+	return nil
+}
+
+type TodoSortField string
+
+const (
+	TodoSortCreatedAt TodoSortField = "createdAt"
+	TodoSortUpdatedAt TodoSortField = "updatedAt"
+	TodoSortTitle     TodoSortField = "title"
+)
+
+// TodoQueryOptions describes how a list of todos is filtered, sorted and paged.
+// Nil pointers and empty strings mean "no filter".
+type TodoQueryOptions struct {
+	// Shared lists the todos others shared with the user, directly or through
+	// a project, and Assigned those assigned to the user in any of their
+	// workspaces, instead of the tenant's.
+	Shared      bool
+	Assigned    bool
+	Limit       int
+	Cursor      *TodoCursor
+	Done        *bool
+	Title       string
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	UpdatedFrom *time.Time
+	UpdatedTo   *time.Time
+	// Tags filters by tag name; with TagMatchAll a todo must carry every tag,
+	// otherwise any of them.
+	Tags        []string
+	ProjectID   *int
+	AssigneeID  *int
+	TagMatchAll bool
+	SortBy      TodoSortField
+	SortDesc    bool
+}
+
+// TodoCursor points at the last todo of a page: the value of the sort column
+// and the id as a tie-breaker.
+type TodoCursor struct {
+	Value string `json:"v"`
+	ID    int    `json:"id"`
+}
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+func (c TodoCursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func DecodeTodoCursor(s string) (*TodoCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c TodoCursor
+	if err := json.Unmarshal(b, &c); err != nil || c.ID <= 0 {
+		return nil, ErrInvalidCursor
+	}
+
+	return &c, nil
+}
+
+type TodoPage struct {
+	Todos      []*Todo
+	NextCursor string
+	HasMore    bool
+}
+
+// TodoSearchResult is a todo matched by a full-text search. The snippets are
+// HTML-escaped with matches wrapped in <mark></mark>.
+type TodoSearchResult struct {
+	*Todo
+	Score          float64 `json:"score"`
+	TitleSnippet   string  `json:"titleSnippet"`
+	ContentSnippet string  `json:"contentSnippet"`
 }
@@ -0,0 +1,62 @@
+package model
+
+import (
+	"time"
+)
+
+// RefreshToken is a server-side record of an issued refresh token. Tokens
+// rotated from the same login share a FamilyID.
+type RefreshToken struct {
+	ID        int
+	UserID    int
+	FamilyID  string
+	TokenHash string
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    int
+}
+
+// PersonalAccessTokenPrefix marks personal access tokens so they can be told
+// apart from JWTs in the Authorization header.
+const PersonalAccessTokenPrefix = "tdl_pat_"
+
+const (
+	ScopeTodosRead  = "todos:read"
+	ScopeTodosWrite = "todos:write"
+	ScopeUserRead   = "user:read"
+)
+
+// PersonalAccessToken is a long-lived credential for scripts, limited to its
+// Scopes. Prefix is the start of the token, shown to help users tell their
+// tokens apart.
+type PersonalAccessToken struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"userId"`
+	Name       string     `json:"name"`
+	TokenHash  string     `json:"-"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	RevokedAt  *time.Time `json:"-"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+func (p *PersonalAccessToken) OwnerID() int {
+	return p.UserID
+}
+
+func (p *PersonalAccessToken) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -4,12 +4,112 @@ import (
 	"time"
 )
 
+type Role string
+
+const (
+	RoleUser  Role = "user"
+	RoleAdmin Role = "admin"
+)
+
+func (r Role) Valid() bool {
+	return r == RoleUser || r == RoleAdmin
+}
+
 type User struct {
-	ID        int       `json:"id"`
-	Email     string    `json:"email"`
-	FirstName string    `json:"firstName,omitempty"`
-	LastName  string    `json:"lastName,omitempty"`
-	Password  string    `json:"-"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	ID              int        `json:"id"`
+	Email           string     `json:"email"`
+	FirstName       string     `json:"firstName,omitempty"`
+	LastName        string     `json:"lastName,omitempty"`
+	Password        string     `json:"-"`
+	Role            Role       `json:"role"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	UpdatedAt       time.Time  `json:"updatedAt"`
+	EmailVerifiedAt *time.Time `json:"emailVerifiedAt,omitempty"`
+	// PendingEmail replaces Email once the user confirms it.
+	PendingEmail *string `json:"pendingEmail,omitempty"`
+	// TOTPSecret is set from 2FA setup on, but only enforced once
+	// TwoFactorEnabledAt is set by confirming a first code.
+	TOTPSecret         string     `json:"-"`
+	TwoFactorEnabledAt *time.Time `json:"twoFactorEnabledAt,omitempty"`
+	// TOTPLastStep is the time step of the last accepted code, which cannot
+	// be used again.
+	TOTPLastStep int64 `json:"-"`
+	// DisabledAt is set by an admin to keep the user from logging in.
+	DisabledAt *time.Time `json:"disabledAt,omitempty"`
+	// TokenGeneration is carried by the access tokens of the user; raising
+	// it invalidates the tokens issued so far.
+	TokenGeneration int `json:"-"`
+}
+
+func (u *User) TwoFactorEnabled() bool {
+	return u.TwoFactorEnabledAt != nil
+}
+
+func (u *User) Disabled() bool {
+	return u.DisabledAt != nil
+}
+
+// UserQuery filters the user list of the admin API. Search matches the
+// email or name; the list is ordered by id and continues after AfterID.
+type UserQuery struct {
+	Search   string
+	Role     Role
+	Disabled *bool
+	AfterID  int
+	Limit    int
+}
+
+type UserPage struct {
+	Users   []*User
+	HasMore bool
+}
+
+// SystemStats are the counts shown on the admin dashboard.
+type SystemStats struct {
+	Users                      int64 `json:"users"`
+	VerifiedUsers              int64 `json:"verifiedUsers"`
+	DisabledUsers              int64 `json:"disabledUsers"`
+	Admins                     int64 `json:"admins"`
+	NewUsersLastWeek           int64 `json:"newUsersLastWeek"`
+	Todos                      int64 `json:"todos"`
+	DoneTodos                  int64 `json:"doneTodos"`
+	TrashedTodos               int64 `json:"trashedTodos"`
+	Projects                   int64 `json:"projects"`
+	Tags                       int64 `json:"tags"`
+	ActivePersonalAccessTokens int64 `json:"activePersonalAccessTokens"`
+}
+
+type TokenPurpose string
+
+const (
+	TokenPurposePasswordReset     TokenPurpose = "password_reset"
+	TokenPurposeEmailVerification TokenPurpose = "email_verification"
+	TokenPurposeTwoFactorLogin    TokenPurpose = "two_factor_login"
+	TokenPurposeAccountUnlock     TokenPurpose = "account_unlock"
+	TokenPurposeEmailChange       TokenPurpose = "email_change"
+)
+
+// UserToken is a single-use, time-limited token mailed to a user. Only the
+// hash of the token is stored.
+type UserToken struct {
+	ID        int
+	UserID    int
+	Purpose   TokenPurpose
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
+
+// VerificationPolicy limits accounts whose email is not verified yet. With
+// BlockLogin set, such users cannot log in once GracePeriod has passed since
+// registration.
+type VerificationPolicy struct {
+	BlockLogin  bool
+	GracePeriod time.Duration
+}
+
+// TwoFactorSetup is what an authenticator app needs to enroll a user.
+type TwoFactorSetup struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauthUri"`
 }
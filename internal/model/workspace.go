@@ -0,0 +1,70 @@
+package model
+
+import "time"
+
+// Workspace is a team space whose todos and projects belong to all of its
+// members rather than to the user who created them.
+type Workspace struct {
+	ID        int       `json:"id"`
+	OwnerID   int       `json:"ownerId"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt,omitempty"`
+	// Role is the role of the requesting user in the workspace.
+	Role WorkspaceRole `json:"role,omitempty"`
+}
+
+type WorkspaceRole string
+
+const (
+	// WorkspaceRoleOwner is the creator of the workspace. There is exactly one
+	// and the role can be neither given away nor taken.
+	WorkspaceRoleOwner  WorkspaceRole = "owner"
+	WorkspaceRoleAdmin  WorkspaceRole = "admin"
+	WorkspaceRoleMember WorkspaceRole = "member"
+)
+
+func (r WorkspaceRole) Valid() bool {
+	return r == WorkspaceRoleOwner || r == WorkspaceRoleAdmin || r == WorkspaceRoleMember
+}
+
+// CanManage reports whether the role may rename the workspace, manage its
+// members and delete anything in it.
+func (r WorkspaceRole) CanManage() bool {
+	return r == WorkspaceRoleOwner || r == WorkspaceRoleAdmin
+}
+
+type WorkspaceMember struct {
+	WorkspaceID int           `json:"workspaceId"`
+	UserID      int           `json:"userId"`
+	Email       string        `json:"email"`
+	FirstName   string        `json:"firstName"`
+	LastName    string        `json:"lastName"`
+	Role        WorkspaceRole `json:"role"`
+	CreatedAt   time.Time     `json:"createdAt"`
+}
+
+// Tenant is the space a request works in: the personal todos and projects of
+// UserID, or those of the workspace WorkspaceID when it is not zero.
+type Tenant struct {
+	UserID      int
+	WorkspaceID int
+}
+
+// Equal reports whether t and other are the same space. Within a workspace
+// the user does not matter.
+func (t Tenant) Equal(other Tenant) bool {
+	if t.WorkspaceID != 0 || other.WorkspaceID != 0 {
+		return t.WorkspaceID == other.WorkspaceID
+	}
+	return t.UserID == other.UserID
+}
+
+// workspaceTenant returns the tenant of something belonging to userID or, when
+// workspaceID is set, to that workspace.
+func workspaceTenant(userID int, workspaceID *int) Tenant {
+	if workspaceID == nil {
+		return Tenant{UserID: userID}
+	}
+	return Tenant{UserID: userID, WorkspaceID: *workspaceID}
+}
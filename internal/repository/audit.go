@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+
+	"github.com/King0625/golang-todolist/internal/model"
+)
+
+// AuditRepository stores the audit log. It is append-only: events are never
+// changed or deleted, not even with the users they are about.
+type AuditRepository interface {
+	Create(ctx context.Context, event *model.AuditEvent) error
+	List(ctx context.Context, query model.AuditQuery) (*model.AuditPage, error)
+}
+
+const auditColumns = "id, actor_id, action, target_type, target_id, detail, changes, ip, user_agent, request_id, createdAt"
+
+func scanAuditEvent(s rowScanner) (*model.AuditEvent, error) {
+	var event model.AuditEvent
+	var actorID, targetID sql.NullInt64
+	var changes []byte
+
+	err := s.Scan(
+		&event.ID,
+		&actorID,
+		&event.Action,
+		&event.TargetType,
+		&targetID,
+		&event.Detail,
+		&changes,
+		&event.IP,
+		&event.UserAgent,
+		&event.RequestID,
+		&event.CreatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	event.ActorID = intPointer(actorID)
+	event.TargetID = intPointer(targetID)
+	if len(changes) > 0 {
+		if err := json.Unmarshal(changes, &event.Changes); err != nil {
+			return nil, err
+		}
+	}
+
+	return &event, nil
+}
+
+type auditRepository struct {
+	db *sql.DB
+}
+
+func NewAuditRepository(db *sql.DB) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+func (a *auditRepository) Create(ctx context.Context, event *model.AuditEvent) error {
+	var changes []byte
+	if len(event.Changes) > 0 {
+		var err error
+		if changes, err = json.Marshal(event.Changes); err != nil {
+			return err
+		}
+	}
+
+	query := `INSERT INTO audit_events (actor_id, action, target_type, target_id, detail, changes, ip, user_agent, request_id, createdAt) VALUES(?,?,?,?,?,?,?,?,?,?)`
+	result, err := a.db.ExecContext(ctx, query,
+		nullInt(event.ActorID),
+		event.Action,
+		event.TargetType,
+		nullInt(event.TargetID),
+		event.Detail,
+		nullString(string(changes)),
+		event.IP,
+		event.UserAgent,
+		event.RequestID,
+		event.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	event.ID, err = result.LastInsertId()
+	return err
+}
+
+func (a *auditRepository) List(ctx context.Context, query model.AuditQuery) (*model.AuditPage, error) {
+	var conditions []string
+	var args []any
+
+	if query.BeforeID > 0 {
+		conditions = append(conditions, "id < ?")
+		args = append(args, query.BeforeID)
+	}
+	if query.SubjectID != nil {
+		conditions = append(conditions, "(actor_id = ? OR (target_type = ? AND target_id = ?))")
+		args = append(args, *query.SubjectID, model.AuditTargetUser, *query.SubjectID)
+	}
+	if query.ActorID != nil {
+		conditions = append(conditions, "actor_id = ?")
+		args = append(args, *query.ActorID)
+	}
+	if query.Action != "" {
+		conditions = append(conditions, "action = ?")
+		args = append(args, query.Action)
+	}
+	if query.TargetType != "" {
+		conditions = append(conditions, "target_type = ?")
+		args = append(args, query.TargetType)
+	}
+	if query.TargetID != nil {
+		conditions = append(conditions, "target_id = ?")
+		args = append(args, *query.TargetID)
+	}
+	if query.From != nil {
+		conditions = append(conditions, "createdAt >= ?")
+		args = append(args, *query.From)
+	}
+	if query.To != nil {
+		conditions = append(conditions, "createdAt < ?")
+		args = append(args, *query.To)
+	}
+
+	listQuery := "SELECT " + auditColumns + " FROM audit_events"
+	if len(conditions) > 0 {
+		listQuery += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	// one extra row tells whether there is another page
+	listQuery += " ORDER BY id DESC LIMIT ?"
+	args = append(args, query.Limit+1)
+
+	rows, err := a.db.QueryContext(ctx, listQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	page := model.AuditPage{Events: []*model.AuditEvent{}}
+	for rows.Next() {
+		event, err := scanAuditEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		page.Events = append(page.Events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(page.Events) > query.Limit {
+		page.Events = page.Events[:query.Limit]
+		page.HasMore = true
+	}
+
+	return &page, nil
+}
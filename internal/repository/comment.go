@@ -0,0 +1,214 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/King0625/golang-todolist/internal/model"
+)
+
+type CommentRepository interface {
+	Create(ctx context.Context, comment *model.Comment) error
+	GetById(ctx context.Context, id int) (*model.Comment, error)
+	GetAllByTodoId(ctx context.Context, todoID int) ([]*model.Comment, error)
+	UpdateById(ctx context.Context, id int, content string, mentions []int) error
+	DeleteById(ctx context.Context, id int) error
+}
+
+const commentColumns = "id, todo_id, user_id, content, createdAt, editedAt"
+
+func scanComment(s rowScanner) (*model.Comment, error) {
+	var comment model.Comment
+	var editedAt sql.NullTime
+
+	err := s.Scan(
+		&comment.ID,
+		&comment.TodoID,
+		&comment.UserID,
+		&comment.Content,
+		&comment.CreatedAt,
+		&editedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if editedAt.Valid {
+		comment.EditedAt = &editedAt.Time
+	}
+	comment.Mentions = []int{}
+
+	return &comment, nil
+}
+
+type commentRepository struct {
+	db *sql.DB
+}
+
+func NewCommentRepository(db *sql.DB) CommentRepository {
+	return &commentRepository{db: db}
+}
+
+func insertMentions(ctx context.Context, db execer, commentID int, mentions []int) error {
+	if len(mentions) == 0 {
+		return nil
+	}
+
+	values := make([]string, 0, len(mentions))
+	args := make([]any, 0, 2*len(mentions))
+	for _, userID := range mentions {
+		values = append(values, "(?,?)")
+		args = append(args, commentID, userID)
+	}
+
+	query := "INSERT IGNORE INTO comment_mentions (comment_id, user_id) VALUES " + strings.Join(values, ",")
+	_, err := db.ExecContext(ctx, query, args...)
+
+	return err
+}
+
+// loadMentions fills the mentions of the given comments.
+func (c *commentRepository) loadMentions(ctx context.Context, comments ...*model.Comment) error {
+	if len(comments) == 0 {
+		return nil
+	}
+
+	byID := make(map[int]*model.Comment, len(comments))
+	args := make([]any, len(comments))
+	for i, comment := range comments {
+		byID[comment.ID] = comment
+		args[i] = comment.ID
+	}
+
+	query := `SELECT comment_id, user_id FROM comment_mentions WHERE comment_id IN (` + placeholders(len(args)) + `) ORDER BY user_id`
+	rows, err := c.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var commentID, userID int
+		if err := rows.Scan(&commentID, &userID); err != nil {
+			return err
+		}
+		byID[commentID].Mentions = append(byID[commentID].Mentions, userID)
+	}
+
+	return rows.Err()
+}
+
+// Create stores the comment together with its mentions.
+func (c *commentRepository) Create(ctx context.Context, comment *model.Comment) error {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	query := `INSERT INTO todo_comments (todo_id, user_id, content, createdAt) VALUES(?,?,?,?)`
+	result, err := tx.ExecContext(ctx, query, comment.TodoID, comment.UserID, comment.Content, now)
+	if err != nil {
+		return err
+	}
+
+	newId, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	if err := insertMentions(ctx, tx, int(newId), comment.Mentions); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	comment.ID = int(newId)
+	comment.CreatedAt = now
+	if comment.Mentions == nil {
+		comment.Mentions = []int{}
+	}
+
+	return nil
+}
+
+func (c *commentRepository) GetById(ctx context.Context, id int) (*model.Comment, error) {
+	query := "SELECT " + commentColumns + " FROM todo_comments WHERE id = ?"
+
+	comment, err := scanComment(c.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.loadMentions(ctx, comment); err != nil {
+		return nil, err
+	}
+
+	return comment, nil
+}
+
+func (c *commentRepository) GetAllByTodoId(ctx context.Context, todoID int) ([]*model.Comment, error) {
+	query := "SELECT " + commentColumns + " FROM todo_comments WHERE todo_id = ? ORDER BY createdAt, id"
+
+	rows, err := c.db.QueryContext(ctx, query, todoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := []*model.Comment{}
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		comments = append(comments, comment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := c.loadMentions(ctx, comments...); err != nil {
+		return nil, err
+	}
+
+	return comments, nil
+}
+
+// UpdateById replaces the content and mentions of the comment and marks it
+// edited.
+func (c *commentRepository) UpdateById(ctx context.Context, id int, content string, mentions []int) error {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE todo_comments SET content = ?, editedAt = ? WHERE id = ?`
+	if _, err := tx.ExecContext(ctx, query, content, time.Now(), id); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM comment_mentions WHERE comment_id = ?`, id); err != nil {
+		return err
+	}
+
+	if err := insertMentions(ctx, tx, id, mentions); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (c *commentRepository) DeleteById(ctx context.Context, id int) error {
+	_, err := c.db.ExecContext(ctx, `DELETE FROM todo_comments WHERE id = ?`, id)
+
+	return err
+}
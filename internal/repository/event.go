@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/King0625/golang-todolist/internal/model"
+)
+
+type TodoEventRepository interface {
+	Create(ctx context.Context, events ...*model.TodoEvent) error
+	GetAllByTodoId(ctx context.Context, todoID int) ([]*model.TodoEvent, error)
+}
+
+const todoEventColumns = "id, todo_id, user_id, type, old_value, new_value, createdAt"
+
+func scanTodoEvent(s rowScanner) (*model.TodoEvent, error) {
+	var event model.TodoEvent
+	var userID sql.NullInt64
+	var from, to sql.NullString
+
+	err := s.Scan(
+		&event.ID,
+		&event.TodoID,
+		&userID,
+		&event.Type,
+		&from,
+		&to,
+		&event.CreatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	event.UserID = intPointer(userID)
+	event.From = from.String
+	event.To = to.String
+
+	return &event, nil
+}
+
+type todoEventRepository struct {
+	db *sql.DB
+}
+
+func NewTodoEventRepository(db *sql.DB) TodoEventRepository {
+	return &todoEventRepository{db: db}
+}
+
+func (t *todoEventRepository) Create(ctx context.Context, events ...*model.TodoEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	values := make([]string, 0, len(events))
+	args := make([]any, 0, 5*len(events))
+	for _, event := range events {
+		values = append(values, "(?,?,?,?,?)")
+		args = append(args, event.TodoID, nullInt(event.UserID), event.Type, nullString(event.From), nullString(event.To))
+	}
+
+	query := "INSERT INTO todo_events (todo_id, user_id, type, old_value, new_value) VALUES " + strings.Join(values, ",")
+	_, err := t.db.ExecContext(ctx, query, args...)
+
+	return err
+}
+
+func (t *todoEventRepository) GetAllByTodoId(ctx context.Context, todoID int) ([]*model.TodoEvent, error) {
+	query := "SELECT " + todoEventColumns + " FROM todo_events WHERE todo_id = ? ORDER BY createdAt, id"
+
+	rows, err := t.db.QueryContext(ctx, query, todoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []*model.TodoEvent{}
+	for rows.Next() {
+		event, err := scanTodoEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/King0625/golang-todolist/internal/model"
+)
+
+type DataExportRepository interface {
+	Create(ctx context.Context, export *model.DataExport) error
+	GetById(ctx context.Context, id int) (*model.DataExport, error)
+	GetActiveByUserId(ctx context.Context, userID int) (*model.DataExport, error)
+	GetArchiveById(ctx context.Context, id int) ([]byte, error)
+	GetPendingIds(ctx context.Context, limit int) ([]int, error)
+	ClaimById(ctx context.Context, id int) (bool, error)
+	CompleteById(ctx context.Context, id int, archive []byte, expiresAt time.Time) error
+	FailById(ctx context.Context, id int, reason string) error
+	ResetStale(ctx context.Context, startedBefore time.Time) (int64, error)
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+const dataExportColumns = "id, user_id, status, error, size, createdAt, completedAt, expiresAt"
+
+func scanDataExport(s rowScanner) (*model.DataExport, error) {
+	var export model.DataExport
+	var completedAt, expiresAt sql.NullTime
+
+	err := s.Scan(
+		&export.ID,
+		&export.UserID,
+		&export.Status,
+		&export.Error,
+		&export.Size,
+		&export.CreatedAt,
+		&completedAt,
+		&expiresAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if completedAt.Valid {
+		export.CompletedAt = &completedAt.Time
+	}
+	if expiresAt.Valid {
+		export.ExpiresAt = &expiresAt.Time
+	}
+
+	return &export, nil
+}
+
+type dataExportRepository struct {
+	db *sql.DB
+}
+
+func NewDataExportRepository(db *sql.DB) DataExportRepository {
+	return &dataExportRepository{db: db}
+}
+
+func (d *dataExportRepository) Create(ctx context.Context, export *model.DataExport) error {
+	insertQuery := `INSERT INTO data_exports (user_id, status, createdAt) VALUES(?,?,?)`
+
+	export.Status = model.ExportPending
+	export.CreatedAt = time.Now()
+	result, err := d.db.ExecContext(ctx, insertQuery, export.UserID, export.Status, export.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	newId, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	export.ID = int(newId)
+
+	return nil
+}
+
+func (d *dataExportRepository) GetById(ctx context.Context, id int) (*model.DataExport, error) {
+	query := "SELECT " + dataExportColumns + " FROM data_exports WHERE id = ?"
+
+	return scanDataExport(d.db.QueryRowContext(ctx, query, id))
+}
+
+// GetActiveByUserId returns the user's export that is still being built.
+func (d *dataExportRepository) GetActiveByUserId(ctx context.Context, userID int) (*model.DataExport, error) {
+	query := "SELECT " + dataExportColumns + ` FROM data_exports
+WHERE user_id = ? AND status IN (?, ?) ORDER BY id DESC LIMIT 1`
+
+	return scanDataExport(d.db.QueryRowContext(ctx, query, userID, model.ExportPending, model.ExportRunning))
+}
+
+func (d *dataExportRepository) GetArchiveById(ctx context.Context, id int) ([]byte, error) {
+	query := `SELECT archive FROM data_exports WHERE id = ? AND status = ?`
+
+	var archive []byte
+	err := d.db.QueryRowContext(ctx, query, id, model.ExportReady).Scan(&archive)
+
+	return archive, err
+}
+
+func (d *dataExportRepository) GetPendingIds(ctx context.Context, limit int) ([]int, error) {
+	query := `SELECT id FROM data_exports WHERE status = ? ORDER BY createdAt, id LIMIT ?`
+
+	rows, err := d.db.QueryContext(ctx, query, model.ExportPending, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// ClaimById marks a pending export as running. It reports false when another
+// worker got to it first.
+func (d *dataExportRepository) ClaimById(ctx context.Context, id int) (bool, error) {
+	query := `UPDATE data_exports SET status = ?, startedAt = ? WHERE id = ? AND status = ?`
+	result, err := d.db.ExecContext(ctx, query, model.ExportRunning, time.Now(), id, model.ExportPending)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
+func (d *dataExportRepository) CompleteById(ctx context.Context, id int, archive []byte, expiresAt time.Time) error {
+	query := `UPDATE data_exports SET status = ?, archive = ?, size = ?, completedAt = ?, expiresAt = ? WHERE id = ?`
+	_, err := d.db.ExecContext(ctx, query, model.ExportReady, archive, len(archive), time.Now(), expiresAt, id)
+
+	return err
+}
+
+func (d *dataExportRepository) FailById(ctx context.Context, id int, reason string) error {
+	query := `UPDATE data_exports SET status = ?, error = ?, completedAt = ? WHERE id = ?`
+	_, err := d.db.ExecContext(ctx, query, model.ExportFailed, reason, time.Now(), id)
+
+	return err
+}
+
+// ResetStale puts exports back in the queue whose worker has gone away, e.g.
+// because the instance running it was restarted.
+func (d *dataExportRepository) ResetStale(ctx context.Context, startedBefore time.Time) (int64, error) {
+	query := `UPDATE data_exports SET status = ?, startedAt = NULL WHERE status = ? AND startedAt < ?`
+	result, err := d.db.ExecContext(ctx, query, model.ExportPending, model.ExportRunning, startedBefore)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// DeleteExpired removes downloads past their expiry, and failed exports
+// after a day.
+func (d *dataExportRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	query := `DELETE FROM data_exports WHERE expiresAt < ? OR (status = ? AND completedAt < ?)`
+	result, err := d.db.ExecContext(ctx, query, now, model.ExportFailed, now.Add(-24*time.Hour))
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/King0625/golang-todolist/internal/model"
+)
+
+type UserIdentityRepository interface {
+	Create(ctx context.Context, identity *model.UserIdentity) error
+	GetByProviderSubject(ctx context.Context, provider, subject string) (*model.UserIdentity, error)
+	GetAllByUserId(ctx context.Context, userID int) ([]*model.UserIdentity, error)
+}
+
+type userIdentityRepository struct {
+	db *sql.DB
+}
+
+func NewUserIdentityRepository(db *sql.DB) UserIdentityRepository {
+	return &userIdentityRepository{db: db}
+}
+
+func (u *userIdentityRepository) Create(ctx context.Context, identity *model.UserIdentity) error {
+	insertQuery := `INSERT INTO user_identities (user_id, provider, subject, email) VALUES(?,?,?,?)`
+
+	identity.CreatedAt = time.Now()
+	result, err := u.db.ExecContext(ctx, insertQuery,
+		identity.UserID,
+		identity.Provider,
+		identity.Subject,
+		identity.Email,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	newId, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	identity.ID = int(newId)
+
+	return nil
+}
+
+func (u *userIdentityRepository) GetByProviderSubject(ctx context.Context, provider, subject string) (*model.UserIdentity, error) {
+	query := `SELECT id, user_id, provider, subject, email, createdAt FROM user_identities WHERE provider = ? AND subject = ?`
+
+	var identity model.UserIdentity
+	err := u.db.QueryRowContext(ctx, query, provider, subject).Scan(
+		&identity.ID,
+		&identity.UserID,
+		&identity.Provider,
+		&identity.Subject,
+		&identity.Email,
+		&identity.CreatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &identity, nil
+}
+
+func (u *userIdentityRepository) GetAllByUserId(ctx context.Context, userID int) ([]*model.UserIdentity, error) {
+	query := `SELECT id, user_id, provider, subject, email, createdAt FROM user_identities WHERE user_id = ? ORDER BY id`
+
+	rows, err := u.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var identities []*model.UserIdentity
+	for rows.Next() {
+		var identity model.UserIdentity
+		if err := rows.Scan(
+			&identity.ID,
+			&identity.UserID,
+			&identity.Provider,
+			&identity.Subject,
+			&identity.Email,
+			&identity.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		identities = append(identities, &identity)
+	}
+
+	return identities, rows.Err()
+}
@@ -0,0 +1,205 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/King0625/golang-todolist/internal/model"
+)
+
+type ChecklistItemRepository interface {
+	Create(ctx context.Context, item *model.ChecklistItem) error
+	GetAllByTodoId(ctx context.Context, todoID int) ([]*model.ChecklistItem, error)
+	GetById(ctx context.Context, id int) (*model.ChecklistItem, error)
+	UpdateById(ctx context.Context, id int, item *model.ChecklistItem) error
+	DeleteById(ctx context.Context, id int) error
+}
+
+const checklistItemColumns = "id, todo_id, title, done, position, createdAt, updatedAt"
+
+func scanChecklistItem(s rowScanner) (*model.ChecklistItem, error) {
+	var item model.ChecklistItem
+
+	err := s.Scan(
+		&item.ID,
+		&item.TodoID,
+		&item.Title,
+		&item.Done,
+		&item.Position,
+		&item.CreatedAt,
+		&item.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &item, nil
+}
+
+type checklistItemRepository struct {
+	db *sql.DB
+}
+
+func NewChecklistItemRepository(db *sql.DB) ChecklistItemRepository {
+	return &checklistItemRepository{db: db}
+}
+
+// Create inserts the item, and bumps the version of its todo in the same
+// transaction; a negative position appends it to the checklist.
+func (c *checklistItemRepository) Create(ctx context.Context, item *model.ChecklistItem) error {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if item.Position < 0 {
+		query := `SELECT COALESCE(MAX(position) + 1, 0) FROM todo_items WHERE todo_id = ?`
+		if err := tx.QueryRowContext(ctx, query, item.TodoID).Scan(&item.Position); err != nil {
+			return err
+		}
+	}
+
+	insertItemQuery := `INSERT INTO todo_items (todo_id, title, done, position) VALUES(?,?,?,?)`
+
+	result, err := tx.ExecContext(ctx, insertItemQuery,
+		item.TodoID,
+		item.Title,
+		item.Done,
+		item.Position,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	newId, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	if err := touchTodo(ctx, tx, item.TodoID); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	item.ID = int(newId)
+
+	return nil
+}
+
+func (c *checklistItemRepository) GetAllByTodoId(ctx context.Context, todoID int) ([]*model.ChecklistItem, error) {
+	query := "SELECT " + checklistItemColumns + " FROM todo_items WHERE todo_id = ? ORDER BY position, id"
+	rows, err := c.db.QueryContext(ctx, query, todoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*model.ChecklistItem{}
+	for rows.Next() {
+		item, err := scanChecklistItem(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+func (c *checklistItemRepository) GetById(ctx context.Context, id int) (*model.ChecklistItem, error) {
+	query := "SELECT " + checklistItemColumns + " FROM todo_items WHERE id = ?"
+
+	return scanChecklistItem(c.db.QueryRowContext(ctx, query, id))
+}
+
+// touchItemTodo bumps the version of the todo the item belongs to, whose
+// representation includes the progress of its checklist.
+func touchItemTodo(ctx context.Context, db execer, id int) error {
+	query := `UPDATE todos JOIN todo_items i ON i.todo_id = todos.id SET todos.version = todos.version + 1 WHERE i.id = ?`
+	_, err := db.ExecContext(ctx, query, id)
+
+	return err
+}
+
+// UpdateById saves the item and bumps the version of its todo in one
+// transaction.
+func (c *checklistItemRepository) UpdateById(ctx context.Context, id int, item *model.ChecklistItem) error {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := "UPDATE todo_items SET title = ?, done = ?, position = ?, updatedAt = ? WHERE id = ?"
+	if _, err := tx.ExecContext(ctx, query, item.Title, item.Done, item.Position, time.Now(), id); err != nil {
+		return err
+	}
+	if err := touchItemTodo(ctx, tx, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteById removes the item and bumps the version of its todo in one
+// transaction.
+func (c *checklistItemRepository) DeleteById(ctx context.Context, id int) error {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := touchItemTodo(ctx, tx, id); err != nil {
+		return err
+	}
+
+	query := `DELETE FROM todo_items WHERE id = ?`
+	if _, err := tx.ExecContext(ctx, query, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// progressByTodoIds summarizes the checklists of the given todos, keyed by
+// todo id. Todos without items are absent from the result.
+func progressByTodoIds(ctx context.Context, db *sql.DB, todoIDs []int) (map[int]*model.Progress, error) {
+	result := make(map[int]*model.Progress)
+	if len(todoIDs) == 0 {
+		return result, nil
+	}
+
+	args := make([]any, len(todoIDs))
+	for i, id := range todoIDs {
+		args[i] = id
+	}
+
+	query := `SELECT todo_id, COALESCE(SUM(done), 0), COUNT(*) FROM todo_items
+WHERE todo_id IN (` + placeholders(len(todoIDs)) + `) GROUP BY todo_id`
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var todoID int
+		var progress model.Progress
+		if err := rows.Scan(&todoID, &progress.Done, &progress.Total); err != nil {
+			return nil, err
+		}
+
+		result[todoID] = &progress
+	}
+
+	return result, rows.Err()
+}
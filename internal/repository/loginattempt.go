@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/King0625/golang-todolist/internal/model"
+)
+
+// LoginAttemptRepository stores failed login counters. The MySQL store is
+// shared by every instance of the app; the memory store only suits a single
+// instance.
+type LoginAttemptRepository interface {
+	// Get returns nil when key has no failures recorded.
+	Get(ctx context.Context, key string) (*model.LoginAttempts, error)
+	// RecordFailure counts a failure at now, restarting the count when the
+	// last failure is older than window.
+	RecordFailure(ctx context.Context, key string, now time.Time, window time.Duration) (*model.LoginAttempts, error)
+	SetLockedUntil(ctx context.Context, key string, until time.Time) error
+	Reset(ctx context.Context, key string) error
+	DeleteStale(ctx context.Context, before time.Time) (int64, error)
+}
+
+type loginAttemptRepository struct {
+	db *sql.DB
+}
+
+func NewLoginAttemptRepository(db *sql.DB) LoginAttemptRepository {
+	return &loginAttemptRepository{db: db}
+}
+
+func (l *loginAttemptRepository) Get(ctx context.Context, key string) (*model.LoginAttempts, error) {
+	query := `SELECT attempt_key, failures, lastFailureAt, lockedUntil FROM login_attempts WHERE attempt_key = ?`
+
+	var attempts model.LoginAttempts
+	var lockedUntil sql.NullTime
+
+	err := l.db.QueryRowContext(ctx, query, key).Scan(
+		&attempts.Key,
+		&attempts.Failures,
+		&attempts.LastFailureAt,
+		&lockedUntil,
+	)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if lockedUntil.Valid {
+		attempts.LockedUntil = &lockedUntil.Time
+	}
+
+	return &attempts, nil
+}
+
+func (l *loginAttemptRepository) RecordFailure(ctx context.Context, key string, now time.Time, window time.Duration) (*model.LoginAttempts, error) {
+	// the assignments run left to right, so failures still sees the old lastFailureAt
+	query := `INSERT INTO login_attempts (attempt_key, failures, lastFailureAt) VALUES(?, 1, ?)
+ON DUPLICATE KEY UPDATE failures = IF(lastFailureAt < ?, 1, failures + 1), lastFailureAt = ?`
+
+	if _, err := l.db.ExecContext(ctx, query, key, now, now.Add(-window), now); err != nil {
+		return nil, err
+	}
+
+	return l.Get(ctx, key)
+}
+
+func (l *loginAttemptRepository) SetLockedUntil(ctx context.Context, key string, until time.Time) error {
+	query := `UPDATE login_attempts SET lockedUntil = ? WHERE attempt_key = ?`
+	_, err := l.db.ExecContext(ctx, query, until, key)
+
+	return err
+}
+
+func (l *loginAttemptRepository) Reset(ctx context.Context, key string) error {
+	_, err := l.db.ExecContext(ctx, `DELETE FROM login_attempts WHERE attempt_key = ?`, key)
+
+	return err
+}
+
+// DeleteStale drops counters without failures since before that are not
+// locked anymore.
+func (l *loginAttemptRepository) DeleteStale(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM login_attempts WHERE lastFailureAt < ? AND (lockedUntil IS NULL OR lockedUntil < ?)`
+	result, err := l.db.ExecContext(ctx, query, before, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+type memoryLoginAttemptRepository struct {
+	mu       sync.Mutex
+	attempts map[string]model.LoginAttempts
+}
+
+func NewMemoryLoginAttemptRepository() LoginAttemptRepository {
+	return &memoryLoginAttemptRepository{attempts: map[string]model.LoginAttempts{}}
+}
+
+func (m *memoryLoginAttemptRepository) Get(ctx context.Context, key string) (*model.LoginAttempts, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	attempts, ok := m.attempts[key]
+	if !ok {
+		return nil, nil
+	}
+	return &attempts, nil
+}
+
+func (m *memoryLoginAttemptRepository) RecordFailure(ctx context.Context, key string, now time.Time, window time.Duration) (*model.LoginAttempts, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	attempts, ok := m.attempts[key]
+	if !ok || attempts.LastFailureAt.Before(now.Add(-window)) {
+		attempts = model.LoginAttempts{Key: key, LockedUntil: attempts.LockedUntil}
+	}
+	attempts.Failures++
+	attempts.LastFailureAt = now
+	m.attempts[key] = attempts
+
+	return &attempts, nil
+}
+
+func (m *memoryLoginAttemptRepository) SetLockedUntil(ctx context.Context, key string, until time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if attempts, ok := m.attempts[key]; ok {
+		attempts.LockedUntil = &until
+		m.attempts[key] = attempts
+	}
+	return nil
+}
+
+func (m *memoryLoginAttemptRepository) Reset(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.attempts, key)
+	return nil
+}
+
+func (m *memoryLoginAttemptRepository) DeleteStale(ctx context.Context, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	var deleted int64
+	for key, attempts := range m.attempts {
+		if attempts.LastFailureAt.Before(before) && (attempts.LockedUntil == nil || attempts.LockedUntil.Before(now)) {
+			delete(m.attempts, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/King0625/golang-todolist/internal/model"
+)
+
+type OIDCStateRepository interface {
+	Create(ctx context.Context, state *model.OIDCLoginState) error
+	ConsumeByHash(ctx context.Context, hash string) (*model.OIDCLoginState, error)
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+type oidcStateRepository struct {
+	db *sql.DB
+}
+
+func NewOIDCStateRepository(db *sql.DB) OIDCStateRepository {
+	return &oidcStateRepository{db: db}
+}
+
+func (o *oidcStateRepository) Create(ctx context.Context, state *model.OIDCLoginState) error {
+	insertQuery := `INSERT INTO oidc_login_states (state_hash, provider, nonce, code_verifier, expiresAt) VALUES(?,?,?,?,?)`
+
+	result, err := o.db.ExecContext(ctx, insertQuery,
+		state.StateHash,
+		state.Provider,
+		state.Nonce,
+		state.CodeVerifier,
+		state.ExpiresAt,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	newId, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	state.ID = int(newId)
+
+	return nil
+}
+
+// ConsumeByHash returns the state and deletes it, so each state completes at
+// most one login. It returns sql.ErrNoRows when the state is unknown or was
+// consumed concurrently.
+func (o *oidcStateRepository) ConsumeByHash(ctx context.Context, hash string) (*model.OIDCLoginState, error) {
+	query := `SELECT id, state_hash, provider, nonce, code_verifier, expiresAt FROM oidc_login_states WHERE state_hash = ?`
+
+	var state model.OIDCLoginState
+	err := o.db.QueryRowContext(ctx, query, hash).Scan(
+		&state.ID,
+		&state.StateHash,
+		&state.Provider,
+		&state.Nonce,
+		&state.CodeVerifier,
+		&state.ExpiresAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := o.db.ExecContext(ctx, `DELETE FROM oidc_login_states WHERE id = ?`, state.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if affected == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return &state, nil
+}
+
+func (o *oidcStateRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	query := `DELETE FROM oidc_login_states WHERE expiresAt < ?`
+	result, err := o.db.ExecContext(ctx, query, now)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/King0625/golang-todolist/internal/model"
+)
+
+type PersonalAccessTokenRepository interface {
+	Create(ctx context.Context, token *model.PersonalAccessToken) error
+	GetActiveByUserId(ctx context.Context, userID int) ([]*model.PersonalAccessToken, error)
+	GetById(ctx context.Context, id int) (*model.PersonalAccessToken, error)
+	GetByHash(ctx context.Context, hash string) (*model.PersonalAccessToken, error)
+	TouchById(ctx context.Context, id int, usedAt time.Time) error
+	RevokeById(ctx context.Context, id int) error
+}
+
+const personalAccessTokenColumns = "id, user_id, name, token_hash, token_prefix, scopes, expiresAt, lastUsedAt, revokedAt, createdAt"
+
+func scanPersonalAccessToken(s rowScanner) (*model.PersonalAccessToken, error) {
+	var token model.PersonalAccessToken
+	var scopes string
+	var expiresAt, lastUsedAt, revokedAt sql.NullTime
+
+	err := s.Scan(
+		&token.ID,
+		&token.UserID,
+		&token.Name,
+		&token.TokenHash,
+		&token.Prefix,
+		&scopes,
+		&expiresAt,
+		&lastUsedAt,
+		&revokedAt,
+		&token.CreatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	token.Scopes = strings.Fields(scopes)
+	if expiresAt.Valid {
+		token.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		token.LastUsedAt = &lastUsedAt.Time
+	}
+	if revokedAt.Valid {
+		token.RevokedAt = &revokedAt.Time
+	}
+
+	return &token, nil
+}
+
+type personalAccessTokenRepository struct {
+	db *sql.DB
+}
+
+func NewPersonalAccessTokenRepository(db *sql.DB) PersonalAccessTokenRepository {
+	return &personalAccessTokenRepository{db: db}
+}
+
+func (p *personalAccessTokenRepository) Create(ctx context.Context, token *model.PersonalAccessToken) error {
+	insertQuery := `INSERT INTO personal_access_tokens (user_id, name, token_hash, token_prefix, scopes, expiresAt) VALUES(?,?,?,?,?,?)`
+
+	token.CreatedAt = time.Now()
+	result, err := p.db.ExecContext(ctx, insertQuery,
+		token.UserID,
+		token.Name,
+		token.TokenHash,
+		token.Prefix,
+		strings.Join(token.Scopes, " "),
+		nullTime(token.ExpiresAt),
+	)
+
+	if err != nil {
+		return err
+	}
+
+	newId, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	token.ID = int(newId)
+
+	return nil
+}
+
+func (p *personalAccessTokenRepository) GetActiveByUserId(ctx context.Context, userID int) ([]*model.PersonalAccessToken, error) {
+	query := "SELECT " + personalAccessTokenColumns + ` FROM personal_access_tokens
+WHERE user_id = ? AND revokedAt IS NULL AND (expiresAt IS NULL OR expiresAt > ?) ORDER BY createdAt DESC, id DESC`
+
+	rows, err := p.db.QueryContext(ctx, query, userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []*model.PersonalAccessToken{}
+	for rows.Next() {
+		token, err := scanPersonalAccessToken(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		tokens = append(tokens, token)
+	}
+
+	return tokens, rows.Err()
+}
+
+func (p *personalAccessTokenRepository) GetById(ctx context.Context, id int) (*model.PersonalAccessToken, error) {
+	query := "SELECT " + personalAccessTokenColumns + " FROM personal_access_tokens WHERE id = ?"
+
+	return scanPersonalAccessToken(p.db.QueryRowContext(ctx, query, id))
+}
+
+// GetByHash finds a token by its hash. Tokens of disabled users are not found.
+func (p *personalAccessTokenRepository) GetByHash(ctx context.Context, hash string) (*model.PersonalAccessToken, error) {
+	query := "SELECT " + personalAccessTokenColumns + ` FROM personal_access_tokens
+WHERE token_hash = ? AND EXISTS (SELECT 1 FROM users WHERE users.id = personal_access_tokens.user_id AND users.disabledAt IS NULL)`
+
+	return scanPersonalAccessToken(p.db.QueryRowContext(ctx, query, hash))
+}
+
+func (p *personalAccessTokenRepository) TouchById(ctx context.Context, id int, usedAt time.Time) error {
+	query := `UPDATE personal_access_tokens SET lastUsedAt = ? WHERE id = ?`
+	_, err := p.db.ExecContext(ctx, query, usedAt, id)
+
+	return err
+}
+
+func (p *personalAccessTokenRepository) RevokeById(ctx context.Context, id int) error {
+	query := `UPDATE personal_access_tokens SET revokedAt = ? WHERE id = ? AND revokedAt IS NULL`
+	_, err := p.db.ExecContext(ctx, query, time.Now(), id)
+
+	return err
+}
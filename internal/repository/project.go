@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/King0625/golang-todolist/internal/model"
+)
+
+type ProjectRepository interface {
+	Create(ctx context.Context, project *model.Project) error
+	GetAll(ctx context.Context, tenant model.Tenant, includeArchived bool) ([]*model.Project, error)
+	GetById(ctx context.Context, id int) (*model.Project, error)
+	UpdateById(ctx context.Context, id int, project *model.Project) error
+	DeleteById(ctx context.Context, id int, mode model.ProjectDeleteMode) error
+}
+
+const projectColumns = "id, user_id, name, color, archived, position, createdAt, updatedAt, workspace_id"
+
+func scanProject(s rowScanner) (*model.Project, error) {
+	var project model.Project
+	var workspaceID sql.NullInt64
+
+	err := s.Scan(
+		&project.ID,
+		&project.UserID,
+		&project.Name,
+		&project.Color,
+		&project.Archived,
+		&project.Position,
+		&project.CreatedAt,
+		&project.UpdatedAt,
+		&workspaceID,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	project.WorkspaceID = intPointer(workspaceID)
+
+	return &project, nil
+}
+
+type projectRepository struct {
+	db *sql.DB
+}
+
+func NewProjectRepository(db *sql.DB) ProjectRepository {
+	return &projectRepository{db: db}
+}
+
+func (p *projectRepository) Create(ctx context.Context, project *model.Project) error {
+	insertProjectQuery := `INSERT INTO projects (user_id, workspace_id, name, color, archived, position) VALUES(?,?,?,?,?,?)`
+
+	result, err := p.db.ExecContext(ctx, insertProjectQuery,
+		project.UserID,
+		nullInt(project.WorkspaceID),
+		project.Name,
+		project.Color,
+		project.Archived,
+		project.Position,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	newId, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	project.ID = int(newId)
+
+	return nil
+}
+
+func (p *projectRepository) GetAll(ctx context.Context, tenant model.Tenant, includeArchived bool) ([]*model.Project, error) {
+	condition, args := tenantCondition(tenant)
+	query := "SELECT " + projectColumns + " FROM projects WHERE " + condition
+	if !includeArchived {
+		query += " AND archived = 0"
+	}
+	query += " ORDER BY archived, position, id"
+
+	rows, err := p.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	projects := []*model.Project{}
+	for rows.Next() {
+		project, err := scanProject(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		projects = append(projects, project)
+	}
+
+	return projects, rows.Err()
+}
+
+func (p *projectRepository) GetById(ctx context.Context, id int) (*model.Project, error) {
+	query := "SELECT " + projectColumns + " FROM projects WHERE id = ?"
+
+	return scanProject(p.db.QueryRowContext(ctx, query, id))
+}
+
+func (p *projectRepository) UpdateById(ctx context.Context, id int, project *model.Project) error {
+	query := "UPDATE projects SET name = ?, color = ?, archived = ?, position = ?, updatedAt = ? WHERE id = ?"
+	_, err := p.db.ExecContext(ctx, query,
+		project.Name,
+		project.Color,
+		project.Archived,
+		project.Position,
+		time.Now(),
+		id,
+	)
+
+	return err
+}
+
+// DeleteById removes the project with its shares and, depending on mode,
+// either moves its todos to the trash or to the inbox, all in one
+// transaction.
+func (p *projectRepository) DeleteById(ctx context.Context, id int, mode model.ProjectDeleteMode) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// cascaded todos go to the trash; restoring them lands them in the inbox
+	now := time.Now()
+	if mode == model.ProjectDeleteCascade {
+		query := `UPDATE todos SET version = version + 1, deletedAt = ? WHERE project_id = ? AND deletedAt IS NULL`
+		if _, err = tx.ExecContext(ctx, query, now, id); err != nil {
+			return err
+		}
+	}
+
+	query := `UPDATE todos SET version = version + 1, project_id = NULL, updatedAt = ? WHERE project_id = ?`
+	if _, err = tx.ExecContext(ctx, query, now, id); err != nil {
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM projects WHERE id = ?`, id); err != nil {
+		return err
+	}
+	if err = deleteShares(ctx, tx, model.ShareProject, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
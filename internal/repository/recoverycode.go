@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+type RecoveryCodeRepository interface {
+	ReplaceByUserId(ctx context.Context, userID int, hashes []string) error
+	UseByHash(ctx context.Context, userID int, hash string) (bool, error)
+	DeleteByUserId(ctx context.Context, userID int) error
+}
+
+type recoveryCodeRepository struct {
+	db *sql.DB
+}
+
+func NewRecoveryCodeRepository(db *sql.DB) RecoveryCodeRepository {
+	return &recoveryCodeRepository{db: db}
+}
+
+// ReplaceByUserId swaps every recovery code of the user for hashes in one
+// transaction.
+func (r *recoveryCodeRepository) ReplaceByUserId(ctx context.Context, userID int, hashes []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+
+	if len(hashes) > 0 {
+		values := make([]string, 0, len(hashes))
+		args := make([]any, 0, 2*len(hashes))
+		for _, hash := range hashes {
+			values = append(values, "(?,?)")
+			args = append(args, userID, hash)
+		}
+
+		query := "INSERT INTO recovery_codes (user_id, code_hash) VALUES " + strings.Join(values, ",")
+
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// UseByHash consumes a recovery code. It reports false when the user has no
+// unused code with that hash.
+func (r *recoveryCodeRepository) UseByHash(ctx context.Context, userID int, hash string) (bool, error) {
+	query := `UPDATE recovery_codes SET usedAt = ? WHERE user_id = ? AND code_hash = ? AND usedAt IS NULL`
+	result, err := r.db.ExecContext(ctx, query, time.Now(), userID, hash)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
+func (r *recoveryCodeRepository) DeleteByUserId(ctx context.Context, userID int) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = ?`, userID)
+
+	return err
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/King0625/golang-todolist/internal/model"
+)
+
+// TodoRevisionRepository reads the revisions todoRepository saves as todos
+// are created and edited.
+type TodoRevisionRepository interface {
+	GetAllByTodoId(ctx context.Context, todoID int) ([]*model.TodoRevision, error)
+	GetByRevision(ctx context.Context, todoID, revision int) (*model.TodoRevision, error)
+	GetLatest(ctx context.Context, todoID int) (*model.TodoRevision, error)
+	PurgeBefore(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+const revisionColumns = "id, todo_id, revision, user_id, title, content, createdAt"
+
+func scanRevision(s rowScanner) (*model.TodoRevision, error) {
+	var revision model.TodoRevision
+	var userID sql.NullInt64
+
+	err := s.Scan(
+		&revision.ID,
+		&revision.TodoID,
+		&revision.Revision,
+		&userID,
+		&revision.Title,
+		&revision.Content,
+		&revision.CreatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	revision.UserID = intPointer(userID)
+
+	return &revision, nil
+}
+
+// insertRevision saves title and content as the next revision of todoID.
+// Callers hold a lock on the todo, so the numbers cannot collide.
+func insertRevision(ctx context.Context, db execer, todoID int, userID *int, title, content string) error {
+	query := `INSERT INTO todo_revisions (todo_id, revision, user_id, title, content, createdAt)
+SELECT ?, COALESCE(MAX(revision), 0) + 1, ?, ?, ?, ? FROM todo_revisions WHERE todo_id = ?`
+	_, err := db.ExecContext(ctx, query, todoID, nullInt(userID), title, content, time.Now(), todoID)
+
+	return err
+}
+
+type todoRevisionRepository struct {
+	db *sql.DB
+}
+
+func NewTodoRevisionRepository(db *sql.DB) TodoRevisionRepository {
+	return &todoRevisionRepository{db: db}
+}
+
+func (t *todoRevisionRepository) GetAllByTodoId(ctx context.Context, todoID int) ([]*model.TodoRevision, error) {
+	query := "SELECT " + revisionColumns + " FROM todo_revisions WHERE todo_id = ? ORDER BY revision DESC"
+
+	rows, err := t.db.QueryContext(ctx, query, todoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []*model.TodoRevision{}
+	for rows.Next() {
+		revision, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		revisions = append(revisions, revision)
+	}
+
+	return revisions, rows.Err()
+}
+
+func (t *todoRevisionRepository) GetByRevision(ctx context.Context, todoID, revision int) (*model.TodoRevision, error) {
+	query := "SELECT " + revisionColumns + " FROM todo_revisions WHERE todo_id = ? AND revision = ?"
+
+	return scanRevision(t.db.QueryRowContext(ctx, query, todoID, revision))
+}
+
+func (t *todoRevisionRepository) GetLatest(ctx context.Context, todoID int) (*model.TodoRevision, error) {
+	query := "SELECT " + revisionColumns + " FROM todo_revisions WHERE todo_id = ? ORDER BY revision DESC LIMIT 1"
+
+	return scanRevision(t.db.QueryRowContext(ctx, query, todoID))
+}
+
+// PurgeBefore deletes revisions saved before cutoff, except the latest of
+// each todo, which is what the todo is now.
+func (t *todoRevisionRepository) PurgeBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	query := `DELETE r FROM todo_revisions r
+JOIN (SELECT todo_id, MAX(revision) AS latest FROM todo_revisions GROUP BY todo_id) l ON l.todo_id = r.todo_id
+WHERE r.createdAt < ? AND r.revision < l.latest`
+	result, err := t.db.ExecContext(ctx, query, cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
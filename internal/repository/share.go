@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/King0625/golang-todolist/internal/model"
+)
+
+type ShareRepository interface {
+	Upsert(ctx context.Context, share *model.Share) error
+	GetById(ctx context.Context, id int) (*model.Share, error)
+	GetAllByResource(ctx context.Context, resourceType model.ShareResource, resourceID int) ([]*model.Share, error)
+	GetPendingByEmail(ctx context.Context, email string) ([]*model.Share, error)
+	RespondById(ctx context.Context, id int, userID int, status model.ShareStatus) (bool, error)
+	DeleteById(ctx context.Context, id int) error
+	GetPermission(ctx context.Context, userID int, resourceType model.ShareResource, resourceID int) (model.SharePermission, error)
+}
+
+const shareColumns = "id, resource_type, resource_id, owner_id, email, user_id, permission, status, createdAt, respondedAt"
+
+func scanShare(s rowScanner) (*model.Share, error) {
+	var share model.Share
+	var userID sql.NullInt64
+	var respondedAt sql.NullTime
+
+	err := s.Scan(
+		&share.ID,
+		&share.ResourceType,
+		&share.ResourceID,
+		&share.OwnerID,
+		&share.Email,
+		&userID,
+		&share.Permission,
+		&share.Status,
+		&share.CreatedAt,
+		&respondedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if userID.Valid {
+		id := int(userID.Int64)
+		share.UserID = &id
+	}
+	if respondedAt.Valid {
+		share.RespondedAt = &respondedAt.Time
+	}
+
+	return &share, nil
+}
+
+type shareRepository struct {
+	db *sql.DB
+}
+
+func NewShareRepository(db *sql.DB) ShareRepository {
+	return &shareRepository{db: db}
+}
+
+// Upsert invites share.Email to the resource, or changes the permission of an
+// existing invitation. Declined invitations become pending again; accepted
+// ones stay accepted. share is reloaded with the stored row.
+func (s *shareRepository) Upsert(ctx context.Context, share *model.Share) error {
+	query := `INSERT INTO shares (resource_type, resource_id, owner_id, email, permission, status, createdAt)
+VALUES(?,?,?,?,?,?,?)
+ON DUPLICATE KEY UPDATE
+	id = LAST_INSERT_ID(id),
+	permission = ?,
+	user_id = IF(status = ?, NULL, user_id),
+	respondedAt = IF(status = ?, NULL, respondedAt),
+	status = IF(status = ?, ?, status)`
+
+	result, err := s.db.ExecContext(ctx, query,
+		share.ResourceType,
+		share.ResourceID,
+		share.OwnerID,
+		share.Email,
+		share.Permission,
+		model.SharePending,
+		time.Now(),
+		share.Permission,
+		model.ShareDeclined,
+		model.ShareDeclined,
+		model.ShareDeclined, model.SharePending,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	stored, err := s.GetById(ctx, int(id))
+	if err != nil {
+		return err
+	}
+
+	*share = *stored
+	return nil
+}
+
+func (s *shareRepository) GetById(ctx context.Context, id int) (*model.Share, error) {
+	query := "SELECT " + shareColumns + " FROM shares WHERE id = ?"
+
+	return scanShare(s.db.QueryRowContext(ctx, query, id))
+}
+
+func (s *shareRepository) queryShares(ctx context.Context, query string, args ...any) ([]*model.Share, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shares := []*model.Share{}
+	for rows.Next() {
+		share, err := scanShare(rows)
+		if err != nil {
+			return nil, err
+		}
+		shares = append(shares, share)
+	}
+
+	return shares, rows.Err()
+}
+
+func (s *shareRepository) GetAllByResource(ctx context.Context, resourceType model.ShareResource, resourceID int) ([]*model.Share, error) {
+	query := "SELECT " + shareColumns + " FROM shares WHERE resource_type = ? AND resource_id = ? ORDER BY id"
+
+	return s.queryShares(ctx, query, resourceType, resourceID)
+}
+
+func (s *shareRepository) GetPendingByEmail(ctx context.Context, email string) ([]*model.Share, error) {
+	query := "SELECT " + shareColumns + " FROM shares WHERE email = ? AND status = ? ORDER BY id"
+
+	return s.queryShares(ctx, query, email, model.SharePending)
+}
+
+// RespondById accepts or declines a pending invitation on behalf of userID.
+// It reports false when the invitation was no longer pending.
+func (s *shareRepository) RespondById(ctx context.Context, id int, userID int, status model.ShareStatus) (bool, error) {
+	var acceptedBy *int
+	if status == model.ShareAccepted {
+		acceptedBy = &userID
+	}
+
+	query := `UPDATE shares SET status = ?, user_id = ?, respondedAt = ? WHERE id = ? AND status = ?`
+	result, err := s.db.ExecContext(ctx, query, status, acceptedBy, time.Now(), id, model.SharePending)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
+func (s *shareRepository) DeleteById(ctx context.Context, id int) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM shares WHERE id = ?`, id)
+
+	return err
+}
+
+// GetPermission returns what userID was granted on the resource by accepted
+// invitations, or PermissionNone.
+func (s *shareRepository) GetPermission(ctx context.Context, userID int, resourceType model.ShareResource, resourceID int) (model.SharePermission, error) {
+	query := `SELECT permission FROM shares
+WHERE user_id = ? AND resource_type = ? AND resource_id = ? AND status = ?
+ORDER BY permission = ? DESC LIMIT 1`
+
+	var permission model.SharePermission
+	err := s.db.QueryRowContext(ctx, query, userID, resourceType, resourceID, model.ShareAccepted, model.PermissionEditor).Scan(&permission)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.PermissionNone, nil
+	}
+
+	return permission, err
+}
+
+// deleteShares removes the invitations to a todo or project that is deleted;
+// shares have no foreign key to what they share.
+func deleteShares(ctx context.Context, db execer, resource model.ShareResource, id int) error {
+	query := `DELETE FROM shares WHERE resource_type = ? AND resource_id = ?`
+	_, err := db.ExecContext(ctx, query, resource, id)
+
+	return err
+}
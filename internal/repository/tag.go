@@ -0,0 +1,260 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/King0625/golang-todolist/internal/model"
+	"github.com/go-sql-driver/mysql"
+)
+
+var ErrDuplicateTag = errors.New("tag already exists")
+
+const mysqlDuplicateEntry = 1062
+
+func isDuplicateEntry(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry
+}
+
+// placeholders returns "?,?,?" for n arguments.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
+
+type TagRepository interface {
+	Create(ctx context.Context, tag *model.Tag) error
+	GetAll(ctx context.Context, tenant model.Tenant) ([]*model.Tag, error)
+	GetById(ctx context.Context, id int) (*model.Tag, error)
+	UpdateById(ctx context.Context, id int, name, color string) error
+	DeleteById(ctx context.Context, id int) error
+	AttachToTodo(ctx context.Context, todoID int, tagIDs []int) error
+	DetachFromTodo(ctx context.Context, todoID, tagID int) error
+}
+
+const tagColumns = "id, user_id, name, color, createdAt, updatedAt, workspace_id"
+
+func scanTag(s rowScanner) (*model.Tag, error) {
+	var tag model.Tag
+	var workspaceID sql.NullInt64
+
+	err := s.Scan(
+		&tag.ID,
+		&tag.UserID,
+		&tag.Name,
+		&tag.Color,
+		&tag.CreatedAt,
+		&tag.UpdatedAt,
+		&workspaceID,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	tag.WorkspaceID = intPointer(workspaceID)
+
+	return &tag, nil
+}
+
+type tagRepository struct {
+	db *sql.DB
+}
+
+func NewTagRepository(db *sql.DB) TagRepository {
+	return &tagRepository{db: db}
+}
+
+func (t *tagRepository) Create(ctx context.Context, tag *model.Tag) error {
+	insertTagQuery := `INSERT INTO tags (user_id, workspace_id, name, color) VALUES(?,?,?,?)`
+
+	result, err := t.db.ExecContext(ctx, insertTagQuery, tag.UserID, nullInt(tag.WorkspaceID), tag.Name, tag.Color)
+	if isDuplicateEntry(err) {
+		return ErrDuplicateTag
+	}
+	if err != nil {
+		return err
+	}
+
+	newId, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	tag.ID = int(newId)
+
+	return nil
+}
+
+func (t *tagRepository) GetAll(ctx context.Context, tenant model.Tenant) ([]*model.Tag, error) {
+	condition, args := tenantCondition(tenant)
+	query := "SELECT " + tagColumns + " FROM tags WHERE " + condition + " ORDER BY name"
+	rows, err := t.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []*model.Tag{}
+	for rows.Next() {
+		tag, err := scanTag(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		tags = append(tags, tag)
+	}
+
+	return tags, rows.Err()
+}
+
+func (t *tagRepository) GetById(ctx context.Context, id int) (*model.Tag, error) {
+	query := "SELECT " + tagColumns + " FROM tags WHERE id = ?"
+
+	return scanTag(t.db.QueryRowContext(ctx, query, id))
+}
+
+// touchTaggedTodos bumps the version of the todos tagged with tagID, whose
+// representation includes the tag.
+func touchTaggedTodos(ctx context.Context, db execer, tagID int) error {
+	query := `UPDATE todos JOIN todo_tags tt ON tt.todo_id = todos.id SET todos.version = todos.version + 1 WHERE tt.tag_id = ?`
+	_, err := db.ExecContext(ctx, query, tagID)
+
+	return err
+}
+
+// UpdateById renames or recolors the tag and bumps the version of the todos
+// tagged with it in one transaction.
+func (t *tagRepository) UpdateById(ctx context.Context, id int, name, color string) error {
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := "UPDATE tags SET name = ?, color = ?, updatedAt = ? WHERE id = ?"
+	_, err = tx.ExecContext(ctx, query, name, color, time.Now(), id)
+	if isDuplicateEntry(err) {
+		return ErrDuplicateTag
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := touchTaggedTodos(ctx, tx, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteById removes the tag and bumps the version of the todos tagged with
+// it in one transaction.
+func (t *tagRepository) DeleteById(ctx context.Context, id int) error {
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := touchTaggedTodos(ctx, tx, id); err != nil {
+		return err
+	}
+
+	query := `DELETE FROM tags WHERE id = ?`
+	if _, err := tx.ExecContext(ctx, query, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func attachTags(ctx context.Context, db execer, todoID int, tagIDs []int) error {
+	values := make([]string, 0, len(tagIDs))
+	args := make([]any, 0, 2*len(tagIDs))
+	for _, tagID := range tagIDs {
+		values = append(values, "(?,?)")
+		args = append(args, todoID, tagID)
+	}
+
+	query := "INSERT IGNORE INTO todo_tags (todo_id, tag_id) VALUES " + strings.Join(values, ",")
+	_, err := db.ExecContext(ctx, query, args...)
+
+	return err
+}
+
+func (t *tagRepository) AttachToTodo(ctx context.Context, todoID int, tagIDs []int) error {
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := attachTags(ctx, tx, todoID, tagIDs); err != nil {
+		return err
+	}
+	if err := touchTodo(ctx, tx, todoID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (t *tagRepository) DetachFromTodo(ctx context.Context, todoID, tagID int) error {
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `DELETE FROM todo_tags WHERE todo_id = ? AND tag_id = ?`
+	if _, err := tx.ExecContext(ctx, query, todoID, tagID); err != nil {
+		return err
+	}
+	if err := touchTodo(ctx, tx, todoID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// tagsByTodoIds loads the tags of the given todos, keyed by todo id.
+func tagsByTodoIds(ctx context.Context, db *sql.DB, todoIDs []int) (map[int][]*model.Tag, error) {
+	result := make(map[int][]*model.Tag)
+	if len(todoIDs) == 0 {
+		return result, nil
+	}
+
+	args := make([]any, len(todoIDs))
+	for i, id := range todoIDs {
+		args[i] = id
+	}
+
+	query := `SELECT tt.todo_id, tg.id, tg.user_id, tg.name, tg.color, tg.createdAt, tg.updatedAt, tg.workspace_id
+FROM todo_tags tt JOIN tags tg ON tg.id = tt.tag_id
+WHERE tt.todo_id IN (` + placeholders(len(todoIDs)) + `) ORDER BY tg.name`
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var todoID int
+		var tag model.Tag
+		var workspaceID sql.NullInt64
+		err := rows.Scan(&todoID, &tag.ID, &tag.UserID, &tag.Name, &tag.Color, &tag.CreatedAt, &tag.UpdatedAt, &workspaceID)
+		if err != nil {
+			return nil, err
+		}
+		tag.WorkspaceID = intPointer(workspaceID)
+
+		result[todoID] = append(result[todoID], &tag)
+	}
+
+	return result, rows.Err()
+}
@@ -3,18 +3,200 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/King0625/golang-todolist/internal/model"
+	"github.com/King0625/golang-todolist/pkg/search"
 )
 
+// ErrVersionConflict is returned when a todo was changed by someone else
+// since it was read.
+var ErrVersionConflict = errors.New("todo was changed in the meantime")
+
+// ErrOpenChecklistItems is returned when the DonePolicy refuses to complete
+// a todo with open checklist items.
+var ErrOpenChecklistItems = errors.New("todo has open checklist items")
+
 type TodoRepository interface {
 	Create(ctx context.Context, todo *model.Todo) error
 	GetAllByUserId(ctx context.Context, userID int) ([]*model.Todo, error)
+	List(ctx context.Context, tenant model.Tenant, opts model.TodoQueryOptions) (*model.TodoPage, error)
+	Search(ctx context.Context, tenant model.Tenant, query search.Query, limit int) ([]*model.TodoSearchResult, error)
 	GetById(ctx context.Context, id int) (*model.Todo, error)
-	UpdateById(ctx context.Context, id int, title, content string, done bool) error
-	MarkDoneById(ctx context.Context, id int) error
-	DeleteById(ctx context.Context, id int) error
+	ListOverdue(ctx context.Context, tenant model.Tenant, now time.Time) ([]*model.Todo, error)
+	ListDueBetween(ctx context.Context, tenant model.Tenant, from, to time.Time) ([]*model.Todo, error)
+	UpdateById(ctx context.Context, id int, todo *model.Todo, editorID *int, policy model.DonePolicy, next *model.Todo) error
+	MoveToProjectById(ctx context.Context, id, version int, projectID *int) error
+	MarkDoneById(ctx context.Context, id, version int, policy model.DonePolicy, next *model.Todo) error
+	DeleteById(ctx context.Context, id, version int) error
+	GetTrash(ctx context.Context, tenant model.Tenant) ([]*model.Todo, error)
+	GetTrashedById(ctx context.Context, id int) (*model.Todo, error)
+	RestoreById(ctx context.Context, id, version int) error
+	PurgeById(ctx context.Context, id, version int) error
+	PurgeDeletedBefore(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+const todoColumns = "id, user_id, title, content, createdAt, updatedAt, done, due_date, due_time, due_timezone, due_at, priority, project_id, recurrence_rule, recurrence_start, deletedAt, workspace_id, assignee_id, version"
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanTodo(s rowScanner) (*model.Todo, error) {
+	var todo model.Todo
+	var dueDate, dueAt, recurrenceStart, deletedAt sql.NullTime
+	var dueTime, dueTimezone, recurrenceRule sql.NullString
+	var projectID, workspaceID, assigneeID sql.NullInt64
+	var version int
+
+	err := s.Scan(
+		&todo.ID,
+		&todo.UserID,
+		&todo.Title,
+		&todo.Content,
+		&todo.CreatedAt,
+		&todo.UpdatedAt,
+		&todo.Done,
+		&dueDate,
+		&dueTime,
+		&dueTimezone,
+		&dueAt,
+		&todo.Priority,
+		&projectID,
+		&recurrenceRule,
+		&recurrenceStart,
+		&deletedAt,
+		&workspaceID,
+		&assigneeID,
+		&version,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if dueDate.Valid {
+		todo.DueDate = dueDate.Time.Format(model.DueDateLayout)
+	}
+	if dueTime.Valid && len(dueTime.String) >= len(model.DueTimeLayout) {
+		// MySQL returns TIME as HH:MM:SS
+		todo.DueTime = dueTime.String[:len(model.DueTimeLayout)]
+	}
+	todo.Timezone = dueTimezone.String
+	if dueAt.Valid {
+		todo.DueAt = &dueAt.Time
+	}
+	todo.ProjectID = intPointer(projectID)
+	todo.WorkspaceID = intPointer(workspaceID)
+	todo.AssigneeID = intPointer(assigneeID)
+	todo.Recurrence = recurrenceRule.String
+	if recurrenceStart.Valid {
+		todo.RecurrenceStart = &recurrenceStart.Time
+	}
+	if deletedAt.Valid {
+		todo.DeletedAt = &deletedAt.Time
+	}
+	todo.SetVersion(version)
+
+	return &todo, nil
+}
+
+// touchTodo bumps the version of a todo whose checklist or tags changed.
+func touchTodo(ctx context.Context, db execer, todoID int) error {
+	_, err := db.ExecContext(ctx, `UPDATE todos SET version = version + 1 WHERE id = ?`, todoID)
+	return err
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func nullInt(i *int) sql.NullInt64 {
+	if i == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: int64(*i), Valid: true}
+}
+
+func intPointer(i sql.NullInt64) *int {
+	if !i.Valid {
+		return nil
+	}
+	v := int(i.Int64)
+	return &v
+}
+
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *t, Valid: true}
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func (t *todoRepository) queryTodos(ctx context.Context, query string, args ...any) ([]*model.Todo, error) {
+	rows, err := t.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	todos := []*model.Todo{}
+	for rows.Next() {
+		todo, err := scanTodo(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		todos = append(todos, todo)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := t.loadRelations(ctx, todos...); err != nil {
+		return nil, err
+	}
+
+	return todos, nil
+}
+
+// loadRelations embeds the tags and checklist progress of every given todo.
+func (t *todoRepository) loadRelations(ctx context.Context, todos ...*model.Todo) error {
+	ids := make([]int, len(todos))
+	for i, todo := range todos {
+		ids[i] = todo.ID
+	}
+
+	tags, err := tagsByTodoIds(ctx, t.db, ids)
+	if err != nil {
+		return err
+	}
+
+	progress, err := progressByTodoIds(ctx, t.db, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, todo := range todos {
+		todo.Tags = tags[todo.ID]
+		if todo.Tags == nil {
+			todo.Tags = []*model.Tag{}
+		}
+		todo.Progress = progress[todo.ID]
+	}
+
+	return nil
 }
 
 type todoRepository struct {
@@ -25,13 +207,25 @@ func NewTodoRepository(db *sql.DB) TodoRepository {
 	return &todoRepository{db: db}
 }
 
-func (t *todoRepository) Create(ctx context.Context, todo *model.Todo) error {
-	insertTodoQuery := `INSERT INTO todos (user_id, title, content) VALUES(?,?,?)`
+// insertTodo inserts todo with its first revision and its tags.
+func insertTodo(ctx context.Context, db execer, todo *model.Todo) error {
+	insertTodoQuery := `INSERT INTO todos (user_id, workspace_id, assignee_id, title, content, due_date, due_time, due_timezone, due_at,
+priority, project_id, recurrence_rule, recurrence_start) VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?)`
 
-	result, err := t.db.ExecContext(ctx, insertTodoQuery,
+	result, err := db.ExecContext(ctx, insertTodoQuery,
 		todo.UserID,
+		nullInt(todo.WorkspaceID),
+		nullInt(todo.AssigneeID),
 		todo.Title,
 		todo.Content,
+		nullString(todo.DueDate),
+		nullString(todo.DueTime),
+		nullString(todo.Timezone),
+		nullTime(todo.DueAt),
+		todo.Priority,
+		nullInt(todo.ProjectID),
+		nullString(todo.Recurrence),
+		nullTime(todo.RecurrenceStart),
 	)
 
 	if err != nil {
@@ -44,100 +238,501 @@ func (t *todoRepository) Create(ctx context.Context, todo *model.Todo) error {
 	}
 
 	todo.ID = int(newId)
+	todo.SetVersion(1)
+
+	if err := insertRevision(ctx, db, todo.ID, &todo.UserID, todo.Title, todo.Content); err != nil {
+		return err
+	}
+
+	if len(todo.Tags) > 0 {
+		tagIDs := make([]int, len(todo.Tags))
+		for i, tag := range todo.Tags {
+			tagIDs[i] = tag.ID
+		}
+		if err := attachTags(ctx, db, todo.ID, tagIDs); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
 
+func (t *todoRepository) Create(ctx context.Context, todo *model.Todo) error {
+	return insertTodo(ctx, t.db, todo)
+}
+
 func (t *todoRepository) GetAllByUserId(ctx context.Context, userID int) ([]*model.Todo, error) {
-	query := "SELECT * FROM todos WHERE user_id = ?"
-	rows, err := t.db.QueryContext(ctx, query, userID)
+	query := "SELECT " + todoColumns + " FROM todos WHERE user_id = ? AND deletedAt IS NULL"
+
+	return t.queryTodos(ctx, query, userID)
+}
+
+// tenantCondition restricts a query on todos or projects to those of tenant.
+func tenantCondition(tenant model.Tenant) (string, []any) {
+	if tenant.WorkspaceID != 0 {
+		return "workspace_id = ?", []any{tenant.WorkspaceID}
+	}
+	return "user_id = ? AND workspace_id IS NULL", []any{tenant.UserID}
+}
+
+func (t *todoRepository) ListOverdue(ctx context.Context, tenant model.Tenant, now time.Time) ([]*model.Todo, error) {
+	condition, args := tenantCondition(tenant)
+	query := "SELECT " + todoColumns + " FROM todos WHERE " + condition + " AND deletedAt IS NULL AND done = 0 AND due_at <= ? ORDER BY due_at, id"
+
+	return t.queryTodos(ctx, query, append(args, now)...)
+}
+
+func (t *todoRepository) ListDueBetween(ctx context.Context, tenant model.Tenant, from, to time.Time) ([]*model.Todo, error) {
+	condition, args := tenantCondition(tenant)
+	query := "SELECT " + todoColumns + " FROM todos WHERE " + condition + " AND deletedAt IS NULL AND done = 0 AND due_at > ? AND due_at <= ? ORDER BY due_at, id"
+
+	return t.queryTodos(ctx, query, append(args, from, to)...)
+}
+
+var todoSortColumns = map[model.TodoSortField]string{
+	model.TodoSortCreatedAt: "createdAt",
+	model.TodoSortUpdatedAt: "updatedAt",
+	model.TodoSortTitle:     "title",
+}
+
+func todoSortValue(todo *model.Todo, field model.TodoSortField) string {
+	switch field {
+	case model.TodoSortUpdatedAt:
+		return todo.UpdatedAt.UTC().Format(time.RFC3339)
+	case model.TodoSortTitle:
+		return todo.Title
+	default:
+		return todo.CreatedAt.UTC().Format(time.RFC3339)
+	}
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func (t *todoRepository) List(ctx context.Context, tenant model.Tenant, opts model.TodoQueryOptions) (*model.TodoPage, error) {
+	sortColumn, ok := todoSortColumns[opts.SortBy]
+	if !ok {
+		opts.SortBy = model.TodoSortCreatedAt
+		sortColumn = todoSortColumns[opts.SortBy]
+	}
+
+	condition, args := tenantCondition(tenant)
+	switch {
+	case opts.Shared:
+		condition = `(id IN (SELECT resource_id FROM shares WHERE resource_type = ? AND user_id = ? AND status = ?)
+OR project_id IN (SELECT resource_id FROM shares WHERE resource_type = ? AND user_id = ? AND status = ?))`
+		args = []any{
+			model.ShareTodo, tenant.UserID, model.ShareAccepted,
+			model.ShareProject, tenant.UserID, model.ShareAccepted,
+		}
+	case opts.Assigned:
+		// assignments outlive neither the membership nor the workspace
+		condition = "assignee_id = ? AND workspace_id IN (SELECT workspace_id FROM workspace_members WHERE user_id = ?)"
+		args = []any{tenant.UserID, tenant.UserID}
+	}
+	conditions := []string{condition, "deletedAt IS NULL"}
+
+	if opts.Done != nil {
+		conditions = append(conditions, "done = ?")
+		args = append(args, *opts.Done)
+	}
+	if opts.ProjectID != nil {
+		conditions = append(conditions, "project_id = ?")
+		args = append(args, *opts.ProjectID)
+	}
+	if opts.AssigneeID != nil {
+		conditions = append(conditions, "assignee_id = ?")
+		args = append(args, *opts.AssigneeID)
+	}
+	if opts.Title != "" {
+		conditions = append(conditions, "title LIKE ?")
+		args = append(args, "%"+escapeLike(opts.Title)+"%")
+	}
+	if opts.CreatedFrom != nil {
+		conditions = append(conditions, "createdAt >= ?")
+		args = append(args, *opts.CreatedFrom)
+	}
+	if opts.CreatedTo != nil {
+		conditions = append(conditions, "createdAt <= ?")
+		args = append(args, *opts.CreatedTo)
+	}
+	if opts.UpdatedFrom != nil {
+		conditions = append(conditions, "updatedAt >= ?")
+		args = append(args, *opts.UpdatedFrom)
+	}
+	if opts.UpdatedTo != nil {
+		conditions = append(conditions, "updatedAt <= ?")
+		args = append(args, *opts.UpdatedTo)
+	}
+
+	if len(opts.Tags) > 0 {
+		// tags are filtered by name within the tenant of each todo
+		tagQuery := `id IN (SELECT tt.todo_id FROM todo_tags tt JOIN tags tg ON tg.id = tt.tag_id
+WHERE tg.workspace_id <=> todos.workspace_id AND (todos.workspace_id IS NOT NULL OR tg.user_id = todos.user_id)
+AND tg.name IN (` + placeholders(len(opts.Tags)) + `)`
+		for _, name := range opts.Tags {
+			args = append(args, name)
+		}
+		if opts.TagMatchAll {
+			tagQuery += " GROUP BY tt.todo_id HAVING COUNT(DISTINCT tg.id) = ?"
+			args = append(args, len(opts.Tags))
+		}
+		conditions = append(conditions, tagQuery+")")
+	}
+
+	direction, comparator := "ASC", ">"
+	if opts.SortDesc {
+		direction, comparator = "DESC", "<"
+	}
 
+	if opts.Cursor != nil {
+		var value any = opts.Cursor.Value
+		if opts.SortBy != model.TodoSortTitle {
+			parsed, err := time.Parse(time.RFC3339, opts.Cursor.Value)
+			if err != nil {
+				return nil, model.ErrInvalidCursor
+			}
+			value = parsed
+		}
+		conditions = append(conditions, fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))", sortColumn, comparator))
+		args = append(args, value, value, opts.Cursor.ID)
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM todos WHERE %s ORDER BY %s %s, id %s LIMIT ?",
+		todoColumns, strings.Join(conditions, " AND "), sortColumn, direction, direction)
+	// fetch one extra row to find out whether another page exists
+	args = append(args, opts.Limit+1)
+
+	todos, err := t.queryTodos(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 
-	var todos []*model.Todo
+	page := &model.TodoPage{Todos: todos}
+	if len(todos) > opts.Limit {
+		page.Todos = todos[:opts.Limit]
+		page.HasMore = true
+		last := page.Todos[len(page.Todos)-1]
+		page.NextCursor = model.TodoCursor{Value: todoSortValue(last, opts.SortBy), ID: last.ID}.Encode()
+	}
 
-	for rows.Next() {
-		var todo model.Todo
-
-		err = rows.Scan(
-			&todo.ID,
-			&todo.UserID,
-			&todo.Title,
-			&todo.Content,
-			&todo.CreatedAt,
-			&todo.UpdatedAt,
-			&todo.Done,
-		)
+	return page, nil
+}
 
+// Search ranks the todos of the tenant by full-text relevance. Snippets are
+// left for the caller to fill.
+func (t *todoRepository) Search(ctx context.Context, tenant model.Tenant, query search.Query, limit int) ([]*model.TodoSearchResult, error) {
+	condition, tenantArgs := tenantCondition(tenant)
+	searchQuery := "SELECT " + todoColumns + `, MATCH(title, content) AGAINST (? IN BOOLEAN MODE) AS score
+FROM todos WHERE ` + condition + ` AND deletedAt IS NULL AND MATCH(title, content) AGAINST (? IN BOOLEAN MODE)
+ORDER BY score DESC, id DESC LIMIT ?`
+	against := query.BooleanMode()
+
+	args := append([]any{against}, tenantArgs...)
+	rows, err := t.db.QueryContext(ctx, searchQuery, append(args, against, limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []*model.TodoSearchResult{}
+	todos := []*model.Todo{}
+	for rows.Next() {
+		var score float64
+		todo, err := scanTodo(scoreScanner{rows, &score})
 		if err != nil {
 			return nil, err
 		}
 
-		todos = append(todos, &todo)
+		todos = append(todos, todo)
+		results = append(results, &model.TodoSearchResult{Todo: todo, Score: score})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
-	return todos, nil
+	if err := t.loadRelations(ctx, todos...); err != nil {
+		return nil, err
+	}
+
+	return results, nil
 }
 
-func (t *todoRepository) GetById(ctx context.Context, id int) (*model.Todo, error) {
-	query := `SELECT * FROM todos WHERE id = ?`
-	var todo model.Todo
+// scoreScanner scans one trailing column into score after the todo columns.
+type scoreScanner struct {
+	rows  *sql.Rows
+	score *float64
+}
 
-	err := t.db.QueryRowContext(ctx, query, id).Scan(
-		&todo.ID,
-		&todo.UserID,
-		&todo.Title,
-		&todo.Content,
-		&todo.CreatedAt,
-		&todo.UpdatedAt,
-		&todo.Done,
-	)
+func (s scoreScanner) Scan(dest ...any) error {
+	return s.rows.Scan(append(dest, s.score)...)
+}
+
+func (t *todoRepository) GetById(ctx context.Context, id int) (*model.Todo, error) {
+	query := "SELECT " + todoColumns + " FROM todos WHERE id = ? AND deletedAt IS NULL"
 
+	todo, err := scanTodo(t.db.QueryRowContext(ctx, query, id))
 	if err != nil {
 		return nil, err
 	}
 
-	return &todo, nil
+	if err := t.loadRelations(ctx, todo); err != nil {
+		return nil, err
+	}
+
+	return todo, nil
 }
 
-func (t *todoRepository) UpdateById(ctx context.Context, id int, title, content string, done bool) error {
-	query := "UPDATE todos SET title = ?, content = ?, updatedAt = ?, done = ? WHERE id = ?"
-	result, err := t.db.ExecContext(ctx, query, title, content, time.Now(), done, id)
+// UpdateById saves the fields of todo that differ from the stored todo and,
+// when its title or content changed, a new revision by editorID, in one
+// transaction. An update that completes the todo completes it like
+// MarkDoneById, in the same transaction. It returns ErrVersionConflict when
+// the todo was changed since todo was read, sql.ErrNoRows when it is in the
+// trash, and bumps todo.Version when anything was saved.
+func (t *todoRepository) UpdateById(ctx context.Context, id int, todo *model.Todo, editorID *int, policy model.DonePolicy, next *model.Todo) error {
+	tx, err := t.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
-	_, err = result.RowsAffected()
+	defer tx.Rollback()
+
+	query := "SELECT " + todoColumns + " FROM todos WHERE id = ? AND deletedAt IS NULL FOR UPDATE"
+	current, err := scanTodo(tx.QueryRowContext(ctx, query, id))
 	if err != nil {
 		return err
 	}
+	if current.Version != todo.Version {
+		return ErrVersionConflict
+	}
+
+	var set []string
+	var args []any
+	assign := func(changed bool, columns []string, values ...any) {
+		if changed {
+			for _, column := range columns {
+				set = append(set, column+" = ?")
+			}
+			args = append(args, values...)
+		}
+	}
+
+	assign(current.Title != todo.Title, []string{"title"}, todo.Title)
+	assign(current.Content != todo.Content, []string{"content"}, todo.Content)
+	assign(current.Done != todo.Done, []string{"done"}, todo.Done)
+	assign(current.DueDate != todo.DueDate || current.DueTime != todo.DueTime || current.Timezone != todo.Timezone,
+		[]string{"due_date", "due_time", "due_timezone", "due_at"},
+		nullString(todo.DueDate), nullString(todo.DueTime), nullString(todo.Timezone), nullTime(todo.DueAt))
+	assign(current.Priority != todo.Priority, []string{"priority"}, todo.Priority)
+	assign(nullInt(current.ProjectID) != nullInt(todo.ProjectID), []string{"project_id"}, nullInt(todo.ProjectID))
+	assign(current.Recurrence != todo.Recurrence || !sameTime(current.RecurrenceStart, todo.RecurrenceStart),
+		[]string{"recurrence_rule", "recurrence_start"},
+		nullString(todo.Recurrence), nullTime(todo.RecurrenceStart))
+	assign(nullInt(current.AssigneeID) != nullInt(todo.AssigneeID), []string{"assignee_id"}, nullInt(todo.AssigneeID))
+
+	if len(set) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	query = "UPDATE todos SET version = version + 1, updatedAt = ?, " + strings.Join(set, ", ") + " WHERE id = ?"
+	args = append([]any{now}, append(args, id)...)
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return err
+	}
+
+	if todo.Done && !current.Done {
+		if err := completeTodo(ctx, tx, id, policy, now, next); err != nil {
+			return err
+		}
+	}
+
+	if todo.Title != current.Title || todo.Content != current.Content {
+		if err := insertRevision(ctx, tx, id, editorID, todo.Title, todo.Content); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
 
+	todo.SetVersion(current.Version + 1)
 	return nil
 }
 
-func (t *todoRepository) MarkDoneById(ctx context.Context, id int) error {
-	query := "UPDATE todos SET done = 1, updatedAt = ? WHERE id = ?"
-	result, err := t.db.Exec(query, time.Now(), id)
+// checkVersion returns ErrVersionConflict when a write conditioned on the
+// version of a todo changed no row.
+func checkVersion(result sql.Result) error {
+	affected, err := result.RowsAffected()
 	if err != nil {
 		return err
 	}
-	_, err = result.RowsAffected()
+	if affected != 1 {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// MoveToProjectById moves the todo at version into projectID.
+func (t *todoRepository) MoveToProjectById(ctx context.Context, id, version int, projectID *int) error {
+	query := "UPDATE todos SET version = version + 1, project_id = ?, updatedAt = ? WHERE id = ? AND version = ? AND deletedAt IS NULL"
+	result, err := t.db.ExecContext(ctx, query, nullInt(projectID), time.Now(), id, version)
 	if err != nil {
 		return err
 	}
-	return nil
+
+	return checkVersion(result)
 }
 
-func (t *todoRepository) DeleteById(ctx context.Context, id int) error {
-	query := `DELETE FROM todos WHERE id = ?`
-	result, err := t.db.Exec(query, id)
+// MarkDoneById marks the open todo at version done, applying policy to its
+// checklist items, and inserts next, the following occurrence of a recurring
+// todo, if any, all in one transaction. It returns ErrVersionConflict and
+// changes nothing when the todo was changed or completed in the meantime, so
+// that concurrent calls create only one next occurrence.
+func (t *todoRepository) MarkDoneById(ctx context.Context, id, version int, policy model.DonePolicy, next *model.Todo) error {
+	tx, err := t.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
-	_, err = result.RowsAffected()
+	defer tx.Rollback()
+
+	now := time.Now()
+	query := "UPDATE todos SET version = version + 1, done = 1, updatedAt = ? WHERE id = ? AND version = ? AND done = 0 AND deletedAt IS NULL"
+	result, err := tx.ExecContext(ctx, query, now, id, version)
 	if err != nil {
 		return err
 	}
+	if err := checkVersion(result); err != nil {
+		return err
+	}
+
+	if err := completeTodo(ctx, tx, id, policy, now, next); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// completeTodo applies policy to the open checklist items of the todo being
+// completed in tx and inserts next, if any. It returns ErrOpenChecklistItems
+// when policy refuses to complete todos with open items and the todo has
+// some.
+func completeTodo(ctx context.Context, tx *sql.Tx, id int, policy model.DonePolicy, now time.Time, next *model.Todo) error {
+	if policy == model.DonePolicyRefuseOpen {
+		// locking the open items keeps them open, or closed, until commit
+		var open int
+		query := "SELECT COUNT(*) FROM todo_items WHERE todo_id = ? AND done = 0 FOR UPDATE"
+		if err := tx.QueryRowContext(ctx, query, id).Scan(&open); err != nil {
+			return err
+		}
+		if open > 0 {
+			return ErrOpenChecklistItems
+		}
+	} else {
+		query := "UPDATE todo_items SET done = 1, updatedAt = ? WHERE todo_id = ? AND done = 0"
+		if _, err := tx.ExecContext(ctx, query, now, id); err != nil {
+			return err
+		}
+	}
+
+	if next != nil {
+		return insertTodo(ctx, tx, next)
+	}
 	return nil
 }
+
+// DeleteById moves the todo at version to the trash.
+func (t *todoRepository) DeleteById(ctx context.Context, id, version int) error {
+	query := `UPDATE todos SET version = version + 1, deletedAt = ? WHERE id = ? AND version = ? AND deletedAt IS NULL`
+	result, err := t.db.ExecContext(ctx, query, time.Now(), id, version)
+	if err != nil {
+		return err
+	}
+
+	return checkVersion(result)
+}
+
+func (t *todoRepository) GetTrash(ctx context.Context, tenant model.Tenant) ([]*model.Todo, error) {
+	condition, args := tenantCondition(tenant)
+	query := "SELECT " + todoColumns + " FROM todos WHERE " + condition + " AND deletedAt IS NOT NULL ORDER BY deletedAt DESC, id DESC"
+
+	return t.queryTodos(ctx, query, args...)
+}
+
+func (t *todoRepository) GetTrashedById(ctx context.Context, id int) (*model.Todo, error) {
+	query := "SELECT " + todoColumns + " FROM todos WHERE id = ? AND deletedAt IS NOT NULL"
+
+	todo, err := scanTodo(t.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := t.loadRelations(ctx, todo); err != nil {
+		return nil, err
+	}
+
+	return todo, nil
+}
+
+// RestoreById takes the trashed todo at version out of the trash.
+func (t *todoRepository) RestoreById(ctx context.Context, id, version int) error {
+	query := `UPDATE todos SET version = version + 1, deletedAt = NULL, updatedAt = ? WHERE id = ? AND version = ? AND deletedAt IS NOT NULL`
+	result, err := t.db.ExecContext(ctx, query, time.Now(), id, version)
+	if err != nil {
+		return err
+	}
+
+	return checkVersion(result)
+}
+
+// PurgeById permanently deletes the trashed todo at version with its shares.
+func (t *todoRepository) PurgeById(ctx context.Context, id, version int) error {
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `DELETE FROM todos WHERE id = ? AND version = ? AND deletedAt IS NOT NULL`
+	result, err := tx.ExecContext(ctx, query, id, version)
+	if err != nil {
+		return err
+	}
+	if err := checkVersion(result); err != nil {
+		return err
+	}
+	if err := deleteShares(ctx, tx, model.ShareTodo, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// PurgeDeletedBefore permanently deletes every todo trashed before cutoff,
+// with their shares.
+func (t *todoRepository) PurgeDeletedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	query := `DELETE FROM shares WHERE resource_type = ? AND resource_id IN
+(SELECT id FROM todos WHERE deletedAt IS NOT NULL AND deletedAt < ?)`
+	if _, err := tx.ExecContext(ctx, query, model.ShareTodo, cutoff); err != nil {
+		return 0, err
+	}
+
+	query = `DELETE FROM todos WHERE deletedAt IS NOT NULL AND deletedAt < ?`
+	result, err := tx.ExecContext(ctx, query, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return purged, tx.Commit()
+}
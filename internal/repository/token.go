@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/King0625/golang-todolist/internal/model"
+)
+
+type TokenRepository interface {
+	CreateRefreshToken(ctx context.Context, token *model.RefreshToken) error
+	GetRefreshTokenByHash(ctx context.Context, hash string) (*model.RefreshToken, error)
+	RotateRefreshToken(ctx context.Context, oldID int, next *model.RefreshToken) (bool, error)
+	RevokeRefreshTokenFamily(ctx context.Context, familyID string) error
+	RevokeRefreshTokensByUserId(ctx context.Context, userID int) error
+	RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error
+	IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error)
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+type tokenRepository struct {
+	db *sql.DB
+}
+
+func NewTokenRepository(db *sql.DB) TokenRepository {
+	return &tokenRepository{db: db}
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func insertRefreshToken(ctx context.Context, db execer, token *model.RefreshToken) error {
+	insertQuery := `INSERT INTO refresh_tokens (user_id, family_id, token_hash, expiresAt) VALUES(?,?,?,?)`
+
+	result, err := db.ExecContext(ctx, insertQuery,
+		token.UserID,
+		token.FamilyID,
+		token.TokenHash,
+		token.ExpiresAt,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	newId, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	token.ID = int(newId)
+
+	return nil
+}
+
+func (t *tokenRepository) CreateRefreshToken(ctx context.Context, token *model.RefreshToken) error {
+	return insertRefreshToken(ctx, t.db, token)
+}
+
+func (t *tokenRepository) GetRefreshTokenByHash(ctx context.Context, hash string) (*model.RefreshToken, error) {
+	query := `SELECT id, user_id, family_id, token_hash, expiresAt, revokedAt, createdAt FROM refresh_tokens WHERE token_hash = ?`
+
+	var token model.RefreshToken
+	var revokedAt sql.NullTime
+
+	err := t.db.QueryRowContext(ctx, query, hash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.FamilyID,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&revokedAt,
+		&token.CreatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if revokedAt.Valid {
+		token.RevokedAt = &revokedAt.Time
+	}
+
+	return &token, nil
+}
+
+// RotateRefreshToken revokes the token oldID and stores next in one
+// transaction. It reports false, storing nothing, when oldID had already been
+// revoked, e.g. by a concurrent rotation.
+func (t *tokenRepository) RotateRefreshToken(ctx context.Context, oldID int, next *model.RefreshToken) (bool, error) {
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE refresh_tokens SET revokedAt = ? WHERE id = ? AND revokedAt IS NULL`
+	result, err := tx.ExecContext(ctx, query, time.Now(), oldID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, nil
+	}
+
+	if err := insertRefreshToken(ctx, tx, next); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+func (t *tokenRepository) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	query := `UPDATE refresh_tokens SET revokedAt = ? WHERE family_id = ? AND revokedAt IS NULL`
+	_, err := t.db.ExecContext(ctx, query, time.Now(), familyID)
+
+	return err
+}
+
+func (t *tokenRepository) RevokeRefreshTokensByUserId(ctx context.Context, userID int) error {
+	query := `UPDATE refresh_tokens SET revokedAt = ? WHERE user_id = ? AND revokedAt IS NULL`
+	_, err := t.db.ExecContext(ctx, query, time.Now(), userID)
+
+	return err
+}
+
+func (t *tokenRepository) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	query := `INSERT IGNORE INTO revoked_access_tokens (jti, expiresAt) VALUES(?,?)`
+	_, err := t.db.ExecContext(ctx, query, jti, expiresAt)
+
+	return err
+}
+
+func (t *tokenRepository) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	var found int
+	query := `SELECT 1 FROM revoked_access_tokens WHERE jti = ?`
+	err := t.db.QueryRowContext(ctx, query, jti).Scan(&found)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// DeleteExpired removes denylist entries and refresh tokens that can no longer
+// be used anyway.
+func (t *tokenRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	var total int64
+
+	for _, query := range []string{
+		`DELETE FROM revoked_access_tokens WHERE expiresAt < ?`,
+		`DELETE FROM refresh_tokens WHERE expiresAt < ?`,
+	} {
+		result, err := t.db.ExecContext(ctx, query, now)
+		if err != nil {
+			return total, err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return total, err
+		}
+		total += affected
+	}
+
+	return total, nil
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"strings"
+	"time"
 
 	"github.com/King0625/golang-todolist/internal/model"
 	"golang.org/x/crypto/bcrypt"
@@ -19,6 +21,73 @@ type UserRepository interface {
 	Create(ctx context.Context, u *model.User) error
 	GetByEmail(ctx context.Context, email string) (*model.User, error)
 	GetById(ctx context.Context, id int) (*model.User, error)
+	UpdatePasswordById(ctx context.Context, id int, password string) error
+	MarkEmailVerifiedById(ctx context.Context, id int) error
+	SetTOTPSecretById(ctx context.Context, id int, secret string) error
+	EnableTOTPById(ctx context.Context, id int, step int64) error
+	UseTOTPStepById(ctx context.Context, id int, step int64) (bool, error)
+	DisableTOTPById(ctx context.Context, id int) error
+	UpdateProfileById(ctx context.Context, id int, firstName, lastName string) error
+	SetPendingEmailById(ctx context.Context, id int, email string) error
+	ConfirmPendingEmailById(ctx context.Context, id int) error
+	DeleteById(ctx context.Context, id int) error
+	List(ctx context.Context, query model.UserQuery) (*model.UserPage, error)
+	SetRoleById(ctx context.Context, id int, role model.Role) error
+	SetRoleByEmails(ctx context.Context, emails []string, role model.Role) (int64, error)
+	SetDisabledById(ctx context.Context, id int, disabled bool) error
+	BumpTokenGenerationById(ctx context.Context, id int) error
+	GetTokenGenerationById(ctx context.Context, id int) (int, error)
+	GetStats(ctx context.Context, since time.Time) (*model.SystemStats, error)
+}
+
+var ErrDuplicateEmail = errors.New("email already in use")
+
+const userColumns = "id, email, firstName, lastName, password, createdAt, updatedAt, emailVerifiedAt, totpSecret, totpEnabledAt, totpLastStep, pendingEmail, role, disabledAt, tokenGeneration"
+
+func scanUser(s rowScanner) (*model.User, error) {
+	var user model.User
+	var emailVerifiedAt, totpEnabledAt, disabledAt sql.NullTime
+	var totpSecret, pendingEmail sql.NullString
+	var totpLastStep sql.NullInt64
+
+	err := s.Scan(
+		&user.ID,
+		&user.Email,
+		&user.FirstName,
+		&user.LastName,
+		&user.Password,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+		&emailVerifiedAt,
+		&totpSecret,
+		&totpEnabledAt,
+		&totpLastStep,
+		&pendingEmail,
+		&user.Role,
+		&disabledAt,
+		&user.TokenGeneration,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if emailVerifiedAt.Valid {
+		user.EmailVerifiedAt = &emailVerifiedAt.Time
+	}
+	if totpEnabledAt.Valid {
+		user.TwoFactorEnabledAt = &totpEnabledAt.Time
+	}
+	user.TOTPSecret = totpSecret.String
+	user.TOTPLastStep = totpLastStep.Int64
+	if pendingEmail.Valid {
+		user.PendingEmail = &pendingEmail.String
+	}
+	if disabledAt.Valid {
+		user.DisabledAt = &disabledAt.Time
+	}
+
+	return &user, nil
 }
 
 type userRepository struct {
@@ -41,13 +110,18 @@ func (r *userRepository) Create(ctx context.Context, u *model.User) error {
 		return err
 	}
 
-	insertUserQuery := `INSERT INTO users (email, firstName, lastName, password, createdAt, updatedAt) VALUES(?,?,?,?,?,?)`
+	if u.Role == "" {
+		u.Role = model.RoleUser
+	}
+
+	insertUserQuery := `INSERT INTO users (email, firstName, lastName, password, role, createdAt, updatedAt) VALUES(?,?,?,?,?,?,?)`
 
 	result, err := r.db.ExecContext(ctx, insertUserQuery,
 		u.Email,
 		u.FirstName,
 		u.LastName,
 		hashedPassword,
+		u.Role,
 		u.CreatedAt,
 		u.UpdatedAt,
 	)
@@ -67,47 +141,314 @@ func (r *userRepository) Create(ctx context.Context, u *model.User) error {
 }
 
 func (r *userRepository) GetByEmail(ctx context.Context, email string) (*model.User, error) {
-	var user model.User
-
 	getUserByEmailQuery := `
-SELECT * FROM users WHERE email = ?`
+SELECT ` + userColumns + ` FROM users WHERE email = ?`
 
-	err := r.db.QueryRowContext(ctx, getUserByEmailQuery, email).Scan(
-		&user.ID,
-		&user.Email,
-		&user.FirstName,
-		&user.LastName,
-		&user.Password,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+	return scanUser(r.db.QueryRowContext(ctx, getUserByEmailQuery, email))
+}
+
+func (r *userRepository) GetById(ctx context.Context, id int) (*model.User, error) {
+	getUserByIdQuery := `
+SELECT ` + userColumns + ` FROM users WHERE id = ?`
+
+	return scanUser(r.db.QueryRowContext(ctx, getUserByIdQuery, id))
+}
+
+func (r *userRepository) UpdatePasswordById(ctx context.Context, id int, password string) error {
+	hashedPassword, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE users SET password = ?, updatedAt = ? WHERE id = ?`
+	_, err = r.db.ExecContext(ctx, query, hashedPassword, time.Now(), id)
+
+	return err
+}
+
+func (r *userRepository) MarkEmailVerifiedById(ctx context.Context, id int) error {
+	query := `UPDATE users SET emailVerifiedAt = ?, updatedAt = ? WHERE id = ? AND emailVerifiedAt IS NULL`
+	now := time.Now()
+	_, err := r.db.ExecContext(ctx, query, now, now, id)
+
+	return err
+}
+
+// SetTOTPSecretById stores a pending secret. 2FA stays off until EnableTOTPById.
+func (r *userRepository) SetTOTPSecretById(ctx context.Context, id int, secret string) error {
+	query := `UPDATE users SET totpSecret = ?, totpEnabledAt = NULL, totpLastStep = NULL, updatedAt = ? WHERE id = ?`
+	_, err := r.db.ExecContext(ctx, query, secret, time.Now(), id)
+
+	return err
+}
+
+func (r *userRepository) EnableTOTPById(ctx context.Context, id int, step int64) error {
+	query := `UPDATE users SET totpEnabledAt = ?, totpLastStep = ?, updatedAt = ? WHERE id = ?`
+	now := time.Now()
+	_, err := r.db.ExecContext(ctx, query, now, step, now, id)
 
+	return err
+}
+
+// UseTOTPStepById records step as used. It reports false when that step or a
+// later one was used already, so a code cannot be replayed.
+func (r *userRepository) UseTOTPStepById(ctx context.Context, id int, step int64) (bool, error) {
+	query := `UPDATE users SET totpLastStep = ? WHERE id = ? AND (totpLastStep IS NULL OR totpLastStep < ?)`
+	result, err := r.db.ExecContext(ctx, query, step, id, step)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
+func (r *userRepository) DisableTOTPById(ctx context.Context, id int) error {
+	query := `UPDATE users SET totpSecret = NULL, totpEnabledAt = NULL, totpLastStep = NULL, updatedAt = ? WHERE id = ?`
+	_, err := r.db.ExecContext(ctx, query, time.Now(), id)
+
+	return err
+}
+
+func (r *userRepository) UpdateProfileById(ctx context.Context, id int, firstName, lastName string) error {
+	query := `UPDATE users SET firstName = ?, lastName = ?, updatedAt = ? WHERE id = ?`
+	_, err := r.db.ExecContext(ctx, query, firstName, lastName, time.Now(), id)
+
+	return err
+}
+
+func (r *userRepository) SetPendingEmailById(ctx context.Context, id int, email string) error {
+	query := `UPDATE users SET pendingEmail = ?, updatedAt = ? WHERE id = ?`
+	_, err := r.db.ExecContext(ctx, query, email, time.Now(), id)
+
+	return err
+}
+
+// ConfirmPendingEmailById makes the pending email the verified email of the
+// user. It returns ErrDuplicateEmail when another account took the address in
+// the meantime.
+func (r *userRepository) ConfirmPendingEmailById(ctx context.Context, id int) error {
+	query := `UPDATE users SET email = pendingEmail, pendingEmail = NULL, emailVerifiedAt = ?, updatedAt = ?
+WHERE id = ? AND pendingEmail IS NOT NULL`
+	now := time.Now()
+	_, err := r.db.ExecContext(ctx, query, now, now, id)
+	if isDuplicateEntry(err) {
+		return ErrDuplicateEmail
+	}
+
+	return err
+}
+
+// userOwnedTables are cleared along with the user. Rows hanging off todos,
+// like checklist items and tag links, go with them by foreign key.
+var userOwnedTables = []string{
+	"todo_comments",
+	"comment_mentions",
+	"todos",
+	"tags",
+	"projects",
+	"refresh_tokens",
+	"user_tokens",
+	"personal_access_tokens",
+	"recovery_codes",
+	"user_identities",
+	"data_exports",
+}
+
+// DeleteById removes the user and everything they own in one transaction.
+func (r *userRepository) DeleteById(ctx context.Context, id int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// workspaces the user owns go with them; what they created in the
+	// workspaces of others is handed to the owners of those workspaces
+	if err := deleteWorkspaces(ctx, tx, "owner_id = ?", id); err != nil {
+		return err
+	}
+	for _, query := range []string{
+		`UPDATE todos t JOIN workspaces w ON w.id = t.workspace_id SET t.user_id = w.owner_id, t.version = t.version + 1 WHERE t.user_id = ?`,
+		`UPDATE projects p JOIN workspaces w ON w.id = p.workspace_id SET p.user_id = w.owner_id WHERE p.user_id = ?`,
+		`UPDATE tags tg JOIN workspaces w ON w.id = tg.workspace_id SET tg.user_id = w.owner_id WHERE tg.user_id = ?`,
+		`UPDATE todos SET assignee_id = NULL, version = version + 1 WHERE assignee_id = ?`,
+		`DELETE FROM workspace_members WHERE user_id = ?`,
+		// the activity of todos that stay keeps the events, just unattributed
+		`UPDATE todo_events SET user_id = NULL WHERE user_id = ?`,
+		`UPDATE todo_revisions SET user_id = NULL WHERE user_id = ?`,
+	} {
+		if _, err := tx.ExecContext(ctx, query, id); err != nil {
+			return err
+		}
+	}
+
+	for _, table := range userOwnedTables {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE user_id = ?", id); err != nil {
+			return err
+		}
+	}
+
+	// shares the user made, and those made with them
+	if _, err := tx.ExecContext(ctx, `DELETE FROM shares WHERE owner_id = ? OR user_id = ?`, id, id); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = ?`, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// likeEscaper escapes the wildcards of LIKE patterns.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (r *userRepository) List(ctx context.Context, query model.UserQuery) (*model.UserPage, error) {
+	var conditions []string
+	var args []any
+
+	conditions = append(conditions, "id > ?")
+	args = append(args, query.AfterID)
+
+	if query.Search != "" {
+		pattern := "%" + likeEscaper.Replace(query.Search) + "%"
+		conditions = append(conditions, "(email LIKE ? OR CONCAT(firstName, ' ', lastName) LIKE ?)")
+		args = append(args, pattern, pattern)
+	}
+	if query.Role != "" {
+		conditions = append(conditions, "role = ?")
+		args = append(args, query.Role)
+	}
+	if query.Disabled != nil {
+		if *query.Disabled {
+			conditions = append(conditions, "disabledAt IS NOT NULL")
+		} else {
+			conditions = append(conditions, "disabledAt IS NULL")
+		}
+	}
+
+	// one extra row tells whether there is another page
+	listQuery := "SELECT " + userColumns + " FROM users WHERE " + strings.Join(conditions, " AND ") + " ORDER BY id LIMIT ?"
+	args = append(args, query.Limit+1)
+
+	rows, err := r.db.QueryContext(ctx, listQuery, args...)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	return &user, nil
+	page := model.UserPage{Users: []*model.User{}}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		page.Users = append(page.Users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(page.Users) > query.Limit {
+		page.Users = page.Users[:query.Limit]
+		page.HasMore = true
+	}
+
+	return &page, nil
 }
 
-func (r *userRepository) GetById(ctx context.Context, id int) (*model.User, error) {
-	var user model.User
+func (r *userRepository) SetRoleById(ctx context.Context, id int, role model.Role) error {
+	query := `UPDATE users SET role = ?, updatedAt = ? WHERE id = ?`
+	_, err := r.db.ExecContext(ctx, query, role, time.Now(), id)
 
-	getUserByIdQuery := `
-SELECT * FROM users WHERE id = ?`
+	return err
+}
 
-	err := r.db.QueryRowContext(ctx, getUserByIdQuery, id).Scan(
-		&user.ID,
-		&user.Email,
-		&user.FirstName,
-		&user.LastName,
-		&user.Password,
-		&user.CreatedAt,
-		&user.UpdatedAt,
+func (r *userRepository) SetRoleByEmails(ctx context.Context, emails []string, role model.Role) (int64, error) {
+	if len(emails) == 0 {
+		return 0, nil
+	}
+
+	query := `UPDATE users SET role = ?, updatedAt = ? WHERE role <> ? AND email IN (` + placeholders(len(emails)) + `)`
+	args := []any{role, time.Now(), role}
+	for _, email := range emails {
+		args = append(args, email)
+	}
+
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+func (r *userRepository) SetDisabledById(ctx context.Context, id int, disabled bool) error {
+	var disabledAt *time.Time
+	now := time.Now()
+	if disabled {
+		disabledAt = &now
+	}
+
+	query := `UPDATE users SET disabledAt = ?, updatedAt = ? WHERE id = ?`
+	_, err := r.db.ExecContext(ctx, query, disabledAt, now, id)
+
+	return err
+}
+
+// BumpTokenGenerationById invalidates the access tokens issued to the user
+// so far.
+func (r *userRepository) BumpTokenGenerationById(ctx context.Context, id int) error {
+	query := `UPDATE users SET tokenGeneration = tokenGeneration + 1, updatedAt = ? WHERE id = ?`
+	_, err := r.db.ExecContext(ctx, query, time.Now(), id)
+
+	return err
+}
+
+// GetTokenGenerationById returns the generation valid access tokens of the
+// user carry.
+func (r *userRepository) GetTokenGenerationById(ctx context.Context, id int) (int, error) {
+	var generation int
+	err := r.db.QueryRowContext(ctx, `SELECT tokenGeneration FROM users WHERE id = ?`, id).Scan(&generation)
+
+	return generation, err
+}
+
+// GetStats counts users and their content; new users are those created
+// after since.
+func (r *userRepository) GetStats(ctx context.Context, since time.Time) (*model.SystemStats, error) {
+	query := `SELECT
+	(SELECT COUNT(*) FROM users),
+	(SELECT COUNT(*) FROM users WHERE emailVerifiedAt IS NOT NULL),
+	(SELECT COUNT(*) FROM users WHERE disabledAt IS NOT NULL),
+	(SELECT COUNT(*) FROM users WHERE role = ?),
+	(SELECT COUNT(*) FROM users WHERE createdAt > ?),
+	(SELECT COUNT(*) FROM todos WHERE deletedAt IS NULL),
+	(SELECT COUNT(*) FROM todos WHERE deletedAt IS NULL AND done = 1),
+	(SELECT COUNT(*) FROM todos WHERE deletedAt IS NOT NULL),
+	(SELECT COUNT(*) FROM projects),
+	(SELECT COUNT(*) FROM tags),
+	(SELECT COUNT(*) FROM personal_access_tokens WHERE revokedAt IS NULL AND (expiresAt IS NULL OR expiresAt > ?))`
+
+	var stats model.SystemStats
+	err := r.db.QueryRowContext(ctx, query, model.RoleAdmin, since, time.Now()).Scan(
+		&stats.Users,
+		&stats.VerifiedUsers,
+		&stats.DisabledUsers,
+		&stats.Admins,
+		&stats.NewUsersLastWeek,
+		&stats.Todos,
+		&stats.DoneTodos,
+		&stats.TrashedTodos,
+		&stats.Projects,
+		&stats.Tags,
+		&stats.ActivePersonalAccessTokens,
 	)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return &user, nil
+	return &stats, nil
 }
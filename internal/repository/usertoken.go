@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/King0625/golang-todolist/internal/model"
+)
+
+type UserTokenRepository interface {
+	Create(ctx context.Context, token *model.UserToken) error
+	GetByHash(ctx context.Context, purpose model.TokenPurpose, hash string) (*model.UserToken, error)
+	MarkUsedById(ctx context.Context, id int) (bool, error)
+	InvalidateByUserId(ctx context.Context, userID int, purpose model.TokenPurpose) error
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+type userTokenRepository struct {
+	db *sql.DB
+}
+
+func NewUserTokenRepository(db *sql.DB) UserTokenRepository {
+	return &userTokenRepository{db: db}
+}
+
+func (u *userTokenRepository) Create(ctx context.Context, token *model.UserToken) error {
+	insertQuery := `INSERT INTO user_tokens (user_id, purpose, token_hash, expiresAt) VALUES(?,?,?,?)`
+
+	result, err := u.db.ExecContext(ctx, insertQuery,
+		token.UserID,
+		token.Purpose,
+		token.TokenHash,
+		token.ExpiresAt,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	newId, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	token.ID = int(newId)
+
+	return nil
+}
+
+func (u *userTokenRepository) GetByHash(ctx context.Context, purpose model.TokenPurpose, hash string) (*model.UserToken, error) {
+	query := `SELECT id, user_id, purpose, token_hash, expiresAt, usedAt FROM user_tokens WHERE purpose = ? AND token_hash = ?`
+
+	var token model.UserToken
+	var usedAt sql.NullTime
+
+	err := u.db.QueryRowContext(ctx, query, purpose, hash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.Purpose,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&usedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if usedAt.Valid {
+		token.UsedAt = &usedAt.Time
+	}
+
+	return &token, nil
+}
+
+// MarkUsedById consumes the token. It reports false when the token had
+// already been used.
+func (u *userTokenRepository) MarkUsedById(ctx context.Context, id int) (bool, error) {
+	query := `UPDATE user_tokens SET usedAt = ? WHERE id = ? AND usedAt IS NULL`
+	result, err := u.db.ExecContext(ctx, query, time.Now(), id)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
+// InvalidateByUserId uses up every outstanding token of the purpose, so only
+// the most recently mailed link works.
+func (u *userTokenRepository) InvalidateByUserId(ctx context.Context, userID int, purpose model.TokenPurpose) error {
+	query := `UPDATE user_tokens SET usedAt = ? WHERE user_id = ? AND purpose = ? AND usedAt IS NULL`
+	_, err := u.db.ExecContext(ctx, query, time.Now(), userID, purpose)
+
+	return err
+}
+
+func (u *userTokenRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	query := `DELETE FROM user_tokens WHERE expiresAt < ?`
+	result, err := u.db.ExecContext(ctx, query, now)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
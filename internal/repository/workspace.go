@@ -0,0 +1,272 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/King0625/golang-todolist/internal/model"
+)
+
+var ErrAlreadyMember = errors.New("user is already a member of the workspace")
+
+type WorkspaceRepository interface {
+	Create(ctx context.Context, workspace *model.Workspace) error
+	GetById(ctx context.Context, id int) (*model.Workspace, error)
+	GetAllByUserId(ctx context.Context, userID int) ([]*model.Workspace, error)
+	UpdateById(ctx context.Context, id int, name string) error
+	DeleteById(ctx context.Context, id int) error
+	GetMembers(ctx context.Context, workspaceID int) ([]*model.WorkspaceMember, error)
+	GetMemberRole(ctx context.Context, workspaceID, userID int) (model.WorkspaceRole, error)
+	AddMember(ctx context.Context, workspaceID, userID int, role model.WorkspaceRole) error
+	SetMemberRole(ctx context.Context, workspaceID, userID int, role model.WorkspaceRole) (bool, error)
+	RemoveMember(ctx context.Context, workspaceID, userID int) (bool, error)
+}
+
+const workspaceColumns = "w.id, w.owner_id, w.name, w.createdAt, w.updatedAt"
+
+func scanWorkspace(s rowScanner, role *model.WorkspaceRole) (*model.Workspace, error) {
+	var workspace model.Workspace
+
+	dest := []any{
+		&workspace.ID,
+		&workspace.OwnerID,
+		&workspace.Name,
+		&workspace.CreatedAt,
+		&workspace.UpdatedAt,
+	}
+	if role != nil {
+		dest = append(dest, role)
+	}
+
+	if err := s.Scan(dest...); err != nil {
+		return nil, err
+	}
+	if role != nil {
+		workspace.Role = *role
+	}
+
+	return &workspace, nil
+}
+
+type workspaceRepository struct {
+	db *sql.DB
+}
+
+func NewWorkspaceRepository(db *sql.DB) WorkspaceRepository {
+	return &workspaceRepository{db: db}
+}
+
+// Create stores the workspace and makes its owner the first member.
+func (w *workspaceRepository) Create(ctx context.Context, workspace *model.Workspace) error {
+	tx, err := w.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `INSERT INTO workspaces (owner_id, name) VALUES(?,?)`, workspace.OwnerID, workspace.Name)
+	if err != nil {
+		return err
+	}
+
+	newId, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO workspace_members (workspace_id, user_id, role) VALUES(?,?,?)`
+	if _, err := tx.ExecContext(ctx, query, newId, workspace.OwnerID, model.WorkspaceRoleOwner); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	workspace.ID = int(newId)
+	workspace.Role = model.WorkspaceRoleOwner
+
+	return nil
+}
+
+func (w *workspaceRepository) GetById(ctx context.Context, id int) (*model.Workspace, error) {
+	query := "SELECT " + workspaceColumns + " FROM workspaces w WHERE w.id = ?"
+
+	return scanWorkspace(w.db.QueryRowContext(ctx, query, id), nil)
+}
+
+// GetAllByUserId lists the workspaces the user is a member of, with their role.
+func (w *workspaceRepository) GetAllByUserId(ctx context.Context, userID int) ([]*model.Workspace, error) {
+	query := "SELECT " + workspaceColumns + `, m.role FROM workspaces w
+JOIN workspace_members m ON m.workspace_id = w.id WHERE m.user_id = ? ORDER BY w.name, w.id`
+
+	rows, err := w.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	workspaces := []*model.Workspace{}
+	for rows.Next() {
+		var role model.WorkspaceRole
+		workspace, err := scanWorkspace(rows, &role)
+		if err != nil {
+			return nil, err
+		}
+
+		workspaces = append(workspaces, workspace)
+	}
+
+	return workspaces, rows.Err()
+}
+
+func (w *workspaceRepository) UpdateById(ctx context.Context, id int, name string) error {
+	query := "UPDATE workspaces SET name = ?, updatedAt = ? WHERE id = ?"
+	_, err := w.db.ExecContext(ctx, query, name, time.Now(), id)
+
+	return err
+}
+
+// DeleteById removes the workspace with its members, projects, tags and todos
+// in one transaction. Checklist items and tag links go with the todos by
+// foreign key.
+func (w *workspaceRepository) DeleteById(ctx context.Context, id int) error {
+	tx, err := w.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := deleteWorkspaces(ctx, tx, "id = ?", id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// deleteWorkspaces removes the workspaces matching condition with everything
+// in them.
+func deleteWorkspaces(ctx context.Context, db execer, condition string, args ...any) error {
+	selected := "SELECT id FROM workspaces WHERE " + condition
+
+	for _, query := range []string{
+		// MySQL cannot select from the table it deletes from, hence the
+		// derived tables
+		`DELETE FROM todos WHERE workspace_id IN (SELECT id FROM (` + selected + `) w)`,
+		`DELETE FROM projects WHERE workspace_id IN (SELECT id FROM (` + selected + `) w)`,
+		`DELETE FROM tags WHERE workspace_id IN (SELECT id FROM (` + selected + `) w)`,
+		`DELETE FROM workspace_members WHERE workspace_id IN (SELECT id FROM (` + selected + `) w)`,
+		`DELETE FROM workspaces WHERE ` + condition,
+	} {
+		if _, err := db.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w *workspaceRepository) GetMembers(ctx context.Context, workspaceID int) ([]*model.WorkspaceMember, error) {
+	query := `SELECT m.workspace_id, m.user_id, u.email, u.firstName, u.lastName, m.role, m.createdAt
+FROM workspace_members m JOIN users u ON u.id = m.user_id WHERE m.workspace_id = ? ORDER BY m.createdAt, m.user_id`
+
+	rows, err := w.db.QueryContext(ctx, query, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []*model.WorkspaceMember{}
+	for rows.Next() {
+		var member model.WorkspaceMember
+		err := rows.Scan(
+			&member.WorkspaceID,
+			&member.UserID,
+			&member.Email,
+			&member.FirstName,
+			&member.LastName,
+			&member.Role,
+			&member.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		members = append(members, &member)
+	}
+
+	return members, rows.Err()
+}
+
+// GetMemberRole returns the role of the user in the workspace, or an empty
+// role when they are not a member.
+func (w *workspaceRepository) GetMemberRole(ctx context.Context, workspaceID, userID int) (model.WorkspaceRole, error) {
+	var role model.WorkspaceRole
+	query := `SELECT role FROM workspace_members WHERE workspace_id = ? AND user_id = ?`
+	err := w.db.QueryRowContext(ctx, query, workspaceID, userID).Scan(&role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+
+	return role, err
+}
+
+func (w *workspaceRepository) AddMember(ctx context.Context, workspaceID, userID int, role model.WorkspaceRole) error {
+	query := `INSERT INTO workspace_members (workspace_id, user_id, role) VALUES(?,?,?)`
+	_, err := w.db.ExecContext(ctx, query, workspaceID, userID, role)
+	if isDuplicateEntry(err) {
+		return ErrAlreadyMember
+	}
+
+	return err
+}
+
+// SetMemberRole changes the role of a member other than the owner. It
+// reports false when there is no such member.
+func (w *workspaceRepository) SetMemberRole(ctx context.Context, workspaceID, userID int, role model.WorkspaceRole) (bool, error) {
+	query := `UPDATE workspace_members SET role = ? WHERE workspace_id = ? AND user_id = ? AND role <> ?`
+	result, err := w.db.ExecContext(ctx, query, role, workspaceID, userID, model.WorkspaceRoleOwner)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+// RemoveMember removes a member other than the owner and unassigns their
+// todos in the workspace. It reports false when there is no such member.
+func (w *workspaceRepository) RemoveMember(ctx context.Context, workspaceID, userID int) (bool, error) {
+	tx, err := w.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	query := `DELETE FROM workspace_members WHERE workspace_id = ? AND user_id = ? AND role <> ?`
+	result, err := tx.ExecContext(ctx, query, workspaceID, userID, model.WorkspaceRoleOwner)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, nil
+	}
+
+	query = `UPDATE todos SET assignee_id = NULL, version = version + 1 WHERE workspace_id = ? AND assignee_id = ?`
+	if _, err := tx.ExecContext(ctx, query, workspaceID, userID); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/King0625/golang-todolist/internal/model"
+	"github.com/King0625/golang-todolist/internal/repository"
+)
+
+var ErrCannotModifySelf = errors.New("admins cannot demote or disable themselves")
+
+type AdminService interface {
+	ListUsers(ctx context.Context, query model.UserQuery) (*model.UserPage, error)
+	GetUserById(ctx context.Context, id int) (*model.User, error)
+	SetUserRole(ctx context.Context, actorID, userID int, role model.Role) error
+	DisableUser(ctx context.Context, actorID, userID int) error
+	EnableUser(ctx context.Context, userID int) error
+	UnlockUser(ctx context.Context, userID int) error
+	GetStats(ctx context.Context) (*model.SystemStats, error)
+	GetUserRole(ctx context.Context, userID int) (model.Role, error)
+	PromoteAdmins(ctx context.Context, emails []string) (int64, error)
+}
+
+type adminService struct {
+	users    repository.UserRepository
+	sessions repository.TokenRepository
+	guard    LoginGuard
+}
+
+func NewAdminService(users repository.UserRepository, sessions repository.TokenRepository, guard LoginGuard) AdminService {
+	return &adminService{users, sessions, guard}
+}
+
+func (a *adminService) ListUsers(ctx context.Context, query model.UserQuery) (*model.UserPage, error) {
+	return a.users.List(ctx, query)
+}
+
+func (a *adminService) GetUserById(ctx context.Context, id int) (*model.User, error) {
+	return a.users.GetById(ctx, id)
+}
+
+// SetUserRole changes the role of userID. It takes effect on the next token
+// refresh, except that admin routes check the current role on every request.
+func (a *adminService) SetUserRole(ctx context.Context, actorID, userID int, role model.Role) error {
+	if actorID == userID && role != model.RoleAdmin {
+		return ErrCannotModifySelf
+	}
+
+	return a.users.SetRoleById(ctx, userID, role)
+}
+
+// DisableUser keeps userID from logging in and signs out their sessions,
+// including the access tokens already issued.
+func (a *adminService) DisableUser(ctx context.Context, actorID, userID int) error {
+	if actorID == userID {
+		return ErrCannotModifySelf
+	}
+
+	if err := a.users.SetDisabledById(ctx, userID, true); err != nil {
+		return err
+	}
+
+	if err := a.sessions.RevokeRefreshTokensByUserId(ctx, userID); err != nil {
+		return err
+	}
+
+	return a.users.BumpTokenGenerationById(ctx, userID)
+}
+
+func (a *adminService) EnableUser(ctx context.Context, userID int) error {
+	return a.users.SetDisabledById(ctx, userID, false)
+}
+
+// UnlockUser lifts a lockout after failed logins, like the link in the
+// unlock email does.
+func (a *adminService) UnlockUser(ctx context.Context, userID int) error {
+	user, err := a.users.GetById(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	return a.guard.Unlock(ctx, user.Email)
+}
+
+func (a *adminService) GetStats(ctx context.Context) (*model.SystemStats, error) {
+	return a.users.GetStats(ctx, time.Now().AddDate(0, 0, -7))
+}
+
+// GetUserRole returns the current role of userID, or an empty role when the
+// user is gone or disabled.
+func (a *adminService) GetUserRole(ctx context.Context, userID int) (model.Role, error) {
+	user, err := a.users.GetById(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if user.Disabled() {
+		return "", nil
+	}
+
+	return user.Role, nil
+}
+
+// PromoteAdmins makes the users with the given emails admins. It bootstraps
+// the first admins from the configuration.
+func (a *adminService) PromoteAdmins(ctx context.Context, emails []string) (int64, error) {
+	return a.users.SetRoleByEmails(ctx, emails, model.RoleAdmin)
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+	"unicode/utf8"
+
+	"github.com/King0625/golang-todolist/internal/actor"
+	"github.com/King0625/golang-todolist/internal/model"
+	"github.com/King0625/golang-todolist/internal/repository"
+)
+
+type AuditLog interface {
+	// Record appends event to the audit log, filling in who made the request
+	// and where it came from. Failures are only logged: the audited change
+	// has happened either way.
+	Record(ctx context.Context, event *model.AuditEvent)
+	List(ctx context.Context, query model.AuditQuery) (*model.AuditPage, error)
+}
+
+type auditLog struct {
+	repo repository.AuditRepository
+}
+
+func NewAuditLog(r repository.AuditRepository) AuditLog {
+	return &auditLog{r}
+}
+
+// truncate cuts s to the size of its column, without splitting a character.
+func truncate(s string, size int) string {
+	if len(s) <= size {
+		return s
+	}
+	for size > 0 && !utf8.RuneStart(s[size]) {
+		size--
+	}
+	return s[:size]
+}
+
+func (a *auditLog) Record(ctx context.Context, event *model.AuditEvent) {
+	if event.ActorID == nil {
+		if id, ok := actor.UserID(ctx); ok {
+			event.ActorID = &id
+		}
+	}
+
+	req := actor.RequestFrom(ctx)
+	event.IP = truncate(req.IP, 45)
+	event.UserAgent = truncate(req.UserAgent, 255)
+	event.RequestID = req.ID
+	event.Detail = truncate(event.Detail, 255)
+	event.CreatedAt = time.Now()
+
+	if err := a.repo.Create(ctx, event); err != nil {
+		log.Printf("record audit event %s error: %v", event.Action, err)
+	}
+}
+
+func (a *auditLog) List(ctx context.Context, query model.AuditQuery) (*model.AuditPage, error) {
+	return a.repo.List(ctx, query)
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/King0625/golang-todolist/internal/model"
+	"github.com/King0625/golang-todolist/internal/repository"
+	"github.com/King0625/golang-todolist/pkg/utils"
+)
+
+const RefreshTokenTTL = 30 * 24 * time.Hour
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reused")
+	ErrAccountDisabled     = errors.New("account is disabled")
+)
+
+type AuthService interface {
+	IssueTokens(ctx context.Context, user *model.User) (*model.TokenPair, error)
+	RefreshTokens(ctx context.Context, refreshToken string) (*model.TokenPair, error)
+	Logout(ctx context.Context, claims *utils.AccessClaims, refreshToken string) error
+	IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error)
+	DeleteExpiredTokens(ctx context.Context) (int64, error)
+}
+
+type authService struct {
+	repo     repository.TokenRepository
+	userRepo repository.UserRepository
+	keys     *utils.JWTKeySet
+}
+
+func NewAuthService(r repository.TokenRepository, userRepo repository.UserRepository, keys *utils.JWTKeySet) AuthService {
+	return &authService{r, userRepo, keys}
+}
+
+func newRefreshToken(userID int, familyID string) (string, *model.RefreshToken, error) {
+	token, hash, err := utils.NewOpaqueToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	return token, &model.RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(RefreshTokenTTL),
+	}, nil
+}
+
+func (a *authService) newTokenPair(user *model.User, refreshToken string) (*model.TokenPair, error) {
+	accessToken, err := a.keys.NewToken(user.FirstName+user.LastName, user.ID, string(user.Role), user.TokenGeneration)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(utils.AccessTokenTTL.Seconds()),
+	}, nil
+}
+
+// IssueTokens starts a new refresh token family for a fresh login. Every
+// login method ends here, so this is where disabled accounts are turned away.
+func (a *authService) IssueTokens(ctx context.Context, user *model.User) (*model.TokenPair, error) {
+	if user.Disabled() {
+		return nil, ErrAccountDisabled
+	}
+
+	familyID, err := utils.RandomString(16)
+	if err != nil {
+		return nil, err
+	}
+
+	token, record, err := newRefreshToken(user.ID, familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.repo.CreateRefreshToken(ctx, record); err != nil {
+		return nil, err
+	}
+
+	return a.newTokenPair(user, token)
+}
+
+// RefreshTokens exchanges a refresh token for a new token pair. Presenting a
+// token that was already rotated or revoked revokes its whole family, since
+// either the client or an attacker holds a stolen copy.
+func (a *authService) RefreshTokens(ctx context.Context, refreshToken string) (*model.TokenPair, error) {
+	record, err := a.repo.GetRefreshTokenByHash(ctx, utils.HashToken(refreshToken))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if record.RevokedAt != nil {
+		if err := a.repo.RevokeRefreshTokenFamily(ctx, record.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	if time.Now().After(record.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	user, err := a.userRepo.GetById(ctx, record.UserID)
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	if user.Disabled() {
+		return nil, ErrAccountDisabled
+	}
+
+	token, next, err := newRefreshToken(record.UserID, record.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+
+	rotated, err := a.repo.RotateRefreshToken(ctx, record.ID, next)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		// lost a race against another use of the same token
+		if err := a.repo.RevokeRefreshTokenFamily(ctx, record.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	return a.newTokenPair(user, token)
+}
+
+// Logout denylists the access token and revokes the family of refreshToken,
+// or every refresh token of the user when refreshToken is empty.
+func (a *authService) Logout(ctx context.Context, claims *utils.AccessClaims, refreshToken string) error {
+	if claims.ID != "" {
+		if err := a.repo.RevokeAccessToken(ctx, claims.ID, claims.ExpiresAt); err != nil {
+			return err
+		}
+	}
+
+	if refreshToken == "" {
+		return a.repo.RevokeRefreshTokensByUserId(ctx, claims.UserID)
+	}
+
+	record, err := a.repo.GetRefreshTokenByHash(ctx, utils.HashToken(refreshToken))
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && record.UserID != claims.UserID) {
+		return ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return err
+	}
+
+	return a.repo.RevokeRefreshTokenFamily(ctx, record.FamilyID)
+}
+
+func (a *authService) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	return a.repo.IsAccessTokenRevoked(ctx, jti)
+}
+
+func (a *authService) DeleteExpiredTokens(ctx context.Context) (int64, error) {
+	return a.repo.DeleteExpired(ctx, time.Now())
+}
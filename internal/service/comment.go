@@ -0,0 +1,216 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"sort"
+
+	"github.com/King0625/golang-todolist/internal/authz"
+	"github.com/King0625/golang-todolist/internal/model"
+	"github.com/King0625/golang-todolist/internal/repository"
+	"github.com/King0625/golang-todolist/pkg/mailer"
+)
+
+var (
+	ErrNotCommentAuthor = errors.New("only the author can change a comment")
+	ErrCommentNotFound  = errors.New("comment not found")
+)
+
+type CommentServiceConfig struct {
+	// BaseURL prefixes the link in mention emails.
+	BaseURL string
+}
+
+type CommentService interface {
+	AddComment(ctx context.Context, todo *model.Todo, comment *model.Comment) error
+	GetComments(ctx context.Context, todoID int) ([]*model.Comment, error)
+	GetCommentById(ctx context.Context, id int) (*model.Comment, error)
+	EditComment(ctx context.Context, todo *model.Todo, comment *model.Comment, userID int, content string) error
+	DeleteComment(ctx context.Context, comment *model.Comment, userID int) error
+	GetActivity(ctx context.Context, todoID int) ([]*model.ActivityEntry, error)
+}
+
+type commentService struct {
+	repo   repository.CommentRepository
+	events repository.TodoEventRepository
+	users  repository.UserRepository
+	policy authz.Policy
+	mailer mailer.Mailer
+	config CommentServiceConfig
+}
+
+func NewCommentService(r repository.CommentRepository, events repository.TodoEventRepository, users repository.UserRepository, policy authz.Policy, m mailer.Mailer, config CommentServiceConfig) CommentService {
+	return &commentService{r, events, users, policy, m, config}
+}
+
+// mentionPattern matches @ followed by an email address, like
+// @jane@example.com, that is not itself part of a word or address.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@.])@([\w.%+-]+@[\w-]+(?:\.[\w-]+)+)`)
+
+// resolveMentions returns the users mentioned in content who may read todo.
+// Addresses of unknown users or users without access are left as plain text.
+func (s *commentService) resolveMentions(ctx context.Context, todo *model.Todo, content string) ([]*model.User, error) {
+	var mentioned []*model.User
+	seen := map[int]bool{}
+
+	for _, match := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		user, err := s.users.GetByEmail(ctx, match[1])
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if seen[user.ID] {
+			continue
+		}
+		seen[user.ID] = true
+
+		err = s.policy.Authorize(ctx, authz.Subject{UserID: user.ID, Role: user.Role}, authz.ActionRead, todo)
+		if errors.Is(err, authz.ErrForbidden) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		mentioned = append(mentioned, user)
+	}
+
+	return mentioned, nil
+}
+
+// notifyMentions mails the mentioned users that were not mentioned before,
+// except the author. Comments are saved already, so failures are only logged.
+func (s *commentService) notifyMentions(ctx context.Context, todo *model.Todo, comment *model.Comment, mentioned []*model.User, before []int) {
+	author, err := s.users.GetById(ctx, comment.UserID)
+	if err != nil {
+		log.Printf("send mention email error: %v", err)
+		return
+	}
+
+	for _, user := range mentioned {
+		if user.ID == author.ID || slices.Contains(before, user.ID) {
+			continue
+		}
+
+		err := s.mailer.Send(ctx, mailer.Message{
+			To:      user.Email,
+			Subject: fmt.Sprintf("%s %s mentioned you on \"%s\"", author.FirstName, author.LastName, todo.Title),
+			Body: fmt.Sprintf("Hi,\n\n%s %s (%s) mentioned you in a comment on the todo \"%s\":\n\n%s\n\n%s\n",
+				author.FirstName, author.LastName, author.Email, todo.Title, comment.Content, fmt.Sprintf("%s/todos/%d/comments", s.config.BaseURL, todo.ID)),
+		})
+		if err != nil {
+			log.Printf("send mention email error: %v", err)
+		}
+	}
+}
+
+func userIDs(users []*model.User) []int {
+	ids := make([]int, len(users))
+	for i, user := range users {
+		ids[i] = user.ID
+	}
+	return ids
+}
+
+// AddComment saves comment on todo and notifies the users it mentions.
+func (s *commentService) AddComment(ctx context.Context, todo *model.Todo, comment *model.Comment) error {
+	mentioned, err := s.resolveMentions(ctx, todo, comment.Content)
+	if err != nil {
+		return err
+	}
+
+	comment.TodoID = todo.ID
+	comment.Mentions = userIDs(mentioned)
+	if err := s.repo.Create(ctx, comment); err != nil {
+		return err
+	}
+
+	s.notifyMentions(ctx, todo, comment, mentioned, nil)
+	return nil
+}
+
+func (s *commentService) GetComments(ctx context.Context, todoID int) ([]*model.Comment, error) {
+	return s.repo.GetAllByTodoId(ctx, todoID)
+}
+
+func (s *commentService) GetCommentById(ctx context.Context, id int) (*model.Comment, error) {
+	comment, err := s.repo.GetById(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCommentNotFound
+	}
+	return comment, err
+}
+
+// EditComment replaces the content of a comment by userID, who must be its
+// author. Only users that were not mentioned before are notified.
+func (s *commentService) EditComment(ctx context.Context, todo *model.Todo, comment *model.Comment, userID int, content string) error {
+	if comment.UserID != userID {
+		return ErrNotCommentAuthor
+	}
+
+	mentioned, err := s.resolveMentions(ctx, todo, content)
+	if err != nil {
+		return err
+	}
+
+	mentions := userIDs(mentioned)
+	if err := s.repo.UpdateById(ctx, comment.ID, content, mentions); err != nil {
+		return err
+	}
+
+	before := comment.Mentions
+	comment.Content = content
+	comment.Mentions = mentions
+	s.notifyMentions(ctx, todo, comment, mentioned, before)
+
+	return nil
+}
+
+// DeleteComment deletes a comment by userID, who must be its author.
+func (s *commentService) DeleteComment(ctx context.Context, comment *model.Comment, userID int) error {
+	if comment.UserID != userID {
+		return ErrNotCommentAuthor
+	}
+
+	return s.repo.DeleteById(ctx, comment.ID)
+}
+
+// GetActivity merges the comments and events of the todo into one feed,
+// oldest first.
+func (s *commentService) GetActivity(ctx context.Context, todoID int) ([]*model.ActivityEntry, error) {
+	comments, err := s.repo.GetAllByTodoId(ctx, todoID)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := s.events.GetAllByTodoId(ctx, todoID)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*model.ActivityEntry, 0, len(comments)+len(events))
+	for _, comment := range comments {
+		entries = append(entries, &model.ActivityEntry{Kind: model.ActivityComment, CreatedAt: comment.CreatedAt, Comment: comment})
+	}
+	for _, event := range events {
+		entries = append(entries, &model.ActivityEntry{Kind: model.ActivityEvent, CreatedAt: event.CreatedAt, Event: event})
+	}
+
+	// events come before comments made in the same instant, since a comment
+	// usually remarks on the change
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].CreatedAt.Equal(entries[j].CreatedAt) {
+			return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+		}
+		return entries[i].Kind == model.ActivityEvent && entries[j].Kind == model.ActivityComment
+	})
+
+	return entries, nil
+}
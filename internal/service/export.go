@@ -0,0 +1,251 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/King0625/golang-todolist/internal/model"
+	"github.com/King0625/golang-todolist/internal/repository"
+	"github.com/King0625/golang-todolist/pkg/jobs"
+)
+
+const (
+	// DataExportTTL is how long a finished archive can be downloaded.
+	DataExportTTL = 7 * 24 * time.Hour
+	// dataExportTimeout bounds a single build. Exports running for longer
+	// are assumed lost and queued again.
+	dataExportTimeout = 15 * time.Minute
+	dataExportBatch   = 20
+)
+
+var ErrExportNotReady = errors.New("export is not ready")
+
+// DataExportSources are the repositories an archive is built from.
+type DataExportSources struct {
+	Users      repository.UserRepository
+	Todos      repository.TodoRepository
+	Items      repository.ChecklistItemRepository
+	Tags       repository.TagRepository
+	Projects   repository.ProjectRepository
+	Tokens     repository.PersonalAccessTokenRepository
+	Identities repository.UserIdentityRepository
+}
+
+type DataExportService interface {
+	RequestExport(ctx context.Context, userID int) (*model.DataExport, error)
+	GetExportById(ctx context.Context, id int) (*model.DataExport, error)
+	GetArchiveById(ctx context.Context, id int) ([]byte, error)
+	ProcessPending(ctx context.Context) (int64, error)
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+type dataExportService struct {
+	repo    repository.DataExportRepository
+	sources DataExportSources
+	runner  *jobs.Runner
+}
+
+func NewDataExportService(r repository.DataExportRepository, sources DataExportSources, runner *jobs.Runner) DataExportService {
+	return &dataExportService{r, sources, runner}
+}
+
+// RequestExport queues a new export for the user, or returns the one already
+// being built.
+func (d *dataExportService) RequestExport(ctx context.Context, userID int) (*model.DataExport, error) {
+	active, err := d.repo.GetActiveByUserId(ctx, userID)
+	if err == nil {
+		return active, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	export := model.DataExport{UserID: userID}
+	if err := d.repo.Create(ctx, &export); err != nil {
+		return nil, err
+	}
+
+	// a full queue is fine, ProcessPending picks the export up later
+	_ = d.enqueue(export.ID)
+
+	return &export, nil
+}
+
+func (d *dataExportService) GetExportById(ctx context.Context, id int) (*model.DataExport, error) {
+	return d.repo.GetById(ctx, id)
+}
+
+func (d *dataExportService) GetArchiveById(ctx context.Context, id int) ([]byte, error) {
+	archive, err := d.repo.GetArchiveById(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrExportNotReady
+	}
+
+	return archive, err
+}
+
+// ProcessPending requeues exports whose build was lost and enqueues pending
+// ones, e.g. those left over from a restart or a full queue.
+func (d *dataExportService) ProcessPending(ctx context.Context) (int64, error) {
+	if _, err := d.repo.ResetStale(ctx, time.Now().Add(-dataExportTimeout)); err != nil {
+		return 0, err
+	}
+
+	ids, err := d.repo.GetPendingIds(ctx, dataExportBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	var enqueued int64
+	for _, id := range ids {
+		if err := d.enqueue(id); err != nil {
+			break
+		}
+		enqueued++
+	}
+
+	return enqueued, nil
+}
+
+func (d *dataExportService) DeleteExpired(ctx context.Context) (int64, error) {
+	return d.repo.DeleteExpired(ctx, time.Now())
+}
+
+func (d *dataExportService) enqueue(id int) error {
+	return d.runner.Enqueue(jobs.Job{
+		Name: fmt.Sprintf("data export %d", id),
+		Run: func(ctx context.Context) error {
+			return d.build(ctx, id)
+		},
+	})
+}
+
+// build claims the export, so that an export enqueued twice is only built
+// once, and stores the archive or the reason it failed.
+func (d *dataExportService) build(ctx context.Context, id int) error {
+	claimed, err := d.repo.ClaimById(ctx, id)
+	if err != nil || !claimed {
+		return err
+	}
+
+	export, err := d.repo.GetById(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, dataExportTimeout)
+	defer cancel()
+
+	archive, err := d.archive(ctx, export.UserID)
+	if err != nil {
+		if ctx.Err() != nil {
+			// shutting down; ResetStale queues it again
+			return err
+		}
+		if failErr := d.repo.FailById(context.WithoutCancel(ctx), id, "cannot build archive"); failErr != nil {
+			return failErr
+		}
+		return err
+	}
+
+	return d.repo.CompleteById(ctx, id, archive, time.Now().Add(DataExportTTL))
+}
+
+// exportedTodo is a todo with its checklist, as written to todos.json.
+type exportedTodo struct {
+	*model.Todo
+	Items []*model.ChecklistItem `json:"items"`
+}
+
+// archive collects everything stored about the user into a zip of JSON files.
+func (d *dataExportService) archive(ctx context.Context, userID int) ([]byte, error) {
+	s := d.sources
+
+	user, err := s.Users.GetById(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	todos, err := s.Todos.GetAllByUserId(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	// workspace content belongs to the team, apart from todos the user created
+	personal := model.Tenant{UserID: userID}
+	trash, err := s.Todos.GetTrash(ctx, personal)
+	if err != nil {
+		return nil, err
+	}
+
+	exportedTodos := make([]exportedTodo, 0, len(todos)+len(trash))
+	for _, todo := range append(todos, trash...) {
+		items, err := s.Items.GetAllByTodoId(ctx, todo.ID)
+		if err != nil {
+			return nil, err
+		}
+		exportedTodos = append(exportedTodos, exportedTodo{todo, items})
+	}
+
+	tags, err := s.Tags.GetAll(ctx, personal)
+	if err != nil {
+		return nil, err
+	}
+
+	projects, err := s.Projects.GetAll(ctx, personal, true)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.Tokens.GetActiveByUserId(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	identities, err := s.Identities.GetAllByUserId(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []struct {
+		name string
+		data any
+	}{
+		{"profile.json", user},
+		{"todos.json", exportedTodos},
+		{"tags.json", tags},
+		{"projects.json", projects},
+		{"personal_access_tokens.json", tokens},
+		{"identities.json", identities},
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, file := range files {
+		w, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     file.name,
+			Method:   zip.Deflate,
+			Modified: time.Now(),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(file.data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+
+	"github.com/King0625/golang-todolist/internal/model"
+	"github.com/King0625/golang-todolist/internal/repository"
+)
+
+type ChecklistItemService interface {
+	CreateItem(ctx context.Context, item *model.ChecklistItem) error
+	GetItemsByTodoId(ctx context.Context, todoID int) ([]*model.ChecklistItem, error)
+	GetItemById(ctx context.Context, id int) (*model.ChecklistItem, error)
+	UpdateItemById(ctx context.Context, id int, item *model.ChecklistItem) error
+	DeleteItemById(ctx context.Context, id int) error
+}
+
+type checklistItemService struct {
+	repo repository.ChecklistItemRepository
+}
+
+func NewChecklistItemService(r repository.ChecklistItemRepository) ChecklistItemService {
+	return &checklistItemService{r}
+}
+
+func (c *checklistItemService) CreateItem(ctx context.Context, item *model.ChecklistItem) error {
+	return c.repo.Create(ctx, item)
+}
+
+func (c *checklistItemService) GetItemsByTodoId(ctx context.Context, todoID int) ([]*model.ChecklistItem, error) {
+	return c.repo.GetAllByTodoId(ctx, todoID)
+}
+
+func (c *checklistItemService) GetItemById(ctx context.Context, id int) (*model.ChecklistItem, error) {
+	return c.repo.GetById(ctx, id)
+}
+
+func (c *checklistItemService) UpdateItemById(ctx context.Context, id int, item *model.ChecklistItem) error {
+	return c.repo.UpdateById(ctx, id, item)
+}
+
+func (c *checklistItemService) DeleteItemById(ctx context.Context, id int) error {
+	return c.repo.DeleteById(ctx, id)
+}
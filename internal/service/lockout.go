@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/King0625/golang-todolist/internal/model"
+	"github.com/King0625/golang-todolist/internal/repository"
+)
+
+var ErrAccountLocked = errors.New("too many failed login attempts")
+
+// LockedError is returned while logins are refused. RetryAfter is how long
+// until the next attempt is allowed.
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return ErrAccountLocked.Error()
+}
+
+func (e *LockedError) Unwrap() error {
+	return ErrAccountLocked
+}
+
+// LoginGuard counts failed logins per email and per client address and
+// refuses logins as LockoutPolicy says.
+type LoginGuard interface {
+	Check(ctx context.Context, email, ip string) error
+	// RecordFailure reports whether this failure locked the account.
+	RecordFailure(ctx context.Context, email, ip string) (bool, error)
+	RecordSuccess(ctx context.Context, email string) error
+	Unlock(ctx context.Context, email string) error
+	DeleteStale(ctx context.Context) (int64, error)
+}
+
+type loginGuard struct {
+	store  repository.LoginAttemptRepository
+	policy model.LockoutPolicy
+	now    func() time.Time
+}
+
+// NewLoginGuard returns a guard keeping its counters in store. now is the
+// clock the policy is applied with; nil means time.Now.
+func NewLoginGuard(store repository.LoginAttemptRepository, policy model.LockoutPolicy, now func() time.Time) LoginGuard {
+	if now == nil {
+		now = time.Now
+	}
+	return &loginGuard{store, policy, now}
+}
+
+// emails are case-insensitive in the users table, so they are here too
+func accountKey(email string) string {
+	return "email:" + strings.ToLower(email)
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+// Check returns a *LockedError while either the account or the address is
+// locked. Unknown emails are tracked like any other, so lockouts do not
+// reveal which accounts exist.
+func (g *loginGuard) Check(ctx context.Context, email, ip string) error {
+	keys := []string{accountKey(email)}
+	if ip != "" {
+		keys = append(keys, ipKey(ip))
+	}
+
+	now := g.now()
+	var retryAfter time.Duration
+	for _, key := range keys {
+		attempts, err := g.store.Get(ctx, key)
+		if err != nil {
+			return err
+		}
+		if attempts != nil && attempts.LockedUntil != nil && attempts.LockedUntil.After(now) {
+			retryAfter = max(retryAfter, attempts.LockedUntil.Sub(now))
+		}
+	}
+
+	if retryAfter > 0 {
+		return &LockedError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+func (g *loginGuard) recordFailure(ctx context.Context, key string, threshold int) (int, error) {
+	now := g.now()
+	attempts, err := g.store.RecordFailure(ctx, key, now, g.policy.Window)
+	if err != nil {
+		return 0, err
+	}
+
+	if delay := g.policy.Delay(attempts.Failures, threshold); delay > 0 {
+		if err := g.store.SetLockedUntil(ctx, key, now.Add(delay)); err != nil {
+			return 0, err
+		}
+	}
+
+	return attempts.Failures, nil
+}
+
+func (g *loginGuard) RecordFailure(ctx context.Context, email, ip string) (bool, error) {
+	if ip != "" {
+		if _, err := g.recordFailure(ctx, ipKey(ip), g.policy.IPThreshold); err != nil {
+			return false, err
+		}
+	}
+
+	failures, err := g.recordFailure(ctx, accountKey(email), g.policy.AccountThreshold)
+	if err != nil {
+		return false, err
+	}
+
+	return failures == g.policy.AccountThreshold, nil
+}
+
+// RecordSuccess clears the account's failures. Address counters are left
+// alone, or an attacker could reset theirs by logging into their own account.
+func (g *loginGuard) RecordSuccess(ctx context.Context, email string) error {
+	return g.store.Reset(ctx, accountKey(email))
+}
+
+func (g *loginGuard) Unlock(ctx context.Context, email string) error {
+	return g.store.Reset(ctx, accountKey(email))
+}
+
+func (g *loginGuard) DeleteStale(ctx context.Context) (int64, error) {
+	return g.store.DeleteStale(ctx, g.now().Add(-g.policy.Window))
+}
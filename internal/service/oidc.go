@@ -0,0 +1,202 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/King0625/golang-todolist/internal/model"
+	"github.com/King0625/golang-todolist/internal/repository"
+	"github.com/King0625/golang-todolist/pkg/oidc"
+	"github.com/King0625/golang-todolist/pkg/utils"
+)
+
+const OIDCLoginStateTTL = 10 * time.Minute
+
+var (
+	ErrUnknownOIDCProvider  = errors.New("unknown oidc provider")
+	ErrInvalidOIDCState     = errors.New("invalid or expired oidc state")
+	ErrOIDCEmailNotVerified = errors.New("the provider has not verified the email")
+	ErrOIDCLoginFailed      = errors.New("oidc login failed")
+)
+
+type OIDCService interface {
+	StartLogin(ctx context.Context, provider string) (authURL, state string, err error)
+	CompleteLogin(ctx context.Context, state, code string) (*model.User, error)
+	DeleteExpiredStates(ctx context.Context) (int64, error)
+}
+
+type oidcService struct {
+	providers  map[string]*oidc.Provider
+	states     repository.OIDCStateRepository
+	identities repository.UserIdentityRepository
+	users      repository.UserRepository
+}
+
+func NewOIDCService(providers []*oidc.Provider, states repository.OIDCStateRepository, identities repository.UserIdentityRepository, users repository.UserRepository) OIDCService {
+	byName := make(map[string]*oidc.Provider, len(providers))
+	for _, p := range providers {
+		byName[p.Name()] = p
+	}
+	return &oidcService{byName, states, identities, users}
+}
+
+// provider finds a provider by name. The name may be left out when only one
+// provider is configured.
+func (o *oidcService) provider(name string) (*oidc.Provider, error) {
+	if name == "" && len(o.providers) == 1 {
+		for _, p := range o.providers {
+			return p, nil
+		}
+	}
+
+	p, ok := o.providers[name]
+	if !ok {
+		return nil, ErrUnknownOIDCProvider
+	}
+	return p, nil
+}
+
+// StartLogin returns the provider URL to send the user to and the state the
+// callback has to present.
+func (o *oidcService) StartLogin(ctx context.Context, provider string) (string, string, error) {
+	p, err := o.provider(provider)
+	if err != nil {
+		return "", "", err
+	}
+
+	state, stateHash, err := utils.NewOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := utils.RandomString(16)
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := utils.RandomString(32)
+	if err != nil {
+		return "", "", err
+	}
+
+	authURL, err := p.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %v", ErrOIDCLoginFailed, err)
+	}
+
+	record := model.OIDCLoginState{
+		StateHash:    stateHash,
+		Provider:     p.Name(),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().Add(OIDCLoginStateTTL),
+	}
+	if err := o.states.Create(ctx, &record); err != nil {
+		return "", "", err
+	}
+
+	return authURL, state, nil
+}
+
+// CompleteLogin redeems the code of a callback and returns the user linked to
+// the provider account. Unknown accounts are linked to the user with the same
+// email, or to a new user, if the provider has verified the email.
+func (o *oidcService) CompleteLogin(ctx context.Context, state, code string) (*model.User, error) {
+	record, err := o.states.ConsumeByHash(ctx, utils.HashToken(state))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidOIDCState
+	}
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().After(record.ExpiresAt) {
+		return nil, ErrInvalidOIDCState
+	}
+
+	p, err := o.provider(record.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	rawIDToken, err := p.Exchange(ctx, code, record.CodeVerifier)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOIDCLoginFailed, err)
+	}
+
+	claims, err := p.VerifyIDToken(ctx, rawIDToken, record.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOIDCLoginFailed, err)
+	}
+
+	identity, err := o.identities.GetByProviderSubject(ctx, p.Name(), claims.Subject)
+	if err == nil {
+		return o.users.GetById(ctx, identity.UserID)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, ErrOIDCEmailNotVerified
+	}
+
+	user, err := o.users.GetByEmail(ctx, claims.Email)
+	if errors.Is(err, sql.ErrNoRows) {
+		user, err = o.createUser(ctx, claims)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if user.EmailVerifiedAt == nil {
+		if err := o.users.MarkEmailVerifiedById(ctx, user.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	err = o.identities.Create(ctx, &model.UserIdentity{
+		UserID:   user.ID,
+		Provider: p.Name(),
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return o.users.GetById(ctx, user.ID)
+}
+
+// createUser registers a user for a provider account. The random password is
+// never shown; the user can set one through the password reset.
+func (o *oidcService) createUser(ctx context.Context, claims *oidc.Claims) (*model.User, error) {
+	password, err := utils.RandomString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	firstName := claims.GivenName
+	if firstName == "" {
+		firstName = claims.Name
+	}
+
+	now := time.Now()
+	user := model.User{
+		Email:     claims.Email,
+		FirstName: firstName,
+		LastName:  claims.FamilyName,
+		Password:  password,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := o.users.Create(ctx, &user); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (o *oidcService) DeleteExpiredStates(ctx context.Context) (int64, error) {
+	return o.states.DeleteExpired(ctx, time.Now())
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/King0625/golang-todolist/internal/model"
+	"github.com/King0625/golang-todolist/internal/repository"
+	"github.com/King0625/golang-todolist/pkg/utils"
+)
+
+var ErrInvalidPersonalAccessToken = errors.New("invalid personal access token")
+
+// lastUsedResolution limits how often lastUsedAt is written for a busy token.
+const lastUsedResolution = time.Minute
+
+type PersonalAccessTokenService interface {
+	CreateToken(ctx context.Context, token *model.PersonalAccessToken) (string, error)
+	GetTokensByUserId(ctx context.Context, userID int) ([]*model.PersonalAccessToken, error)
+	GetTokenById(ctx context.Context, id int) (*model.PersonalAccessToken, error)
+	RevokeTokenById(ctx context.Context, id int) error
+	VerifyPersonalAccessToken(ctx context.Context, raw string) (*model.PersonalAccessToken, error)
+}
+
+type personalAccessTokenService struct {
+	repo repository.PersonalAccessTokenRepository
+}
+
+func NewPersonalAccessTokenService(r repository.PersonalAccessTokenRepository) PersonalAccessTokenService {
+	return &personalAccessTokenService{r}
+}
+
+// CreateToken generates the secret for token, stores its hash and returns the
+// raw token, which cannot be recovered later.
+func (p *personalAccessTokenService) CreateToken(ctx context.Context, token *model.PersonalAccessToken) (string, error) {
+	secret, err := utils.RandomString(32)
+	if err != nil {
+		return "", err
+	}
+
+	raw := model.PersonalAccessTokenPrefix + secret
+	token.TokenHash = utils.HashToken(raw)
+	token.Prefix = raw[:len(model.PersonalAccessTokenPrefix)+4]
+
+	if err := p.repo.Create(ctx, token); err != nil {
+		return "", err
+	}
+
+	return raw, nil
+}
+
+func (p *personalAccessTokenService) GetTokensByUserId(ctx context.Context, userID int) ([]*model.PersonalAccessToken, error) {
+	return p.repo.GetActiveByUserId(ctx, userID)
+}
+
+func (p *personalAccessTokenService) GetTokenById(ctx context.Context, id int) (*model.PersonalAccessToken, error) {
+	return p.repo.GetById(ctx, id)
+}
+
+func (p *personalAccessTokenService) RevokeTokenById(ctx context.Context, id int) error {
+	return p.repo.RevokeById(ctx, id)
+}
+
+// VerifyPersonalAccessToken returns the active token matching raw and records
+// its use.
+func (p *personalAccessTokenService) VerifyPersonalAccessToken(ctx context.Context, raw string) (*model.PersonalAccessToken, error) {
+	token, err := p.repo.GetByHash(ctx, utils.HashToken(raw))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidPersonalAccessToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if token.RevokedAt != nil || (token.ExpiresAt != nil && now.After(*token.ExpiresAt)) {
+		return nil, ErrInvalidPersonalAccessToken
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) > lastUsedResolution {
+		if err := p.repo.TouchById(ctx, token.ID, now); err != nil {
+			log.Printf("record personal access token use error: %v", err)
+		}
+	}
+
+	return token, nil
+}
@@ -0,0 +1,29 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// RunPeriodically calls task right away and then every interval until ctx is
+// done. task returns how many rows it affected, which is logged when non-zero.
+func RunPeriodically(ctx context.Context, name string, interval time.Duration, task func(ctx context.Context) (int64, error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		affected, err := task(ctx)
+		if err != nil {
+			log.Printf("%s error: %v", name, err)
+		} else if affected > 0 {
+			log.Printf("%s: %d rows affected", name, affected)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
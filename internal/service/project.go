@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+
+	"github.com/King0625/golang-todolist/internal/model"
+	"github.com/King0625/golang-todolist/internal/repository"
+)
+
+type ProjectService interface {
+	CreateProject(ctx context.Context, project *model.Project) error
+	GetProjects(ctx context.Context, tenant model.Tenant, includeArchived bool) ([]*model.Project, error)
+	GetProjectById(ctx context.Context, id int) (*model.Project, error)
+	UpdateProjectById(ctx context.Context, id int, project *model.Project) error
+	DeleteProjectById(ctx context.Context, id int, mode model.ProjectDeleteMode) error
+}
+
+type projectService struct {
+	repo repository.ProjectRepository
+}
+
+func NewProjectService(r repository.ProjectRepository) ProjectService {
+	return &projectService{r}
+}
+
+func (p *projectService) CreateProject(ctx context.Context, project *model.Project) error {
+	return p.repo.Create(ctx, project)
+}
+
+func (p *projectService) GetProjects(ctx context.Context, tenant model.Tenant, includeArchived bool) ([]*model.Project, error) {
+	return p.repo.GetAll(ctx, tenant, includeArchived)
+}
+
+func (p *projectService) GetProjectById(ctx context.Context, id int) (*model.Project, error) {
+	return p.repo.GetById(ctx, id)
+}
+
+func (p *projectService) UpdateProjectById(ctx context.Context, id int, project *model.Project) error {
+	return p.repo.UpdateById(ctx, id, project)
+}
+
+func (p *projectService) DeleteProjectById(ctx context.Context, id int, mode model.ProjectDeleteMode) error {
+	return p.repo.DeleteById(ctx, id, mode)
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/King0625/golang-todolist/internal/model"
+	"github.com/King0625/golang-todolist/internal/repository"
+	"github.com/King0625/golang-todolist/pkg/textdiff"
+)
+
+var ErrRevisionNotFound = errors.New("revision not found")
+
+type RevisionService interface {
+	GetRevisions(ctx context.Context, todoID int) ([]*model.TodoRevision, error)
+	DiffRevisions(ctx context.Context, todoID, from, to int) (*model.RevisionDiff, error)
+	RestoreRevision(ctx context.Context, todo *model.Todo, revision int) error
+	PurgeRevisions(ctx context.Context, retention time.Duration) (int64, error)
+}
+
+type revisionService struct {
+	repo  repository.TodoRevisionRepository
+	todos TodoService
+}
+
+func NewRevisionService(r repository.TodoRevisionRepository, todos TodoService) RevisionService {
+	return &revisionService{r, todos}
+}
+
+func (s *revisionService) GetRevisions(ctx context.Context, todoID int) ([]*model.TodoRevision, error) {
+	return s.repo.GetAllByTodoId(ctx, todoID)
+}
+
+// getRevision returns a revision of todoID, or its latest one for revision 0.
+func (s *revisionService) getRevision(ctx context.Context, todoID, revision int) (*model.TodoRevision, error) {
+	var rev *model.TodoRevision
+	var err error
+	if revision == 0 {
+		rev, err = s.repo.GetLatest(ctx, todoID)
+	} else {
+		rev, err = s.repo.GetByRevision(ctx, todoID, revision)
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRevisionNotFound
+	}
+	return rev, err
+}
+
+// DiffRevisions compares two revisions of the todo line by line. A to of 0
+// compares with the latest revision.
+func (s *revisionService) DiffRevisions(ctx context.Context, todoID, from, to int) (*model.RevisionDiff, error) {
+	fromRev, err := s.getRevision(ctx, todoID, from)
+	if err != nil {
+		return nil, err
+	}
+	toRev, err := s.getRevision(ctx, todoID, to)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := model.RevisionDiff{
+		From:    fromRev.Revision,
+		To:      toRev.Revision,
+		Content: textdiff.Lines(fromRev.Content, toRev.Content),
+	}
+	if fromRev.Title != toRev.Title {
+		diff.Title = &model.Change{From: fromRev.Title, To: toRev.Title}
+	}
+
+	return &diff, nil
+}
+
+// RestoreRevision puts the title and content of a revision back into todo.
+// This is an edit like any other, so it saves a new revision rather than
+// dropping the ones after it.
+func (s *revisionService) RestoreRevision(ctx context.Context, todo *model.Todo, revision int) error {
+	rev, err := s.getRevision(ctx, todo.ID, revision)
+	if err != nil {
+		return err
+	}
+
+	todo.Title = rev.Title
+	todo.Content = rev.Content
+
+	_, err = s.todos.UpdateTodoById(ctx, todo.ID, todo)
+	return err
+}
+
+// PurgeRevisions deletes revisions older than retention. The latest revision
+// of every todo is kept.
+func (s *revisionService) PurgeRevisions(ctx context.Context, retention time.Duration) (int64, error) {
+	return s.repo.PurgeBefore(ctx, time.Now().Add(-retention))
+}
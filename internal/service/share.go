@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/King0625/golang-todolist/internal/model"
+	"github.com/King0625/golang-todolist/internal/repository"
+	"github.com/King0625/golang-todolist/pkg/mailer"
+)
+
+var (
+	ErrCannotShareWithSelf = errors.New("cannot share with yourself")
+	ErrInvitationNotFound  = errors.New("invitation not found")
+)
+
+type ShareServiceConfig struct {
+	// BaseURL prefixes the link in invitation emails.
+	BaseURL string
+}
+
+type ShareService interface {
+	Invite(ctx context.Context, share *model.Share, title string) error
+	GetShareById(ctx context.Context, id int) (*model.Share, error)
+	GetSharesByResource(ctx context.Context, resourceType model.ShareResource, resourceID int) ([]*model.Share, error)
+	RevokeShareById(ctx context.Context, id int) error
+	GetInvitations(ctx context.Context, userID int) ([]*model.Share, error)
+	RespondToInvitation(ctx context.Context, userID, shareID int, accept bool) error
+}
+
+type shareService struct {
+	repo   repository.ShareRepository
+	users  repository.UserRepository
+	mailer mailer.Mailer
+	config ShareServiceConfig
+}
+
+func NewShareService(r repository.ShareRepository, users repository.UserRepository, m mailer.Mailer, config ShareServiceConfig) ShareService {
+	return &shareService{r, users, m, config}
+}
+
+// Invite shares the resource with share.Email and mails them an invitation.
+// Inviting an address again changes the permission of the existing share.
+func (s *shareService) Invite(ctx context.Context, share *model.Share, title string) error {
+	owner, err := s.users.GetById(ctx, share.OwnerID)
+	if err != nil {
+		return err
+	}
+
+	if strings.EqualFold(owner.Email, share.Email) {
+		return ErrCannotShareWithSelf
+	}
+
+	if err := s.repo.Upsert(ctx, share); err != nil {
+		return err
+	}
+
+	if share.Status != model.SharePending {
+		return nil
+	}
+
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      share.Email,
+		Subject: fmt.Sprintf("%s %s shared a %s with you", owner.FirstName, owner.LastName, share.ResourceType),
+		Body: fmt.Sprintf("Hi,\n\n%s %s (%s) invited you to the %s \"%s\" as %s.\n\nLog in with this email address to accept or decline the invitation:\n\n%s\n",
+			owner.FirstName, owner.LastName, owner.Email, share.ResourceType, title, share.Permission, s.config.BaseURL+"/users/me/invitations"),
+	})
+}
+
+func (s *shareService) GetShareById(ctx context.Context, id int) (*model.Share, error) {
+	return s.repo.GetById(ctx, id)
+}
+
+func (s *shareService) GetSharesByResource(ctx context.Context, resourceType model.ShareResource, resourceID int) ([]*model.Share, error) {
+	return s.repo.GetAllByResource(ctx, resourceType, resourceID)
+}
+
+func (s *shareService) RevokeShareById(ctx context.Context, id int) error {
+	return s.repo.DeleteById(ctx, id)
+}
+
+// GetInvitations lists the pending invitations to the user's email address.
+func (s *shareService) GetInvitations(ctx context.Context, userID int) ([]*model.Share, error) {
+	user, err := s.users.GetById(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetPendingByEmail(ctx, user.Email)
+}
+
+// RespondToInvitation accepts or declines an invitation sent to the user's
+// email address. Accepting requires the address to be verified, since that
+// is what proves the invitation reached its owner.
+func (s *shareService) RespondToInvitation(ctx context.Context, userID, shareID int, accept bool) error {
+	user, err := s.users.GetById(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	share, err := s.repo.GetById(ctx, shareID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrInvitationNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if share.Status != model.SharePending || !strings.EqualFold(share.Email, user.Email) {
+		return ErrInvitationNotFound
+	}
+
+	status := model.ShareDeclined
+	if accept {
+		if user.EmailVerifiedAt == nil {
+			return ErrEmailNotVerified
+		}
+		status = model.ShareAccepted
+	}
+
+	responded, err := s.repo.RespondById(ctx, shareID, userID, status)
+	if err != nil {
+		return err
+	}
+	if !responded {
+		return ErrInvitationNotFound
+	}
+
+	return nil
+}
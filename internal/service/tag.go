@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+
+	"github.com/King0625/golang-todolist/internal/model"
+	"github.com/King0625/golang-todolist/internal/repository"
+)
+
+type TagService interface {
+	CreateTag(ctx context.Context, tag *model.Tag) error
+	GetTags(ctx context.Context, tenant model.Tenant) ([]*model.Tag, error)
+	GetTagById(ctx context.Context, id int) (*model.Tag, error)
+	UpdateTagById(ctx context.Context, id int, name, color string) error
+	DeleteTagById(ctx context.Context, id int) error
+	AttachTagsToTodo(ctx context.Context, todoID int, tagIDs []int) error
+	DetachTagFromTodo(ctx context.Context, todoID, tagID int) error
+}
+
+type tagService struct {
+	repo repository.TagRepository
+}
+
+func NewTagService(r repository.TagRepository) TagService {
+	return &tagService{r}
+}
+
+func (t *tagService) CreateTag(ctx context.Context, tag *model.Tag) error {
+	return t.repo.Create(ctx, tag)
+}
+
+func (t *tagService) GetTags(ctx context.Context, tenant model.Tenant) ([]*model.Tag, error) {
+	return t.repo.GetAll(ctx, tenant)
+}
+
+func (t *tagService) GetTagById(ctx context.Context, id int) (*model.Tag, error) {
+	return t.repo.GetById(ctx, id)
+}
+
+func (t *tagService) UpdateTagById(ctx context.Context, id int, name, color string) error {
+	return t.repo.UpdateById(ctx, id, name, color)
+}
+
+func (t *tagService) DeleteTagById(ctx context.Context, id int) error {
+	return t.repo.DeleteById(ctx, id)
+}
+
+func (t *tagService) AttachTagsToTodo(ctx context.Context, todoID int, tagIDs []int) error {
+	return t.repo.AttachToTodo(ctx, todoID, tagIDs)
+}
+
+func (t *tagService) DetachTagFromTodo(ctx context.Context, todoID, tagID int) error {
+	return t.repo.DetachFromTodo(ctx, todoID, tagID)
+}
@@ -2,48 +2,484 @@ package service
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
 
+	"github.com/King0625/golang-todolist/internal/actor"
 	"github.com/King0625/golang-todolist/internal/model"
 	"github.com/King0625/golang-todolist/internal/repository"
+	"github.com/King0625/golang-todolist/pkg/recurrence"
+	"github.com/King0625/golang-todolist/pkg/search"
 )
 
 type TodoService interface {
 	CreateTodo(ctx context.Context, todo *model.Todo) error
 	GetTodosByUserId(ctx context.Context, userID int) ([]*model.Todo, error)
+	ListTodos(ctx context.Context, tenant model.Tenant, opts model.TodoQueryOptions) (*model.TodoPage, error)
+	GetOverdueTodos(ctx context.Context, tenant model.Tenant) ([]*model.Todo, error)
+	GetUpcomingTodos(ctx context.Context, tenant model.Tenant, days int) ([]*model.Todo, error)
+	SearchTodos(ctx context.Context, tenant model.Tenant, query search.Query, limit int) ([]*model.TodoSearchResult, error)
 	GetTodoById(ctx context.Context, id int) (*model.Todo, error)
-	UpdateTodoById(ctx context.Context, id int, title, content string, done bool) error
-	MarkTodoDoneById(ctx context.Context, id int) error
-	DeleteTodoById(ctx context.Context, id int) error
+	UpdateTodoById(ctx context.Context, id int, todo *model.Todo) (*model.Todo, error)
+	MoveTodoToProject(ctx context.Context, id, version int, projectID *int) error
+	MarkTodoDoneById(ctx context.Context, id, version int) (*model.Todo, error)
+	DeleteTodoById(ctx context.Context, id, version int) error
+	GetTrash(ctx context.Context, tenant model.Tenant) ([]*model.Todo, error)
+	GetTrashedTodoById(ctx context.Context, id int) (*model.Todo, error)
+	RestoreTodoById(ctx context.Context, id, version int) error
+	PurgeTodoById(ctx context.Context, id, version int) error
+	PurgeTrash(ctx context.Context, retention time.Duration) (int64, error)
 }
 
+var (
+	ErrInvalidAssignee = errors.New("assignee is not a member of the todo's workspace")
+	ErrTodoNotFound    = errors.New("todo not found")
+)
+
 type todoService struct {
-	repo repository.TodoRepository
+	repo       repository.TodoRepository
+	workspaces repository.WorkspaceRepository
+	events     repository.TodoEventRepository
+	auditLog   AuditLog
+	donePolicy model.DonePolicy
+}
+
+func NewTodoService(r repository.TodoRepository, workspaces repository.WorkspaceRepository, events repository.TodoEventRepository, auditLog AuditLog, donePolicy model.DonePolicy) TodoService {
+	if donePolicy != model.DonePolicyRefuseOpen {
+		donePolicy = model.DonePolicyCompleteItems
+	}
+	return &todoService{r, workspaces, events, auditLog, donePolicy}
+}
+
+// todoDiff returns the fields changed from before to after, leaving out those
+// that are computed or kept by the database.
+func todoDiff(before, after *model.Todo) map[string]model.Change {
+	changes, err := model.Diff(before, after, "createdAt", "updatedAt", "dueState", "progress", "etag")
+	if err != nil {
+		log.Printf("diff todo error: %v", err)
+	}
+	return changes
+}
+
+// audit records action on todoID in the audit log.
+func (t *todoService) audit(ctx context.Context, action model.AuditAction, todoID int, changes map[string]model.Change) {
+	t.auditLog.Record(ctx, &model.AuditEvent{
+		Action:     action,
+		TargetType: model.AuditTargetTodo,
+		TargetID:   &todoID,
+		Changes:    changes,
+	})
+}
+
+// record adds events to the activity feed of todoID, attributed to the user
+// ctx acts for. The change itself has already happened, so failures are only
+// logged.
+func (t *todoService) record(ctx context.Context, todoID int, events ...*model.TodoEvent) {
+	var userID *int
+	if id, ok := actor.UserID(ctx); ok {
+		userID = &id
+	}
+
+	for _, event := range events {
+		event.TodoID = todoID
+		event.UserID = userID
+	}
+
+	if err := t.events.Create(ctx, events...); err != nil {
+		log.Printf("record todo events error: %v", err)
+	}
+}
+
+func formatDue(todo *model.Todo) string {
+	if todo.DueDate == "" {
+		return ""
+	}
+
+	due := todo.DueDate
+	if todo.DueTime != "" {
+		due += " " + todo.DueTime
+	}
+	return due + " " + todo.Timezone
+}
+
+func formatID(id *int) string {
+	if id == nil {
+		return ""
+	}
+	return strconv.Itoa(*id)
+}
+
+// todoChanges describes how todo differs from old as events.
+func todoChanges(old, todo *model.Todo) []*model.TodoEvent {
+	var events []*model.TodoEvent
+	changed := func(eventType model.TodoEventType, from, to string) {
+		if from != to {
+			events = append(events, &model.TodoEvent{Type: eventType, From: from, To: to})
+		}
+	}
+
+	changed(model.EventTitleChanged, old.Title, todo.Title)
+	if old.Content != todo.Content {
+		// contents can be long; the feed only notes that it changed
+		events = append(events, &model.TodoEvent{Type: model.EventContentChanged})
+	}
+	if old.Done != todo.Done {
+		eventType := model.EventReopened
+		if todo.Done {
+			eventType = model.EventMarkedDone
+		}
+		events = append(events, &model.TodoEvent{Type: eventType})
+	}
+	changed(model.EventDueChanged, formatDue(old), formatDue(todo))
+	changed(model.EventPriorityChanged, old.Priority.String(), todo.Priority.String())
+	changed(model.EventRecurrenceChanged, old.Recurrence, todo.Recurrence)
+	changed(model.EventProjectChanged, formatID(old.ProjectID), formatID(todo.ProjectID))
+	changed(model.EventAssigneeChanged, formatID(old.AssigneeID), formatID(todo.AssigneeID))
+
+	return events
 }
 
-func NewTodoService(r repository.TodoRepository) TodoService {
-	return &todoService{r}
+func computeDueStates(todos []*model.Todo) {
+	now := time.Now()
+	for _, todo := range todos {
+		todo.ComputeDueState(now)
+	}
+}
+
+// checkAssignee makes sure todos are only assigned to members of their
+// workspace. Personal todos cannot be assigned.
+func (t *todoService) checkAssignee(ctx context.Context, todo *model.Todo) error {
+	if todo.AssigneeID == nil {
+		return nil
+	}
+	if todo.WorkspaceID == nil {
+		return ErrInvalidAssignee
+	}
+
+	role, err := t.workspaces.GetMemberRole(ctx, *todo.WorkspaceID, *todo.AssigneeID)
+	if err != nil {
+		return err
+	}
+	if role == "" {
+		return ErrInvalidAssignee
+	}
+
+	return nil
 }
 
 func (t *todoService) CreateTodo(ctx context.Context, todo *model.Todo) error {
-	return t.repo.Create(ctx, todo)
+	if err := t.checkAssignee(ctx, todo); err != nil {
+		return err
+	}
+
+	if err := t.repo.Create(ctx, todo); err != nil {
+		return err
+	}
+
+	t.record(ctx, todo.ID, &model.TodoEvent{Type: model.EventCreated})
+	t.audit(ctx, model.AuditTodoCreated, todo.ID, todoDiff(nil, todo))
+	return nil
 }
 
 func (t *todoService) GetTodosByUserId(ctx context.Context, userID int) ([]*model.Todo, error) {
-	return t.repo.GetAllByUserId(ctx, userID)
+	todos, err := t.repo.GetAllByUserId(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	computeDueStates(todos)
+	return todos, nil
+}
+
+func (t *todoService) ListTodos(ctx context.Context, tenant model.Tenant, opts model.TodoQueryOptions) (*model.TodoPage, error) {
+	page, err := t.repo.List(ctx, tenant, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	computeDueStates(page.Todos)
+	return page, nil
+}
+
+func (t *todoService) GetOverdueTodos(ctx context.Context, tenant model.Tenant) ([]*model.Todo, error) {
+	todos, err := t.repo.ListOverdue(ctx, tenant, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	computeDueStates(todos)
+	return todos, nil
+}
+
+func (t *todoService) GetUpcomingTodos(ctx context.Context, tenant model.Tenant, days int) ([]*model.Todo, error) {
+	now := time.Now()
+	todos, err := t.repo.ListDueBetween(ctx, tenant, now, now.AddDate(0, 0, days))
+	if err != nil {
+		return nil, err
+	}
+
+	computeDueStates(todos)
+	return todos, nil
+}
+
+const snippetLength = 160
+
+func (t *todoService) SearchTodos(ctx context.Context, tenant model.Tenant, query search.Query, limit int) ([]*model.TodoSearchResult, error) {
+	results, err := t.repo.Search(ctx, tenant, query, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for _, result := range results {
+		result.ComputeDueState(now)
+		result.TitleSnippet = query.Snippet(result.Title, snippetLength)
+		result.ContentSnippet = query.Snippet(result.Content, snippetLength)
+	}
+
+	return results, nil
 }
 
 func (t *todoService) GetTodoById(ctx context.Context, id int) (*model.Todo, error) {
-	return t.repo.GetById(ctx, id)
+	todo, err := t.repo.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	todo.ComputeDueState(time.Now())
+	return todo, nil
 }
 
-func (t *todoService) UpdateTodoById(ctx context.Context, id int, title, content string, done bool) error {
-	return t.repo.UpdateById(ctx, id, title, content, done)
+// UpdateTodoById saves the changes to todo. Completing the todo this way
+// goes through the DonePolicy and recurrence like MarkTodoDoneById, in the
+// same transaction as the other changes, and returns the next occurrence it
+// creates.
+func (t *todoService) UpdateTodoById(ctx context.Context, id int, todo *model.Todo) (*model.Todo, error) {
+	if err := t.checkAssignee(ctx, todo); err != nil {
+		return nil, err
+	}
+
+	old, err := t.repo.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// the next occurrence follows the updated todo
+	completes := todo.Done && !old.Done
+	var next *model.Todo
+	if completes && todo.Recurrence != "" {
+		if next, err = nextOccurrence(todo); err != nil {
+			return nil, err
+		}
+	}
+
+	var editorID *int
+	if userID, ok := actor.UserID(ctx); ok {
+		editorID = &userID
+	}
+
+	err = t.repo.UpdateById(ctx, id, todo, editorID, t.donePolicy, next)
+	if errors.Is(err, sql.ErrNoRows) {
+		// trashed since it was read
+		return nil, ErrTodoNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// updates that change nothing are neither saved nor recorded; the
+	// completion is recorded on its own, as by MarkTodoDoneById
+	edited := *todo
+	edited.Done = old.Done
+	if changes := todoDiff(old, &edited); len(changes) > 0 {
+		t.record(ctx, id, todoChanges(old, &edited)...)
+		t.audit(ctx, model.AuditTodoUpdated, id, changes)
+	}
+
+	if !completes {
+		return nil, nil
+	}
+	return t.recordDone(ctx, todo, next), nil
 }
 
-func (t *todoService) MarkTodoDoneById(ctx context.Context, id int) error {
-	return t.repo.MarkDoneById(ctx, id)
+// MoveTodoToProject moves the todo into projectID. It returns
+// repository.ErrVersionConflict unless the todo is still at version.
+func (t *todoService) MoveTodoToProject(ctx context.Context, id, version int, projectID *int) error {
+	old, err := t.repo.GetById(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := t.repo.MoveToProjectById(ctx, id, version, projectID); err != nil {
+		return err
+	}
+
+	if from, to := formatID(old.ProjectID), formatID(projectID); from != to {
+		t.record(ctx, id, &model.TodoEvent{Type: model.EventProjectChanged, From: from, To: to})
+	}
+	t.audit(ctx, model.AuditTodoMoved, id, map[string]model.Change{
+		"projectId": {From: old.ProjectID, To: projectID},
+	})
+	return nil
 }
 
-func (t *todoService) DeleteTodoById(ctx context.Context, id int) error {
-	return t.repo.DeleteById(ctx, id)
+// MarkTodoDoneById applies the configured DonePolicy to the open checklist
+// items of the todo while marking it done. Completing a recurring todo
+// creates and returns its next occurrence, or nil once the series has ended.
+// It returns repository.ErrVersionConflict unless the todo is still at
+// version, and repository.ErrOpenChecklistItems when the DonePolicy refuses
+// to complete it.
+func (t *todoService) MarkTodoDoneById(ctx context.Context, id, version int) (*model.Todo, error) {
+	todo, err := t.repo.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if todo.Version != version {
+		return nil, repository.ErrVersionConflict
+	}
+	if todo.Done {
+		return nil, nil
+	}
+
+	var next *model.Todo
+	if todo.Recurrence != "" {
+		if next, err = nextOccurrence(todo); err != nil {
+			return nil, err
+		}
+	}
+
+	// only the call that actually completes the todo spawns its successor
+	if err := t.repo.MarkDoneById(ctx, todo.ID, todo.Version, t.donePolicy, next); err != nil {
+		return nil, err
+	}
+	todo.Done = true
+	todo.SetVersion(todo.Version + 1)
+
+	return t.recordDone(ctx, todo, next), nil
+}
+
+// recordDone records the completion of todo and the creation of next, its
+// following occurrence, if any, and returns next.
+func (t *todoService) recordDone(ctx context.Context, todo, next *model.Todo) *model.Todo {
+	t.record(ctx, todo.ID, &model.TodoEvent{Type: model.EventMarkedDone})
+	t.audit(ctx, model.AuditTodoDone, todo.ID, map[string]model.Change{
+		"done": {From: false, To: true},
+	})
+
+	if next == nil {
+		return nil
+	}
+
+	t.record(ctx, next.ID, &model.TodoEvent{Type: model.EventCreated})
+	t.audit(ctx, model.AuditTodoCreated, next.ID, todoDiff(nil, next))
+
+	next.ComputeDueState(time.Now())
+	return next
+}
+
+// nextOccurrence builds the todo following todo in its recurrence series. It
+// returns nil when the series has ended.
+func nextOccurrence(todo *model.Todo) (*model.Todo, error) {
+	rule, err := recurrence.Parse(todo.Recurrence)
+	if err != nil {
+		return nil, err
+	}
+
+	anchor, ok := todo.DueAnchor()
+	if !ok || todo.RecurrenceStart == nil {
+		return nil, errors.New("recurring todo without due date")
+	}
+
+	occurrence, ok := rule.Next(todo.RecurrenceStart.In(anchor.Location()), anchor)
+	if !ok {
+		return nil, nil
+	}
+
+	next := &model.Todo{
+		UserID:          todo.UserID,
+		WorkspaceID:     todo.WorkspaceID,
+		AssigneeID:      todo.AssigneeID,
+		ProjectID:       todo.ProjectID,
+		Title:           todo.Title,
+		Content:         todo.Content,
+		Priority:        todo.Priority,
+		Recurrence:      todo.Recurrence,
+		RecurrenceStart: todo.RecurrenceStart,
+		Tags:            todo.Tags,
+	}
+
+	clock := ""
+	if todo.DueTime != "" {
+		clock = occurrence.Format(model.DueTimeLayout)
+	}
+	if err := next.SetDue(occurrence.Format(model.DueDateLayout), clock, todo.Timezone); err != nil {
+		return nil, err
+	}
+
+	return next, nil
+}
+
+// DeleteTodoById moves the todo to the trash. It returns
+// repository.ErrVersionConflict unless the todo is still at version.
+func (t *todoService) DeleteTodoById(ctx context.Context, id, version int) error {
+	if err := t.repo.DeleteById(ctx, id, version); err != nil {
+		return err
+	}
+
+	t.record(ctx, id, &model.TodoEvent{Type: model.EventTrashed})
+	t.audit(ctx, model.AuditTodoDeleted, id, nil)
+	return nil
+}
+
+func (t *todoService) GetTrash(ctx context.Context, tenant model.Tenant) ([]*model.Todo, error) {
+	return t.repo.GetTrash(ctx, tenant)
+}
+
+func (t *todoService) GetTrashedTodoById(ctx context.Context, id int) (*model.Todo, error) {
+	return t.repo.GetTrashedById(ctx, id)
+}
+
+// RestoreTodoById takes the todo out of the trash. It returns
+// repository.ErrVersionConflict unless the todo is still in the trash at
+// version.
+func (t *todoService) RestoreTodoById(ctx context.Context, id, version int) error {
+	if err := t.repo.RestoreById(ctx, id, version); err != nil {
+		return err
+	}
+
+	t.record(ctx, id, &model.TodoEvent{Type: model.EventRestored})
+	t.audit(ctx, model.AuditTodoRestored, id, nil)
+	return nil
+}
+
+// PurgeTodoById permanently deletes the trashed todo. It returns
+// repository.ErrVersionConflict unless the todo is still in the trash at
+// version.
+func (t *todoService) PurgeTodoById(ctx context.Context, id, version int) error {
+	if err := t.repo.PurgeById(ctx, id, version); err != nil {
+		return err
+	}
+
+	t.audit(ctx, model.AuditTodoPurged, id, nil)
+	return nil
+}
+
+// PurgeTrash permanently deletes todos that have been in the trash for longer
+// than retention.
+func (t *todoService) PurgeTrash(ctx context.Context, retention time.Duration) (int64, error) {
+	purged, err := t.repo.PurgeDeletedBefore(ctx, time.Now().Add(-retention))
+	if err != nil || purged == 0 {
+		return purged, err
+	}
+
+	t.auditLog.Record(ctx, &model.AuditEvent{
+		Action:     model.AuditTodoPurged,
+		TargetType: model.AuditTargetTodo,
+		Detail:     fmt.Sprintf("%d todos purged from the trash after %s", purged, retention),
+	})
+	return purged, nil
 }
@@ -0,0 +1,290 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/King0625/golang-todolist/internal/model"
+	"github.com/King0625/golang-todolist/internal/repository"
+	"github.com/King0625/golang-todolist/pkg/totp"
+	"github.com/King0625/golang-todolist/pkg/utils"
+)
+
+const (
+	TwoFactorChallengeTTL = 5 * time.Minute
+	recoveryCodeCount     = 10
+	// totpSkew accepts codes one period early or late for clock drift.
+	totpSkew = 1
+)
+
+var (
+	ErrTwoFactorAlreadyEnabled   = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled       = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorSetupMissing     = errors.New("two-factor setup has not been started")
+	ErrInvalidTwoFactorCode      = errors.New("invalid two-factor code")
+	ErrInvalidTwoFactorChallenge = errors.New("invalid or expired two-factor challenge")
+)
+
+type TwoFactorService interface {
+	BeginSetup(ctx context.Context, userID int) (*model.TwoFactorSetup, error)
+	ConfirmSetup(ctx context.Context, userID int, code string) ([]string, error)
+	Disable(ctx context.Context, userID int, password, code string) error
+	RegenerateRecoveryCodes(ctx context.Context, userID int, code string) ([]string, error)
+	StartLogin(ctx context.Context, user *model.User) (string, error)
+	CompleteLogin(ctx context.Context, challenge, code, ip string) (*model.User, error)
+}
+
+type TwoFactorConfig struct {
+	// Issuer names the app in authenticator apps, "golang-todolist" by default.
+	Issuer string
+	// Now is the clock codes and challenges are checked against. It defaults
+	// to time.Now and is only replaced in tests.
+	Now func() time.Time
+}
+
+type twoFactorService struct {
+	users         repository.UserRepository
+	recoveryCodes repository.RecoveryCodeRepository
+	userTokens    repository.UserTokenRepository
+	guard         LoginGuard
+	config        TwoFactorConfig
+}
+
+func NewTwoFactorService(users repository.UserRepository, recoveryCodes repository.RecoveryCodeRepository, userTokens repository.UserTokenRepository, guard LoginGuard, config TwoFactorConfig) TwoFactorService {
+	if config.Issuer == "" {
+		config.Issuer = "golang-todolist"
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	return &twoFactorService{users, recoveryCodes, userTokens, guard, config}
+}
+
+// BeginSetup stores a new pending secret for the user. It replaces any
+// earlier pending one, but never the secret of an enabled setup.
+func (t *twoFactorService) BeginSetup(ctx context.Context, userID int) (*model.TwoFactorSetup, error) {
+	user, err := t.users.GetById(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled() {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := t.users.SetTOTPSecretById(ctx, userID, secret); err != nil {
+		return nil, err
+	}
+
+	return &model.TwoFactorSetup{
+		Secret:     secret,
+		OTPAuthURI: totp.URI(t.config.Issuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmSetup enables 2FA once the user proves their app produces valid
+// codes, and returns the first set of recovery codes.
+func (t *twoFactorService) ConfirmSetup(ctx context.Context, userID int, code string) ([]string, error) {
+	user, err := t.users.GetById(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled() {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrTwoFactorSetupMissing
+	}
+
+	step, ok := totp.Validate(user.TOTPSecret, normalizeCode(code), t.config.Now(), totpSkew)
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	if err := t.users.EnableTOTPById(ctx, userID, step); err != nil {
+		return nil, err
+	}
+
+	return t.replaceRecoveryCodes(ctx, userID)
+}
+
+func (t *twoFactorService) Disable(ctx context.Context, userID int, password, code string) error {
+	user, err := t.users.GetById(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.TwoFactorEnabled() {
+		return ErrTwoFactorNotEnabled
+	}
+	if !comparePassword(user.Password, password) {
+		return ErrWrongPassword
+	}
+
+	if err := t.verifyCode(ctx, user, code); err != nil {
+		return err
+	}
+
+	if err := t.users.DisableTOTPById(ctx, userID); err != nil {
+		return err
+	}
+
+	return t.recoveryCodes.DeleteByUserId(ctx, userID)
+}
+
+func (t *twoFactorService) RegenerateRecoveryCodes(ctx context.Context, userID int, code string) ([]string, error) {
+	user, err := t.users.GetById(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TwoFactorEnabled() {
+		return nil, ErrTwoFactorNotEnabled
+	}
+
+	if err := t.verifyCode(ctx, user, code); err != nil {
+		return nil, err
+	}
+
+	return t.replaceRecoveryCodes(ctx, userID)
+}
+
+// StartLogin returns the challenge token a user whose password checked out
+// exchanges, together with a code, for a session in CompleteLogin.
+func (t *twoFactorService) StartLogin(ctx context.Context, user *model.User) (string, error) {
+	token, hash, err := utils.NewOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	record := model.UserToken{
+		UserID:    user.ID,
+		Purpose:   model.TokenPurposeTwoFactorLogin,
+		TokenHash: hash,
+		ExpiresAt: t.config.Now().Add(TwoFactorChallengeTTL),
+	}
+	if err := t.userTokens.Create(ctx, &record); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// CompleteLogin checks code for the user of challenge. A wrong code leaves the
+// challenge valid so the user can try again until it expires, but counts as a
+// failed login of the account.
+func (t *twoFactorService) CompleteLogin(ctx context.Context, challenge, code, ip string) (*model.User, error) {
+	record, err := t.userTokens.GetByHash(ctx, model.TokenPurposeTwoFactorLogin, utils.HashToken(challenge))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidTwoFactorChallenge
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if record.UsedAt != nil || t.config.Now().After(record.ExpiresAt) {
+		return nil, ErrInvalidTwoFactorChallenge
+	}
+
+	user, err := t.users.GetById(ctx, record.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2FA was turned off since the challenge was issued; the password was
+	// checked already, so the code is moot
+	if user.TwoFactorEnabled() {
+		if err := t.guard.Check(ctx, user.Email, ip); err != nil {
+			return nil, err
+		}
+
+		err := t.verifyCode(ctx, user, code)
+		if errors.Is(err, ErrInvalidTwoFactorCode) {
+			if _, err := t.guard.RecordFailure(ctx, user.Email, ip); err != nil {
+				log.Printf("record failed login error: %v", err)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	used, err := t.userTokens.MarkUsedById(ctx, record.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !used {
+		return nil, ErrInvalidTwoFactorChallenge
+	}
+
+	return user, nil
+}
+
+// verifyCode accepts either a current TOTP code or an unused recovery code,
+// and uses it up.
+func (t *twoFactorService) verifyCode(ctx context.Context, user *model.User, code string) error {
+	code = normalizeCode(code)
+
+	if len(code) == totp.Digits {
+		step, ok := totp.Validate(user.TOTPSecret, code, t.config.Now(), totpSkew)
+		if !ok {
+			return ErrInvalidTwoFactorCode
+		}
+
+		fresh, err := t.users.UseTOTPStepById(ctx, user.ID, step)
+		if err != nil {
+			return err
+		}
+		if !fresh {
+			return ErrInvalidTwoFactorCode
+		}
+
+		return nil
+	}
+
+	used, err := t.recoveryCodes.UseByHash(ctx, user.ID, utils.HashToken(code))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidTwoFactorCode
+	}
+
+	return nil
+}
+
+// replaceRecoveryCodes generates a new set of recovery codes, formatted
+// xxxx-xxxx-xxxx-xxxx, invalidating the old ones.
+func (t *twoFactorService) replaceRecoveryCodes(ctx context.Context, userID int) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+
+		code := strings.ToLower(base32.StdEncoding.EncodeToString(b))
+		codes[i] = code[:4] + "-" + code[4:8] + "-" + code[8:12] + "-" + code[12:]
+		hashes[i] = utils.HashToken(code)
+	}
+
+	if err := t.recoveryCodes.ReplaceByUserId(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// normalizeCode drops the separators users type or paste along with codes.
+func normalizeCode(code string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "-", "").Replace(code))
+}
@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/King0625/golang-todolist/internal/model"
+	"github.com/King0625/golang-todolist/internal/repository"
+	"github.com/King0625/golang-todolist/pkg/totp"
+)
+
+// testSecret is the key of the RFC 6238 test vectors in base32.
+const testSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// The fakes embed the repository interfaces and implement only what the
+// two-factor service calls; anything else panics.
+
+type fakeUsers struct {
+	repository.UserRepository
+	user *model.User
+}
+
+func (f *fakeUsers) GetById(ctx context.Context, id int) (*model.User, error) {
+	user := *f.user
+	return &user, nil
+}
+
+func (f *fakeUsers) UseTOTPStepById(ctx context.Context, id int, step int64) (bool, error) {
+	if step <= f.user.TOTPLastStep {
+		return false, nil
+	}
+	f.user.TOTPLastStep = step
+	return true, nil
+}
+
+type fakeRecoveryCodes struct {
+	repository.RecoveryCodeRepository
+}
+
+func (f *fakeRecoveryCodes) UseByHash(ctx context.Context, userID int, hash string) (bool, error) {
+	return false, nil
+}
+
+type fakeUserTokens struct {
+	repository.UserTokenRepository
+	tokens []*model.UserToken
+}
+
+func (f *fakeUserTokens) Create(ctx context.Context, token *model.UserToken) error {
+	token.ID = len(f.tokens) + 1
+	f.tokens = append(f.tokens, token)
+	return nil
+}
+
+func (f *fakeUserTokens) GetByHash(ctx context.Context, purpose model.TokenPurpose, hash string) (*model.UserToken, error) {
+	for _, token := range f.tokens {
+		if token.Purpose == purpose && token.TokenHash == hash {
+			record := *token
+			return &record, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeUserTokens) MarkUsedById(ctx context.Context, id int) (bool, error) {
+	token := f.tokens[id-1]
+	if token.UsedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	token.UsedAt = &now
+	return true, nil
+}
+
+type fakeGuard struct {
+	LoginGuard
+	failures int
+}
+
+func (f *fakeGuard) Check(ctx context.Context, email, ip string) error {
+	return nil
+}
+
+func (f *fakeGuard) RecordFailure(ctx context.Context, email, ip string) (bool, error) {
+	f.failures++
+	return false, nil
+}
+
+// fakeClock is a clock tests move by hand.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func newTestTwoFactorService(clock *fakeClock) (*twoFactorService, *fakeUsers, *fakeGuard) {
+	enabledAt := clock.now.Add(-time.Hour)
+	users := &fakeUsers{user: &model.User{
+		ID:                 1,
+		Email:              "user@example.com",
+		TOTPSecret:         testSecret,
+		TwoFactorEnabledAt: &enabledAt,
+	}}
+	guard := &fakeGuard{}
+
+	s := NewTwoFactorService(users, &fakeRecoveryCodes{}, &fakeUserTokens{}, guard, TwoFactorConfig{Now: clock.Now})
+	return s.(*twoFactorService), users, guard
+}
+
+func code(t *testing.T, at time.Time) string {
+	t.Helper()
+	c, err := totp.Code(testSecret, at)
+	if err != nil {
+		t.Fatalf("totp.Code: %v", err)
+	}
+	return c
+}
+
+func TestVerifyCodeSkew(t *testing.T) {
+	tests := []struct {
+		name   string
+		offset int
+		ok     bool
+	}{
+		{"two periods early", -2, false},
+		{"one period early", -1, true},
+		{"current period", 0, true},
+		{"one period late", 1, true},
+		{"two periods late", 2, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := &fakeClock{time.Unix(1234567890, 0)}
+			s, users, _ := newTestTwoFactorService(clock)
+
+			at := clock.now.Add(time.Duration(tt.offset) * totp.Period)
+			err := s.verifyCode(context.Background(), users.user, code(t, at))
+
+			if tt.ok && err != nil {
+				t.Fatalf("verifyCode: %v", err)
+			}
+			if !tt.ok && !errors.Is(err, ErrInvalidTwoFactorCode) {
+				t.Fatalf("verifyCode error = %v, want ErrInvalidTwoFactorCode", err)
+			}
+			if tt.ok && users.user.TOTPLastStep != totp.Step(at) {
+				t.Errorf("last step = %d, want %d", users.user.TOTPLastStep, totp.Step(at))
+			}
+		})
+	}
+}
+
+func TestVerifyCodeRejectsReplay(t *testing.T) {
+	clock := &fakeClock{time.Unix(1234567890, 0)}
+	s, users, _ := newTestTwoFactorService(clock)
+	ctx := context.Background()
+
+	current := code(t, clock.now)
+	if err := s.verifyCode(ctx, users.user, current); err != nil {
+		t.Fatalf("first use: %v", err)
+	}
+	if err := s.verifyCode(ctx, users.user, current); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("replay in the same step: error = %v, want ErrInvalidTwoFactorCode", err)
+	}
+
+	// a code of an earlier step is still within the skew, but older than the
+	// one used
+	earlier := code(t, clock.now.Add(-totp.Period))
+	if err := s.verifyCode(ctx, users.user, earlier); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("code of an earlier step: error = %v, want ErrInvalidTwoFactorCode", err)
+	}
+
+	clock.now = clock.now.Add(totp.Period)
+	if err := s.verifyCode(ctx, users.user, code(t, clock.now)); err != nil {
+		t.Errorf("code of the next step: %v", err)
+	}
+}
+
+func TestCompleteLoginChallengeExpiry(t *testing.T) {
+	tests := []struct {
+		name    string
+		elapsed time.Duration
+		wantErr error
+	}{
+		{"fresh challenge", 0, nil},
+		{"at the expiry time", TwoFactorChallengeTTL, nil},
+		{"expired challenge", TwoFactorChallengeTTL + time.Second, ErrInvalidTwoFactorChallenge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := &fakeClock{time.Unix(1234567890, 0)}
+			s, users, guard := newTestTwoFactorService(clock)
+			ctx := context.Background()
+
+			challenge, err := s.StartLogin(ctx, users.user)
+			if err != nil {
+				t.Fatalf("StartLogin: %v", err)
+			}
+
+			clock.now = clock.now.Add(tt.elapsed)
+			user, err := s.CompleteLogin(ctx, challenge, code(t, clock.now), "127.0.0.1")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CompleteLogin error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && user.ID != users.user.ID {
+				t.Errorf("CompleteLogin user = %d, want %d", user.ID, users.user.ID)
+			}
+			if guard.failures != 0 {
+				t.Errorf("recorded %d failed logins, want none", guard.failures)
+			}
+		})
+	}
+}
+
+func TestCompleteLoginChallengeIsSingleUse(t *testing.T) {
+	clock := &fakeClock{time.Unix(1234567890, 0)}
+	s, users, guard := newTestTwoFactorService(clock)
+	ctx := context.Background()
+
+	challenge, err := s.StartLogin(ctx, users.user)
+	if err != nil {
+		t.Fatalf("StartLogin: %v", err)
+	}
+
+	// a wrong code keeps the challenge and counts as a failed login
+	if _, err := s.CompleteLogin(ctx, challenge, "000000", "127.0.0.1"); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Fatalf("wrong code: error = %v, want ErrInvalidTwoFactorCode", err)
+	}
+	if guard.failures != 1 {
+		t.Errorf("recorded %d failed logins, want 1", guard.failures)
+	}
+
+	if _, err := s.CompleteLogin(ctx, challenge, code(t, clock.now), "127.0.0.1"); err != nil {
+		t.Fatalf("CompleteLogin: %v", err)
+	}
+
+	clock.now = clock.now.Add(totp.Period)
+	if _, err := s.CompleteLogin(ctx, challenge, code(t, clock.now), "127.0.0.1"); !errors.Is(err, ErrInvalidTwoFactorChallenge) {
+		t.Errorf("reused challenge: error = %v, want ErrInvalidTwoFactorChallenge", err)
+	}
+}
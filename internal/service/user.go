@@ -2,10 +2,18 @@ package service
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/King0625/golang-todolist/internal/model"
 	"github.com/King0625/golang-todolist/internal/repository"
+	"github.com/King0625/golang-todolist/pkg/mailer"
+	"github.com/King0625/golang-todolist/pkg/utils"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -14,26 +22,104 @@ func comparePassword(hash, password string) bool {
 	return err == nil
 }
 
+const (
+	passwordResetTTL     = time.Hour
+	emailVerificationTTL = 48 * time.Hour
+	accountUnlockTTL     = 24 * time.Hour
+)
+
+var (
+	ErrInvalidUserToken = errors.New("invalid or expired token")
+	ErrEmailNotVerified = errors.New("email not verified")
+	ErrWrongPassword    = errors.New("wrong password")
+	ErrEmailTaken       = errors.New("email already in use")
+)
+
 type UserService interface {
 	Register(ctx context.Context, user *model.User) error
-	Login(ctx context.Context, email, password string) (*model.User, error)
+	Login(ctx context.Context, email, password, ip string) (*model.User, error)
 	GetUserDataById(ctx context.Context, id int) (*model.User, error)
+	RequestPasswordReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, password string) error
+	SendVerificationEmail(ctx context.Context, user *model.User) error
+	VerifyEmail(ctx context.Context, token string) error
+	UnlockAccount(ctx context.Context, token string) error
+	UpdateProfile(ctx context.Context, userID int, firstName, lastName *string) error
+	RequestEmailChange(ctx context.Context, userID int, email string) error
+	ConfirmEmailChange(ctx context.Context, token string) error
+	ChangePassword(ctx context.Context, userID int, currentPassword, newPassword, ip string) error
+	DeleteAccount(ctx context.Context, userID int, password, ip string) error
+	DeleteExpiredTokens(ctx context.Context) (int64, error)
+	TokenGeneration(ctx context.Context, userID int) (int, error)
+}
+
+type UserServiceConfig struct {
+	// BaseURL is the public address of the app. Emails link to /users/verify
+	// and to the /reset-password page below it.
+	BaseURL      string
+	Verification model.VerificationPolicy
 }
 
 type userService struct {
-	repo repository.UserRepository
+	repo      repository.UserRepository
+	tokenRepo repository.UserTokenRepository
+	sessions  repository.TokenRepository
+	mailer    mailer.Mailer
+	guard     LoginGuard
+	auditLog  AuditLog
+	config    UserServiceConfig
 }
 
-func NewUserService(r repository.UserRepository) UserService {
-	return &userService{r}
+func NewUserService(r repository.UserRepository, tokenRepo repository.UserTokenRepository, sessions repository.TokenRepository, m mailer.Mailer, guard LoginGuard, auditLog AuditLog, config UserServiceConfig) UserService {
+	return &userService{r, tokenRepo, sessions, m, guard, auditLog, config}
+}
+
+// audit records a security event about userID, or about an unknown account
+// when userID is 0.
+func (u *userService) audit(ctx context.Context, action model.AuditAction, userID int, detail string, changes map[string]model.Change) {
+	event := model.AuditEvent{
+		Action:     action,
+		TargetType: model.AuditTargetUser,
+		Detail:     detail,
+		Changes:    changes,
+	}
+	if userID != 0 {
+		event.TargetID = &userID
+	}
+
+	u.auditLog.Record(ctx, &event)
 }
 
 func (u *userService) Register(ctx context.Context, user *model.User) error {
-	return u.repo.Create(ctx, user)
+	if err := u.repo.Create(ctx, user); err != nil {
+		return err
+	}
+
+	// the account exists either way; the user can ask for another email
+	if err := u.SendVerificationEmail(ctx, user); err != nil {
+		log.Printf("send verification email error: %v", err)
+	}
+
+	return nil
 }
 
-func (u *userService) Login(ctx context.Context, email, password string) (*model.User, error) {
+// Login checks the password of a user logging in from ip. It returns a
+// *LockedError while too many recent attempts have failed.
+func (u *userService) Login(ctx context.Context, email, password, ip string) (*model.User, error) {
+	if err := u.guard.Check(ctx, email, ip); err != nil {
+		var locked *LockedError
+		if errors.As(err, &locked) {
+			u.audit(ctx, model.AuditLoginFailed, 0, "locked out: "+email, nil)
+		}
+		return nil, err
+	}
+
 	user, err := u.repo.GetByEmail(ctx, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		u.audit(ctx, model.AuditLoginFailed, 0, "unknown email: "+email, nil)
+		u.recordLoginFailure(ctx, email, ip, nil)
+		return nil, err
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -42,12 +128,371 @@ func (u *userService) Login(ctx context.Context, email, password string) (*model
 	pwdMatch := comparePassword(hashedPassword, password)
 
 	if !pwdMatch {
-		return nil, errors.New("wrong password")
+		u.audit(ctx, model.AuditLoginFailed, user.ID, "wrong password", nil)
+		u.recordLoginFailure(ctx, email, ip, user)
+		return nil, ErrWrongPassword
+	}
+
+	policy := u.config.Verification
+	if user.EmailVerifiedAt == nil && policy.BlockLogin && time.Since(user.CreatedAt) > policy.GracePeriod {
+		u.audit(ctx, model.AuditLoginFailed, user.ID, "email not verified", nil)
+		return nil, ErrEmailNotVerified
 	}
 
+	if err := u.guard.RecordSuccess(ctx, email); err != nil {
+		log.Printf("reset failed logins error: %v", err)
+	}
+
+	detail := "password"
+	if user.TwoFactorEnabled() {
+		detail = "password; second factor pending"
+	}
+	u.audit(ctx, model.AuditLoginSucceeded, user.ID, detail, nil)
+
 	return user, nil
 }
 
+// recordLoginFailure counts a failed login, mailing the user an unlock link
+// when it locks their account. Errors are only logged; the login failed
+// either way.
+func (u *userService) recordLoginFailure(ctx context.Context, email, ip string, user *model.User) {
+	locked, err := u.guard.RecordFailure(ctx, email, ip)
+	if err != nil {
+		log.Printf("record failed login error: %v", err)
+		return
+	}
+
+	if locked && user != nil {
+		if err := u.sendUnlockEmail(ctx, user); err != nil {
+			log.Printf("send unlock email error: %v", err)
+		}
+	}
+}
+
+func (u *userService) sendUnlockEmail(ctx context.Context, user *model.User) error {
+	token, err := u.issueUserToken(ctx, user.ID, model.TokenPurposeAccountUnlock, accountUnlockTTL)
+	if err != nil {
+		return err
+	}
+
+	return u.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Your account has been locked",
+		Body: fmt.Sprintf("Hi %s,\n\nWe locked your account after too many failed login attempts. If these were you, open the link below to unlock it right away. It expires in %s.\n\n%s\n\nIf they were not you, consider changing your password.\n",
+			user.FirstName, accountUnlockTTL, u.link("/users/unlock", token)),
+	})
+}
+
+func (u *userService) UnlockAccount(ctx context.Context, token string) error {
+	record, err := u.consumeUserToken(ctx, model.TokenPurposeAccountUnlock, token)
+	if err != nil {
+		return err
+	}
+
+	user, err := u.repo.GetById(ctx, record.UserID)
+	if err != nil {
+		return err
+	}
+
+	return u.guard.Unlock(ctx, user.Email)
+}
+
 func (u *userService) GetUserDataById(ctx context.Context, id int) (*model.User, error) {
 	return u.repo.GetById(ctx, id)
 }
+
+// issueUserToken stores a new single-use token for the user, invalidating
+// older ones of the same purpose, and returns the raw token.
+func (u *userService) issueUserToken(ctx context.Context, userID int, purpose model.TokenPurpose, ttl time.Duration) (string, error) {
+	if err := u.tokenRepo.InvalidateByUserId(ctx, userID, purpose); err != nil {
+		return "", err
+	}
+
+	token, hash, err := utils.NewOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	record := model.UserToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := u.tokenRepo.Create(ctx, &record); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// consumeUserToken checks the token and marks it used.
+func (u *userService) consumeUserToken(ctx context.Context, purpose model.TokenPurpose, token string) (*model.UserToken, error) {
+	record, err := u.tokenRepo.GetByHash(ctx, purpose, utils.HashToken(token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidUserToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if record.UsedAt != nil || time.Now().After(record.ExpiresAt) {
+		return nil, ErrInvalidUserToken
+	}
+
+	used, err := u.tokenRepo.MarkUsedById(ctx, record.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !used {
+		return nil, ErrInvalidUserToken
+	}
+
+	return record, nil
+}
+
+func (u *userService) link(path, token string) string {
+	return u.config.BaseURL + path + "?token=" + url.QueryEscape(token)
+}
+
+// RequestPasswordReset mails a reset link if the email belongs to a user. It
+// does not reveal whether it does.
+func (u *userService) RequestPasswordReset(ctx context.Context, email string) error {
+	user, err := u.repo.GetByEmail(ctx, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	token, err := u.issueUserToken(ctx, user.ID, model.TokenPurposePasswordReset, passwordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	return u.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nUse the link below to choose a new password. It expires in %s.\n\n%s\n\nIf you did not ask for this, you can ignore this email.\n",
+			user.FirstName, passwordResetTTL, u.link("/reset-password", token)),
+	})
+}
+
+// ResetPassword sets a new password, signs the user out everywhere and lifts
+// any lockout.
+func (u *userService) ResetPassword(ctx context.Context, token, password string) error {
+	record, err := u.consumeUserToken(ctx, model.TokenPurposePasswordReset, token)
+	if err != nil {
+		return err
+	}
+
+	if err := u.repo.UpdatePasswordById(ctx, record.UserID, password); err != nil {
+		return err
+	}
+
+	if err := u.endSessions(ctx, record.UserID); err != nil {
+		return err
+	}
+	u.audit(ctx, model.AuditPasswordReset, record.UserID, "", nil)
+
+	// whoever can read the mailbox may log in again, as with the unlock link
+	user, err := u.repo.GetById(ctx, record.UserID)
+	if err != nil {
+		return err
+	}
+
+	return u.guard.Unlock(ctx, user.Email)
+}
+
+func (u *userService) SendVerificationEmail(ctx context.Context, user *model.User) error {
+	token, err := u.issueUserToken(ctx, user.ID, model.TokenPurposeEmailVerification, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	return u.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below. It expires in %s.\n\n%s\n",
+			user.FirstName, emailVerificationTTL, u.link("/users/verify", token)),
+	})
+}
+
+func (u *userService) VerifyEmail(ctx context.Context, token string) error {
+	record, err := u.consumeUserToken(ctx, model.TokenPurposeEmailVerification, token)
+	if err != nil {
+		return err
+	}
+
+	return u.repo.MarkEmailVerifiedById(ctx, record.UserID)
+}
+
+func (u *userService) DeleteExpiredTokens(ctx context.Context) (int64, error) {
+	return u.tokenRepo.DeleteExpired(ctx, time.Now())
+}
+
+// UpdateProfile changes the names that are not nil.
+func (u *userService) UpdateProfile(ctx context.Context, userID int, firstName, lastName *string) error {
+	user, err := u.repo.GetById(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if firstName != nil {
+		user.FirstName = *firstName
+	}
+	if lastName != nil {
+		user.LastName = *lastName
+	}
+
+	return u.repo.UpdateProfileById(ctx, userID, user.FirstName, user.LastName)
+}
+
+// RequestEmailChange mails a confirmation link to the new address. The
+// account keeps its current email until the link is opened.
+func (u *userService) RequestEmailChange(ctx context.Context, userID int, email string) error {
+	user, err := u.repo.GetById(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if strings.EqualFold(user.Email, email) {
+		return nil
+	}
+
+	_, err = u.repo.GetByEmail(ctx, email)
+	if err == nil {
+		return ErrEmailTaken
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	if err := u.repo.SetPendingEmailById(ctx, userID, email); err != nil {
+		return err
+	}
+
+	token, err := u.issueUserToken(ctx, userID, model.TokenPurposeEmailChange, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	return u.mailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm that you want to use this address for your account by opening the link below. It expires in %s.\n\n%s\n",
+			user.FirstName, emailVerificationTTL, u.link("/users/email/confirm", token)),
+	})
+}
+
+// ConfirmEmailChange switches the account to its pending email and lets the
+// old address know.
+func (u *userService) ConfirmEmailChange(ctx context.Context, token string) error {
+	record, err := u.consumeUserToken(ctx, model.TokenPurposeEmailChange, token)
+	if err != nil {
+		return err
+	}
+
+	user, err := u.repo.GetById(ctx, record.UserID)
+	if err != nil {
+		return err
+	}
+	if user.PendingEmail == nil {
+		return ErrInvalidUserToken
+	}
+
+	err = u.repo.ConfirmPendingEmailById(ctx, user.ID)
+	if errors.Is(err, repository.ErrDuplicateEmail) {
+		return ErrEmailTaken
+	}
+	if err != nil {
+		return err
+	}
+	u.audit(ctx, model.AuditEmailChanged, user.ID, "", map[string]model.Change{
+		"email": {From: user.Email, To: *user.PendingEmail},
+	})
+
+	err = u.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Your email address was changed",
+		Body: fmt.Sprintf("Hi %s,\n\nThe email address of your account was changed to %s. If you did not do this, please contact us right away.\n",
+			user.FirstName, *user.PendingEmail),
+	})
+	if err != nil {
+		log.Printf("send email change notice error: %v", err)
+	}
+
+	return nil
+}
+
+// checkPassword re-authenticates a signed in user. Wrong passwords count as
+// failed logins, so a stolen session cannot be used to guess the password.
+func (u *userService) checkPassword(ctx context.Context, user *model.User, password, ip string) error {
+	if err := u.guard.Check(ctx, user.Email, ip); err != nil {
+		return err
+	}
+
+	if !comparePassword(user.Password, password) {
+		u.audit(ctx, model.AuditLoginFailed, user.ID, "wrong password when re-authenticating", nil)
+		u.recordLoginFailure(ctx, user.Email, ip, user)
+		return ErrWrongPassword
+	}
+
+	return nil
+}
+
+// ChangePassword sets a new password and ends every session. The caller is
+// expected to hand out new tokens to the session that made the change.
+func (u *userService) ChangePassword(ctx context.Context, userID int, currentPassword, newPassword, ip string) error {
+	user, err := u.repo.GetById(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := u.checkPassword(ctx, user, currentPassword, ip); err != nil {
+		return err
+	}
+
+	if err := u.repo.UpdatePasswordById(ctx, userID, newPassword); err != nil {
+		return err
+	}
+	u.audit(ctx, model.AuditPasswordChanged, userID, "", nil)
+
+	return u.endSessions(ctx, userID)
+}
+
+// endSessions signs userID out everywhere: their refresh tokens are revoked
+// and access tokens issued so far are no longer accepted.
+func (u *userService) endSessions(ctx context.Context, userID int) error {
+	if err := u.sessions.RevokeRefreshTokensByUserId(ctx, userID); err != nil {
+		return err
+	}
+
+	return u.repo.BumpTokenGenerationById(ctx, userID)
+}
+
+// TokenGeneration returns the generation access tokens of userID must carry
+// to be accepted.
+func (u *userService) TokenGeneration(ctx context.Context, userID int) (int, error) {
+	return u.repo.GetTokenGenerationById(ctx, userID)
+}
+
+// DeleteAccount deletes the user along with their todos and everything else
+// they own.
+func (u *userService) DeleteAccount(ctx context.Context, userID int, password, ip string) error {
+	user, err := u.repo.GetById(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := u.checkPassword(ctx, user, password, ip); err != nil {
+		return err
+	}
+
+	if err := u.repo.DeleteById(ctx, userID); err != nil {
+		return err
+	}
+	u.audit(ctx, model.AuditAccountDeleted, userID, "", nil)
+
+	return u.guard.Unlock(ctx, user.Email)
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/King0625/golang-todolist/internal/model"
+	"github.com/King0625/golang-todolist/internal/repository"
+)
+
+var (
+	ErrWorkspaceOwner = errors.New("the owner of a workspace cannot be changed or removed")
+	ErrMemberNotFound = errors.New("workspace member not found")
+	ErrUserNotFound   = errors.New("user not found")
+)
+
+type WorkspaceService interface {
+	CreateWorkspace(ctx context.Context, workspace *model.Workspace) error
+	GetWorkspaces(ctx context.Context, userID int) ([]*model.Workspace, error)
+	GetWorkspaceById(ctx context.Context, id int) (*model.Workspace, error)
+	RenameWorkspace(ctx context.Context, id int, name string) error
+	DeleteWorkspace(ctx context.Context, id int) error
+	GetMemberRole(ctx context.Context, workspaceID, userID int) (model.WorkspaceRole, error)
+	GetMembers(ctx context.Context, workspaceID int) ([]*model.WorkspaceMember, error)
+	AddMember(ctx context.Context, workspaceID int, email string, role model.WorkspaceRole) error
+	SetMemberRole(ctx context.Context, workspaceID, userID int, role model.WorkspaceRole) error
+	RemoveMember(ctx context.Context, workspaceID, userID int) error
+}
+
+type workspaceService struct {
+	repo  repository.WorkspaceRepository
+	users repository.UserRepository
+}
+
+func NewWorkspaceService(r repository.WorkspaceRepository, users repository.UserRepository) WorkspaceService {
+	return &workspaceService{r, users}
+}
+
+// CreateWorkspace creates the workspace with workspace.OwnerID as its owner.
+func (s *workspaceService) CreateWorkspace(ctx context.Context, workspace *model.Workspace) error {
+	return s.repo.Create(ctx, workspace)
+}
+
+func (s *workspaceService) GetWorkspaces(ctx context.Context, userID int) ([]*model.Workspace, error) {
+	return s.repo.GetAllByUserId(ctx, userID)
+}
+
+func (s *workspaceService) GetWorkspaceById(ctx context.Context, id int) (*model.Workspace, error) {
+	return s.repo.GetById(ctx, id)
+}
+
+func (s *workspaceService) RenameWorkspace(ctx context.Context, id int, name string) error {
+	return s.repo.UpdateById(ctx, id, name)
+}
+
+// DeleteWorkspace deletes the workspace with all of its projects and todos.
+func (s *workspaceService) DeleteWorkspace(ctx context.Context, id int) error {
+	return s.repo.DeleteById(ctx, id)
+}
+
+// GetMemberRole returns the role of userID in the workspace, or an empty role
+// when they are not a member.
+func (s *workspaceService) GetMemberRole(ctx context.Context, workspaceID, userID int) (model.WorkspaceRole, error) {
+	return s.repo.GetMemberRole(ctx, workspaceID, userID)
+}
+
+func (s *workspaceService) GetMembers(ctx context.Context, workspaceID int) ([]*model.WorkspaceMember, error) {
+	return s.repo.GetMembers(ctx, workspaceID)
+}
+
+// AddMember adds the user registered with email to the workspace. It returns
+// repository.ErrAlreadyMember when they already belong to it.
+func (s *workspaceService) AddMember(ctx context.Context, workspaceID int, email string, role model.WorkspaceRole) error {
+	if role == model.WorkspaceRoleOwner {
+		return ErrWorkspaceOwner
+	}
+
+	user, err := s.users.GetByEmail(ctx, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.repo.AddMember(ctx, workspaceID, user.ID, role)
+}
+
+// checkMember makes sure userID is a member of the workspace other than its
+// owner.
+func (s *workspaceService) checkMember(ctx context.Context, workspaceID, userID int) error {
+	current, err := s.repo.GetMemberRole(ctx, workspaceID, userID)
+	if err != nil {
+		return err
+	}
+
+	switch current {
+	case "":
+		return ErrMemberNotFound
+	case model.WorkspaceRoleOwner:
+		return ErrWorkspaceOwner
+	}
+
+	return nil
+}
+
+func (s *workspaceService) SetMemberRole(ctx context.Context, workspaceID, userID int, role model.WorkspaceRole) error {
+	if role == model.WorkspaceRoleOwner {
+		return ErrWorkspaceOwner
+	}
+	if err := s.checkMember(ctx, workspaceID, userID); err != nil {
+		return err
+	}
+
+	updated, err := s.repo.SetMemberRole(ctx, workspaceID, userID, role)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return ErrMemberNotFound
+	}
+
+	return nil
+}
+
+// RemoveMember removes userID from the workspace and unassigns their todos in
+// it. The owner cannot leave their workspace; they can only delete it.
+func (s *workspaceService) RemoveMember(ctx context.Context, workspaceID, userID int) error {
+	if err := s.checkMember(ctx, workspaceID, userID); err != nil {
+		return err
+	}
+
+	removed, err := s.repo.RemoveMember(ctx, workspaceID, userID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrMemberNotFound
+	}
+
+	return nil
+}
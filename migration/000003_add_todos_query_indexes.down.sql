@@ -0,0 +1,4 @@
+DROP INDEX idx_todos_user_done ON todos;
+DROP INDEX idx_todos_user_title ON todos;
+DROP INDEX idx_todos_user_updated ON todos;
+DROP INDEX idx_todos_user_created ON todos;
@@ -0,0 +1,4 @@
+CREATE INDEX idx_todos_user_created ON todos (user_id, createdAt, id);
+CREATE INDEX idx_todos_user_updated ON todos (user_id, updatedAt, id);
+CREATE INDEX idx_todos_user_title ON todos (user_id, title, id);
+CREATE INDEX idx_todos_user_done ON todos (user_id, done);
@@ -0,0 +1,8 @@
+DROP INDEX idx_todos_user_due ON todos;
+
+ALTER TABLE todos
+	DROP COLUMN priority,
+	DROP COLUMN due_at,
+	DROP COLUMN due_timezone,
+	DROP COLUMN due_time,
+	DROP COLUMN due_date;
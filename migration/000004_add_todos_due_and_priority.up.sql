@@ -0,0 +1,8 @@
+ALTER TABLE todos
+	ADD COLUMN due_date DATE NULL,
+	ADD COLUMN due_time TIME NULL,
+	ADD COLUMN due_timezone VARCHAR(64) NULL,
+	ADD COLUMN due_at DATETIME NULL,
+	ADD COLUMN priority TINYINT NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_todos_user_due ON todos (user_id, done, due_at);
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS tags (
+	id INT AUTO_INCREMENT,
+	user_id INT NOT NULL,
+	name VARCHAR(64) NOT NULL,
+	color VARCHAR(7) NOT NULL DEFAULT '',
+	createdAt DATETIME DEFAULT NOW(),
+	updatedAt DATETIME DEFAULT NOW(),
+	PRIMARY KEY (id),
+	UNIQUE KEY uq_tags_user_name (user_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS todo_tags (
+	todo_id INT NOT NULL,
+	tag_id INT NOT NULL,
+	PRIMARY KEY (todo_id, tag_id),
+	KEY idx_todo_tags_tag (tag_id),
+	CONSTRAINT fk_todo_tags_todo FOREIGN KEY (todo_id) REFERENCES todos (id) ON DELETE CASCADE,
+	CONSTRAINT fk_todo_tags_tag FOREIGN KEY (tag_id) REFERENCES tags (id) ON DELETE CASCADE
+);
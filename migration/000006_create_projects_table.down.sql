@@ -0,0 +1,5 @@
+DROP INDEX idx_todos_user_project ON todos;
+
+ALTER TABLE todos DROP COLUMN project_id;
+
+DROP TABLE IF EXISTS projects;
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS projects (
+	id INT AUTO_INCREMENT,
+	user_id INT NOT NULL,
+	name VARCHAR(255) NOT NULL,
+	color VARCHAR(7) NOT NULL DEFAULT '',
+	archived TINYINT(1) NOT NULL DEFAULT 0,
+	position INT NOT NULL DEFAULT 0,
+	createdAt DATETIME DEFAULT NOW(),
+	updatedAt DATETIME DEFAULT NOW(),
+	PRIMARY KEY (id),
+	KEY idx_projects_user_position (user_id, archived, position)
+);
+
+ALTER TABLE todos ADD COLUMN project_id INT NULL;
+
+CREATE INDEX idx_todos_user_project ON todos (user_id, project_id);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS todo_items (
+	id INT AUTO_INCREMENT,
+	todo_id INT NOT NULL,
+	title VARCHAR(255) NOT NULL,
+	done TINYINT(1) NOT NULL DEFAULT 0,
+	position INT NOT NULL DEFAULT 0,
+	createdAt DATETIME DEFAULT NOW(),
+	updatedAt DATETIME DEFAULT NOW(),
+	PRIMARY KEY (id),
+	KEY idx_todo_items_todo_position (todo_id, position),
+	CONSTRAINT fk_todo_items_todo FOREIGN KEY (todo_id) REFERENCES todos (id) ON DELETE CASCADE
+);
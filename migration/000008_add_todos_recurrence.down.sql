@@ -0,0 +1,3 @@
+ALTER TABLE todos
+	DROP COLUMN recurrence_start,
+	DROP COLUMN recurrence_rule;
@@ -0,0 +1,3 @@
+ALTER TABLE todos
+	ADD COLUMN recurrence_rule VARCHAR(255) NULL,
+	ADD COLUMN recurrence_start DATETIME NULL;
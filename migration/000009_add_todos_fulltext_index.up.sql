@@ -0,0 +1 @@
+ALTER TABLE todos ADD FULLTEXT INDEX ft_todos_title_content (title, content);
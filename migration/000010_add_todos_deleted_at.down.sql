@@ -0,0 +1,3 @@
+DROP INDEX idx_todos_user_deleted ON todos;
+
+ALTER TABLE todos DROP COLUMN deletedAt;
@@ -0,0 +1,3 @@
+ALTER TABLE todos ADD COLUMN deletedAt DATETIME NULL;
+
+CREATE INDEX idx_todos_user_deleted ON todos (user_id, deletedAt);
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+	id INT AUTO_INCREMENT,
+	user_id INT NOT NULL,
+	family_id VARCHAR(64) NOT NULL,
+	token_hash CHAR(64) NOT NULL,
+	expiresAt DATETIME NOT NULL,
+	revokedAt DATETIME NULL,
+	createdAt DATETIME DEFAULT NOW(),
+	PRIMARY KEY (id),
+	UNIQUE KEY uq_refresh_tokens_hash (token_hash),
+	KEY idx_refresh_tokens_family (family_id),
+	KEY idx_refresh_tokens_user (user_id)
+);
+
+CREATE TABLE IF NOT EXISTS revoked_access_tokens (
+	jti VARCHAR(64) NOT NULL,
+	expiresAt DATETIME NOT NULL,
+	PRIMARY KEY (jti),
+	KEY idx_revoked_access_tokens_expires (expiresAt)
+);
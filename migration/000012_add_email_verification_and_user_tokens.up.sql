@@ -0,0 +1,14 @@
+ALTER TABLE users ADD COLUMN emailVerifiedAt DATETIME NULL;
+
+CREATE TABLE IF NOT EXISTS user_tokens (
+	id INT AUTO_INCREMENT,
+	user_id INT NOT NULL,
+	purpose VARCHAR(32) NOT NULL,
+	token_hash CHAR(64) NOT NULL,
+	expiresAt DATETIME NOT NULL,
+	usedAt DATETIME NULL,
+	createdAt DATETIME DEFAULT NOW(),
+	PRIMARY KEY (id),
+	UNIQUE KEY uq_user_tokens_hash (token_hash),
+	KEY idx_user_tokens_user_purpose (user_id, purpose)
+);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS personal_access_tokens (
+	id INT AUTO_INCREMENT,
+	user_id INT NOT NULL,
+	name VARCHAR(100) NOT NULL,
+	token_hash CHAR(64) NOT NULL,
+	token_prefix VARCHAR(16) NOT NULL,
+	scopes VARCHAR(255) NOT NULL,
+	expiresAt DATETIME NULL,
+	lastUsedAt DATETIME NULL,
+	revokedAt DATETIME NULL,
+	createdAt DATETIME DEFAULT NOW(),
+	PRIMARY KEY (id),
+	UNIQUE KEY uq_personal_access_tokens_hash (token_hash),
+	KEY idx_personal_access_tokens_user (user_id)
+);
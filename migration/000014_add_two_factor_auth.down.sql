@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS recovery_codes;
+
+ALTER TABLE users
+	DROP COLUMN totpSecret,
+	DROP COLUMN totpEnabledAt,
+	DROP COLUMN totpLastStep;
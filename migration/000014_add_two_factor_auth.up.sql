@@ -0,0 +1,14 @@
+ALTER TABLE users
+	ADD COLUMN totpSecret VARCHAR(64) NULL,
+	ADD COLUMN totpEnabledAt DATETIME NULL,
+	ADD COLUMN totpLastStep BIGINT NULL;
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+	id INT AUTO_INCREMENT,
+	user_id INT NOT NULL,
+	code_hash CHAR(64) NOT NULL,
+	usedAt DATETIME NULL,
+	createdAt DATETIME DEFAULT NOW(),
+	PRIMARY KEY (id),
+	UNIQUE KEY uq_recovery_codes_user_hash (user_id, code_hash)
+);
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS user_identities (
+	id INT AUTO_INCREMENT,
+	user_id INT NOT NULL,
+	provider VARCHAR(64) NOT NULL,
+	subject VARCHAR(255) NOT NULL,
+	email VARCHAR(255) NOT NULL,
+	createdAt DATETIME DEFAULT NOW(),
+	PRIMARY KEY (id),
+	UNIQUE KEY uq_user_identities_provider_subject (provider, subject),
+	KEY idx_user_identities_user (user_id)
+);
+
+CREATE TABLE IF NOT EXISTS oidc_login_states (
+	id INT AUTO_INCREMENT,
+	state_hash CHAR(64) NOT NULL,
+	provider VARCHAR(64) NOT NULL,
+	nonce VARCHAR(64) NOT NULL,
+	code_verifier VARCHAR(128) NOT NULL,
+	expiresAt DATETIME NOT NULL,
+	createdAt DATETIME DEFAULT NOW(),
+	PRIMARY KEY (id),
+	UNIQUE KEY uq_oidc_login_states_hash (state_hash)
+);
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS login_attempts (
+	attempt_key VARCHAR(300) NOT NULL,
+	failures INT NOT NULL DEFAULT 0,
+	lastFailureAt DATETIME NOT NULL,
+	lockedUntil DATETIME NULL,
+	PRIMARY KEY (attempt_key),
+	KEY idx_login_attempts_last_failure (lastFailureAt)
+);
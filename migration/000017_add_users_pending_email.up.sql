@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN pendingEmail VARCHAR(255) NULL;
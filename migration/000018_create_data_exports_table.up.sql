@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS data_exports (
+	id INT AUTO_INCREMENT,
+	user_id INT NOT NULL,
+	status VARCHAR(16) NOT NULL DEFAULT 'pending',
+	error VARCHAR(255) NOT NULL DEFAULT '',
+	archive LONGBLOB NULL,
+	size BIGINT NOT NULL DEFAULT 0,
+	createdAt DATETIME DEFAULT NOW(),
+	startedAt DATETIME NULL,
+	completedAt DATETIME NULL,
+	expiresAt DATETIME NULL,
+	PRIMARY KEY (id),
+	KEY idx_data_exports_user (user_id, status),
+	KEY idx_data_exports_status (status, createdAt)
+);
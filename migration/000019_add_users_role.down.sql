@@ -0,0 +1,4 @@
+ALTER TABLE users
+	DROP KEY idx_users_role,
+	DROP COLUMN role,
+	DROP COLUMN disabledAt;
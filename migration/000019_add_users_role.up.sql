@@ -0,0 +1,4 @@
+ALTER TABLE users
+	ADD COLUMN role VARCHAR(16) NOT NULL DEFAULT 'user',
+	ADD COLUMN disabledAt DATETIME NULL,
+	ADD KEY idx_users_role (role);
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS shares (
+	id INT AUTO_INCREMENT,
+	resource_type VARCHAR(16) NOT NULL,
+	resource_id INT NOT NULL,
+	owner_id INT NOT NULL,
+	email VARCHAR(255) NOT NULL,
+	user_id INT NULL,
+	permission VARCHAR(16) NOT NULL,
+	status VARCHAR(16) NOT NULL DEFAULT 'pending',
+	createdAt DATETIME DEFAULT NOW(),
+	respondedAt DATETIME NULL,
+	PRIMARY KEY (id),
+	UNIQUE KEY uq_shares_resource_email (resource_type, resource_id, email),
+	KEY idx_shares_user (user_id, status, resource_type),
+	KEY idx_shares_email (email, status)
+);
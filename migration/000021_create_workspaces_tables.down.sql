@@ -0,0 +1,9 @@
+DROP INDEX idx_projects_workspace ON projects;
+DROP INDEX idx_todos_assignee ON todos;
+DROP INDEX idx_todos_workspace ON todos;
+
+ALTER TABLE projects DROP COLUMN workspace_id;
+ALTER TABLE todos DROP COLUMN assignee_id, DROP COLUMN workspace_id;
+
+DROP TABLE IF EXISTS workspace_members;
+DROP TABLE IF EXISTS workspaces;
@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS workspaces (
+	id INT AUTO_INCREMENT,
+	owner_id INT NOT NULL,
+	name VARCHAR(100) NOT NULL,
+	createdAt DATETIME DEFAULT NOW(),
+	updatedAt DATETIME DEFAULT NOW(),
+	PRIMARY KEY (id),
+	KEY idx_workspaces_owner (owner_id)
+);
+
+CREATE TABLE IF NOT EXISTS workspace_members (
+	workspace_id INT NOT NULL,
+	user_id INT NOT NULL,
+	role VARCHAR(16) NOT NULL DEFAULT 'member',
+	createdAt DATETIME DEFAULT NOW(),
+	PRIMARY KEY (workspace_id, user_id),
+	KEY idx_workspace_members_user (user_id)
+);
+
+ALTER TABLE todos ADD COLUMN workspace_id INT NULL, ADD COLUMN assignee_id INT NULL;
+ALTER TABLE projects ADD COLUMN workspace_id INT NULL;
+
+CREATE INDEX idx_todos_workspace ON todos (workspace_id, deletedAt);
+CREATE INDEX idx_todos_assignee ON todos (assignee_id, deletedAt);
+CREATE INDEX idx_projects_workspace ON projects (workspace_id, archived, position);
@@ -0,0 +1,33 @@
+CREATE TABLE IF NOT EXISTS todo_comments (
+	id INT AUTO_INCREMENT,
+	todo_id INT NOT NULL,
+	user_id INT NOT NULL,
+	content TEXT NOT NULL,
+	createdAt DATETIME DEFAULT NOW(),
+	editedAt DATETIME NULL,
+	PRIMARY KEY (id),
+	KEY idx_todo_comments_todo (todo_id, createdAt),
+	KEY idx_todo_comments_user (user_id),
+	CONSTRAINT fk_todo_comments_todo FOREIGN KEY (todo_id) REFERENCES todos (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS comment_mentions (
+	comment_id INT NOT NULL,
+	user_id INT NOT NULL,
+	PRIMARY KEY (comment_id, user_id),
+	KEY idx_comment_mentions_user (user_id),
+	CONSTRAINT fk_comment_mentions_comment FOREIGN KEY (comment_id) REFERENCES todo_comments (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS todo_events (
+	id INT AUTO_INCREMENT,
+	todo_id INT NOT NULL,
+	user_id INT NULL,
+	type VARCHAR(32) NOT NULL,
+	old_value TEXT NULL,
+	new_value TEXT NULL,
+	createdAt DATETIME DEFAULT NOW(),
+	PRIMARY KEY (id),
+	KEY idx_todo_events_todo (todo_id, createdAt),
+	CONSTRAINT fk_todo_events_todo FOREIGN KEY (todo_id) REFERENCES todos (id) ON DELETE CASCADE
+);
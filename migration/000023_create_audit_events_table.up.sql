@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS audit_events (
+	id BIGINT AUTO_INCREMENT,
+	actor_id INT NULL,
+	action VARCHAR(64) NOT NULL,
+	target_type VARCHAR(32) NOT NULL,
+	target_id INT NULL,
+	detail VARCHAR(255) NOT NULL DEFAULT '',
+	changes JSON NULL,
+	ip VARCHAR(45) NOT NULL DEFAULT '',
+	user_agent VARCHAR(255) NOT NULL DEFAULT '',
+	request_id VARCHAR(64) NOT NULL DEFAULT '',
+	createdAt DATETIME DEFAULT NOW(),
+	PRIMARY KEY (id),
+	KEY idx_audit_events_actor (actor_id, id),
+	KEY idx_audit_events_target (target_type, target_id, id),
+	KEY idx_audit_events_action (action, id),
+	KEY idx_audit_events_created (createdAt)
+);
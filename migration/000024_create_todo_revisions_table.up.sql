@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS todo_revisions (
+	id INT AUTO_INCREMENT,
+	todo_id INT NOT NULL,
+	revision INT NOT NULL,
+	user_id INT NULL,
+	title VARCHAR(256) NOT NULL,
+	content TEXT NOT NULL,
+	createdAt DATETIME DEFAULT NOW(),
+	PRIMARY KEY (id),
+	UNIQUE KEY uq_todo_revisions_todo_revision (todo_id, revision),
+	KEY idx_todo_revisions_created (createdAt),
+	CONSTRAINT fk_todo_revisions_todo FOREIGN KEY (todo_id) REFERENCES todos (id) ON DELETE CASCADE
+);
+
+-- existing todos start their history with what they are now
+INSERT INTO todo_revisions (todo_id, revision, user_id, title, content, createdAt)
+SELECT id, 1, user_id, title, content, updatedAt FROM todos;
@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN tokensValidAfter DATETIME NULL;
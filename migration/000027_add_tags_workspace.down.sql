@@ -0,0 +1,7 @@
+DELETE FROM tags WHERE workspace_id IS NOT NULL;
+
+ALTER TABLE tags
+	DROP INDEX uq_tags_tenant_name,
+	DROP COLUMN tenant_key,
+	DROP COLUMN workspace_id,
+	ADD UNIQUE KEY uq_tags_user_name (user_id, name);
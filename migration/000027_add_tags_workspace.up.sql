@@ -0,0 +1,7 @@
+-- tag names are unique per tenant: per user for personal tags, per workspace
+-- for the tags of a workspace
+ALTER TABLE tags
+	ADD COLUMN workspace_id INT NULL,
+	ADD COLUMN tenant_key VARCHAR(32) AS (IF(workspace_id IS NULL, CONCAT('user:', user_id), CONCAT('workspace:', workspace_id))) STORED,
+	DROP INDEX uq_tags_user_name,
+	ADD UNIQUE KEY uq_tags_tenant_name (tenant_key, name);
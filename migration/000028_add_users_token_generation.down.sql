@@ -0,0 +1 @@
+ALTER TABLE users DROP COLUMN tokenGeneration, ADD COLUMN tokensValidAfter DATETIME NULL;
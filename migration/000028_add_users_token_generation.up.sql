@@ -0,0 +1 @@
+ALTER TABLE users DROP COLUMN tokensValidAfter, ADD COLUMN tokenGeneration INT NOT NULL DEFAULT 0;
@@ -0,0 +1,101 @@
+// Package jobs runs background work on a fixed pool of workers.
+//
+// Jobs live only in memory. Work that must survive a restart should be
+// recorded elsewhere, e.g. in the database, and re-enqueued on start.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+)
+
+var (
+	ErrQueueFull     = errors.New("job queue is full")
+	ErrRunnerStopped = errors.New("job runner is stopped")
+)
+
+// Job is one unit of work. name is only used for logging.
+type Job struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+type Runner struct {
+	queue   chan Job
+	workers int
+
+	mu      sync.RWMutex
+	stopped bool
+	wg      sync.WaitGroup
+}
+
+// NewRunner returns a runner with the given number of workers and room for
+// queueSize jobs waiting for one.
+func NewRunner(workers, queueSize int) *Runner {
+	return &Runner{queue: make(chan Job, queueSize), workers: workers}
+}
+
+// Start runs the workers until ctx is done. Jobs get ctx, so they should stop
+// early when it is cancelled.
+func (r *Runner) Start(ctx context.Context) {
+	for range r.workers {
+		r.wg.Add(1)
+		go r.work(ctx)
+	}
+
+	go func() {
+		<-ctx.Done()
+		r.mu.Lock()
+		r.stopped = true
+		close(r.queue)
+		r.mu.Unlock()
+	}()
+}
+
+func (r *Runner) work(ctx context.Context) {
+	defer r.wg.Done()
+
+	for job := range r.queue {
+		if ctx.Err() != nil {
+			continue
+		}
+		r.run(ctx, job)
+	}
+}
+
+func (r *Runner) run(ctx context.Context, job Job) {
+	defer func() {
+		if p := recover(); p != nil {
+			log.Printf("job %s panicked: %v", job.Name, p)
+		}
+	}()
+
+	if err := job.Run(ctx); err != nil {
+		log.Printf("job %s error: %v", job.Name, err)
+	}
+}
+
+// Enqueue adds job to the queue without blocking.
+func (r *Runner) Enqueue(job Job) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.stopped {
+		return ErrRunnerStopped
+	}
+
+	select {
+	case r.queue <- job:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Wait blocks until the workers have exited after the context given to Start
+// is done.
+func (r *Runner) Wait() {
+	r.wg.Wait()
+}
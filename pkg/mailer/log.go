@@ -0,0 +1,39 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// LogMailer is for local development: it logs every message, and also writes
+// it to an .eml file when Dir is set.
+type LogMailer struct {
+	From string
+	Dir  string
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	raw := format(m.From, msg)
+
+	if m.Dir == "" {
+		log.Printf("mail to %s:\n%s", msg.To, raw)
+		return nil
+	}
+
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), filepath.Base(msg.To))
+	path := filepath.Join(m.Dir, name)
+	if err := os.WriteFile(path, raw, 0o644); err != nil {
+		return err
+	}
+
+	log.Printf("mail to %s written to %s", msg.To, path)
+	return nil
+}
@@ -0,0 +1,163 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// minRefreshInterval keeps tokens with made up kids from hammering the
+// provider's JWKS endpoint.
+const minRefreshInterval = time.Minute
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type publicKey struct {
+	key crypto.PublicKey
+	alg string
+}
+
+// keySet caches the provider's signing keys and refetches them when a token
+// names a kid it does not know, which is how providers roll keys.
+type keySet struct {
+	provider *Provider
+	uri      string
+
+	mu        sync.Mutex
+	keys      map[string]publicKey
+	fetchedAt time.Time
+}
+
+func newKeySet(p *Provider, uri string) *keySet {
+	return &keySet{provider: p, uri: uri}
+}
+
+func (k *keySet) get(ctx context.Context, kid string) (publicKey, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if key, ok := k.lookup(kid); ok {
+		return key, nil
+	}
+
+	if k.keys != nil && k.provider.now().Sub(k.fetchedAt) < minRefreshInterval {
+		return publicKey{}, fmt.Errorf("unknown key %q", kid)
+	}
+
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := k.provider.getJSON(ctx, k.uri, &doc); err != nil {
+		return publicKey{}, err
+	}
+
+	keys := map[string]publicKey{}
+	for _, raw := range doc.Keys {
+		if raw.Use != "" && raw.Use != "sig" {
+			continue
+		}
+		key, err := parseJWK(raw)
+		if err != nil {
+			// skip key types we cannot use rather than failing the whole set
+			continue
+		}
+		keys[raw.Kid] = key
+	}
+	k.keys, k.fetchedAt = keys, k.provider.now()
+
+	if key, ok := k.lookup(kid); ok {
+		return key, nil
+	}
+	return publicKey{}, fmt.Errorf("unknown key %q", kid)
+}
+
+// lookup finds kid, or the only key when the token names none.
+func (k *keySet) lookup(kid string) (publicKey, bool) {
+	if kid == "" && len(k.keys) == 1 {
+		for _, key := range k.keys {
+			return key, true
+		}
+	}
+	key, ok := k.keys[kid]
+	return key, ok
+}
+
+func decodeBase64URL(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(s)
+}
+
+func parseJWK(raw jwk) (publicKey, error) {
+	switch raw.Kty {
+	case "RSA":
+		n, err := decodeBase64URL(raw.N)
+		if err != nil {
+			return publicKey{}, err
+		}
+		e, err := decodeBase64URL(raw.E)
+		if err != nil {
+			return publicKey{}, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+			return publicKey{}, errors.New("rsa exponent too large")
+		}
+		key := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}
+		return publicKey{key, defaultAlg(raw.Alg, "RS256")}, nil
+
+	case "EC":
+		if raw.Crv != "P-256" {
+			return publicKey{}, fmt.Errorf("unsupported curve %q", raw.Crv)
+		}
+		x, err := decodeBase64URL(raw.X)
+		if err != nil {
+			return publicKey{}, err
+		}
+		y, err := decodeBase64URL(raw.Y)
+		if err != nil {
+			return publicKey{}, err
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return publicKey{}, errors.New("ec point is not on the curve")
+		}
+		return publicKey{key, "ES256"}, nil
+
+	case "OKP":
+		if raw.Crv != "Ed25519" {
+			return publicKey{}, fmt.Errorf("unsupported curve %q", raw.Crv)
+		}
+		x, err := decodeBase64URL(raw.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return publicKey{}, errors.New("invalid ed25519 key")
+		}
+		return publicKey{ed25519.PublicKey(x), "EdDSA"}, nil
+	}
+
+	return publicKey{}, fmt.Errorf("unsupported key type %q", raw.Kty)
+}
+
+func defaultAlg(alg, fallback string) string {
+	if alg == "" {
+		return fallback
+	}
+	return alg
+}
@@ -0,0 +1,430 @@
+// Package recurrence implements the subset of RFC 5545 recurrence rules used
+// by recurring todos: FREQ, INTERVAL, BYDAY, BYMONTHDAY, COUNT and UNTIL.
+//
+// Occurrences keep the wall-clock time of DTSTART in its location, so a daily
+// 09:00 rule stays at 09:00 across DST changes. Dates that do not exist in a
+// period (e.g. the 31st in April) are skipped, as the RFC requires.
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+// maxPeriods bounds the search for the next occurrence so that rules which can
+// never match (e.g. BYMONTHDAY=31 with FREQ=MONTHLY;INTERVAL=12 starting in
+// April) terminate.
+const maxPeriods = 100000
+
+var ErrInvalidRule = errors.New("invalid recurrence rule")
+
+// WeekdayNum is a BYDAY entry such as MO, 2TU or -1FR. N is zero when the
+// entry has no ordinal.
+type WeekdayNum struct {
+	Day time.Weekday
+	N   int
+}
+
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	// Count limits the number of occurrences, zero means unlimited.
+	Count int
+	// Until is the last instant an occurrence may fall on, zero means unlimited.
+	// With UntilDate set, UNTIL was a date without time and Until is midnight
+	// UTC of it; the series then runs to the end of that day in the location
+	// of DTSTART.
+	Until     time.Time
+	UntilDate bool
+}
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+func invalid(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalidRule, fmt.Sprintf(format, args...))
+}
+
+// Parse parses a rule such as "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE". An
+// optional "RRULE:" prefix is accepted.
+func Parse(s string) (*Rule, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return nil, invalid("empty rule")
+	}
+
+	rule := &Rule{Interval: 1}
+	seen := make(map[string]bool)
+
+	for _, part := range strings.Split(s, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		key = strings.ToUpper(key)
+		if !ok || value == "" {
+			return nil, invalid("malformed part %q", part)
+		}
+		if seen[key] {
+			return nil, invalid("duplicate %s", key)
+		}
+		seen[key] = true
+
+		switch key {
+		case "FREQ":
+			switch f := Frequency(strings.ToUpper(value)); f {
+			case Daily, Weekly, Monthly, Yearly:
+				rule.Freq = f
+			default:
+				return nil, invalid("unsupported FREQ %q", value)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, invalid("INTERVAL must be a positive integer")
+			}
+			rule.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, invalid("COUNT must be a positive integer")
+			}
+			rule.Count = n
+		case "UNTIL":
+			until, dateOnly, err := parseUntil(value, time.UTC)
+			if err != nil {
+				return nil, err
+			}
+			rule.Until, rule.UntilDate = until, dateOnly
+		case "BYDAY":
+			for _, code := range strings.Split(value, ",") {
+				wd, err := parseWeekdayNum(code)
+				if err != nil {
+					return nil, err
+				}
+				rule.ByDay = append(rule.ByDay, wd)
+			}
+		case "BYMONTHDAY":
+			for _, v := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(v)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return nil, invalid("BYMONTHDAY must be within -31..-1 or 1..31")
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, n)
+			}
+		default:
+			return nil, invalid("unsupported part %s", key)
+		}
+	}
+
+	if rule.Freq == "" {
+		return nil, invalid("FREQ is required")
+	}
+	if rule.Count > 0 && !rule.Until.IsZero() {
+		return nil, invalid("COUNT and UNTIL are mutually exclusive")
+	}
+	if rule.Freq == Yearly && (len(rule.ByDay) > 0 || len(rule.ByMonthDay) > 0) {
+		return nil, invalid("BYDAY and BYMONTHDAY are not supported with FREQ=YEARLY")
+	}
+	for _, wd := range rule.ByDay {
+		if wd.N != 0 && rule.Freq != Monthly {
+			return nil, invalid("ordinal BYDAY is only supported with FREQ=MONTHLY")
+		}
+	}
+	if len(rule.ByMonthDay) > 0 && rule.Freq == Weekly {
+		return nil, invalid("BYMONTHDAY is not allowed with FREQ=WEEKLY")
+	}
+
+	return rule, nil
+}
+
+// parseUntil parses an UNTIL value. A date without time is returned as
+// midnight of that day in loc, and reported as date-only.
+func parseUntil(value string, loc *time.Location) (time.Time, bool, error) {
+	if t, err := time.Parse("20060102T150405Z", value); err == nil {
+		return t, false, nil
+	}
+	if t, err := time.ParseInLocation("20060102", value, loc); err == nil {
+		return t, true, nil
+	}
+	return time.Time{}, false, invalid("UNTIL must be YYYYMMDD or YYYYMMDDTHHMMSSZ")
+}
+
+// until returns the last instant an occurrence may fall on for a series in
+// loc. A date-only UNTIL includes the whole day there.
+func (r *Rule) until(loc *time.Location) time.Time {
+	if !r.UntilDate {
+		return r.Until
+	}
+
+	y, m, d := r.Until.Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, loc).Add(-time.Second)
+}
+
+func parseWeekdayNum(code string) (WeekdayNum, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) < 2 {
+		return WeekdayNum{}, invalid("invalid BYDAY %q", code)
+	}
+
+	day, ok := weekdayCodes[code[len(code)-2:]]
+	if !ok {
+		return WeekdayNum{}, invalid("invalid BYDAY %q", code)
+	}
+
+	wd := WeekdayNum{Day: day}
+	if prefix := code[:len(code)-2]; prefix != "" {
+		n, err := strconv.Atoi(prefix)
+		if err != nil || n == 0 || n < -5 || n > 5 {
+			return WeekdayNum{}, invalid("invalid BYDAY ordinal %q", code)
+		}
+		wd.N = n
+	}
+
+	return wd, nil
+}
+
+// String formats the rule in RRULE syntax without the "RRULE:" prefix.
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		codes := make([]string, len(r.ByDay))
+		for i, wd := range r.ByDay {
+			code := strings.ToUpper(wd.Day.String()[:2])
+			if wd.N != 0 {
+				code = strconv.Itoa(wd.N) + code
+			}
+			codes[i] = code
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, d := range r.ByMonthDay {
+			days[i] = strconv.Itoa(d)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	switch {
+	case r.UntilDate:
+		parts = append(parts, "UNTIL="+r.Until.Format("20060102"))
+	case !r.Until.IsZero():
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Next returns the first occurrence of the series starting at dtstart that is
+// strictly after after. It reports false when the series has ended.
+func (r *Rule) Next(dtstart, after time.Time) (time.Time, bool) {
+	until := r.until(dtstart.Location())
+	count := 0
+	for period := 0; period < maxPeriods; period++ {
+		for _, occ := range r.occurrencesInPeriod(dtstart, period*r.Interval) {
+			if occ.Before(dtstart) {
+				continue
+			}
+			if !until.IsZero() && occ.After(until) {
+				return time.Time{}, false
+			}
+			count++
+			if r.Count > 0 && count > r.Count {
+				return time.Time{}, false
+			}
+			if occ.After(after) {
+				return occ, true
+			}
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// occurrencesInPeriod returns the sorted candidates of the period that lies
+// offset periods (days, weeks, months or years) after the one of dtstart.
+func (r *Rule) occurrencesInPeriod(dtstart time.Time, offset int) []time.Time {
+	y, m, d := dtstart.Date()
+	var candidates []time.Time
+
+	switch r.Freq {
+	case Daily:
+		day := r.at(dtstart, y, m, d+offset)
+		if r.matchesByDay(day) && r.matchesByMonthDay(day) {
+			candidates = append(candidates, day)
+		}
+	case Weekly:
+		// weeks start on Monday (WKST=MO)
+		sinceMonday := (int(dtstart.Weekday()) + 6) % 7
+		monday := d - sinceMonday + 7*offset
+		if len(r.ByDay) == 0 {
+			candidates = append(candidates, r.at(dtstart, y, m, monday+sinceMonday))
+		}
+		for _, wd := range r.ByDay {
+			candidates = append(candidates, r.at(dtstart, y, m, monday+(int(wd.Day)+6)%7))
+		}
+	case Monthly:
+		year, month := addMonths(y, m, offset)
+		candidates = r.monthCandidates(dtstart, year, month, d)
+	case Yearly:
+		if day, ok := r.exactDate(dtstart, y+offset, m, d); ok {
+			candidates = append(candidates, day)
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Before(candidates[j]) })
+	return dedupe(candidates)
+}
+
+func (r *Rule) monthCandidates(dtstart time.Time, year int, month time.Month, startDay int) []time.Time {
+	var candidates []time.Time
+	last := daysIn(year, month)
+
+	switch {
+	case len(r.ByMonthDay) > 0:
+		for _, md := range r.ByMonthDay {
+			if md < 0 {
+				md = last + md + 1
+			}
+			if md < 1 || md > last {
+				continue
+			}
+			day := r.at(dtstart, year, month, md)
+			if r.matchesByDay(day) {
+				candidates = append(candidates, day)
+			}
+		}
+	case len(r.ByDay) > 0:
+		for _, wd := range r.ByDay {
+			candidates = append(candidates, r.weekdaysInMonth(dtstart, year, month, wd)...)
+		}
+	default:
+		if day, ok := r.exactDate(dtstart, year, month, startDay); ok {
+			candidates = append(candidates, day)
+		}
+	}
+
+	return candidates
+}
+
+// weekdaysInMonth returns every matching weekday of the month, or only the
+// Nth (counting from the end when negative) when wd has an ordinal.
+func (r *Rule) weekdaysInMonth(dtstart time.Time, year int, month time.Month, wd WeekdayNum) []time.Time {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC).Weekday()
+	day := 1 + (int(wd.Day)-int(first)+7)%7
+
+	var days []int
+	for ; day <= daysIn(year, month); day += 7 {
+		days = append(days, day)
+	}
+
+	switch {
+	case wd.N > 0 && wd.N <= len(days):
+		days = days[wd.N-1 : wd.N]
+	case wd.N < 0 && -wd.N <= len(days):
+		days = days[len(days)+wd.N : len(days)+wd.N+1]
+	case wd.N != 0:
+		days = nil
+	}
+
+	result := make([]time.Time, len(days))
+	for i, d := range days {
+		result[i] = r.at(dtstart, year, month, d)
+	}
+	return result
+}
+
+func (r *Rule) matchesByDay(t time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, wd := range r.ByDay {
+		if wd.Day == t.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) matchesByMonthDay(t time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	last := daysIn(t.Year(), t.Month())
+	for _, md := range r.ByMonthDay {
+		if md == t.Day() || last+md+1 == t.Day() {
+			return true
+		}
+	}
+	return false
+}
+
+// at builds the given (possibly unnormalized) date with the wall-clock time
+// of dtstart in its location. A wall-clock time that falls into a DST gap is
+// interpreted with the UTC offset before the gap, so 02:30 on a spring-forward
+// day becomes 03:30, as RFC 5545 requires.
+func (r *Rule) at(dtstart time.Time, year int, month time.Month, day int) time.Time {
+	h, min, sec := dtstart.Clock()
+	loc := dtstart.Location()
+
+	t := time.Date(year, month, day, h, min, sec, 0, loc)
+	if th, tmin, _ := t.Clock(); th == h && tmin == min {
+		return t
+	}
+
+	_, offset := time.Date(year, month, day-1, h, min, sec, 0, loc).Zone()
+	wall := time.Date(year, month, day, h, min, sec, 0, time.UTC)
+	return wall.Add(-time.Duration(offset) * time.Second).In(loc)
+}
+
+// exactDate is like at but reports false instead of rolling over into the
+// next month, e.g. for February 30.
+func (r *Rule) exactDate(dtstart time.Time, year int, month time.Month, day int) (time.Time, bool) {
+	if day > daysIn(year, month) {
+		return time.Time{}, false
+	}
+	return r.at(dtstart, year, month, day), true
+}
+
+func addMonths(year int, month time.Month, n int) (int, time.Month) {
+	total := int(month) - 1 + n
+	return year + total/12, time.Month(total%12 + 1)
+}
+
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+func dedupe(times []time.Time) []time.Time {
+	result := times[:0]
+	for i, t := range times {
+		if i == 0 || !t.Equal(times[i-1]) {
+			result = append(result, t)
+		}
+	}
+	return result
+}
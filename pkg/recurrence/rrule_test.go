@@ -0,0 +1,247 @@
+package recurrence
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("load location %s: %v", name, err)
+	}
+	return loc
+}
+
+func TestNext(t *testing.T) {
+	newYork := mustLoad(t, "America/New_York")
+	date := func(loc *time.Location, y int, m time.Month, d, h, min int) time.Time {
+		return time.Date(y, m, d, h, min, 0, 0, loc)
+	}
+
+	tests := []struct {
+		name    string
+		rule    string
+		dtstart time.Time
+		after   time.Time
+		want    time.Time
+		ended   bool
+	}{
+		{
+			name:    "BYMONTHDAY=31 skips February",
+			rule:    "FREQ=MONTHLY;BYMONTHDAY=31",
+			dtstart: date(time.UTC, 2026, time.January, 31, 9, 0),
+			after:   date(time.UTC, 2026, time.January, 31, 9, 0),
+			want:    date(time.UTC, 2026, time.March, 31, 9, 0),
+		},
+		{
+			name:    "BYMONTHDAY=31 skips April",
+			rule:    "FREQ=MONTHLY;BYMONTHDAY=31",
+			dtstart: date(time.UTC, 2026, time.January, 31, 9, 0),
+			after:   date(time.UTC, 2026, time.March, 31, 9, 0),
+			want:    date(time.UTC, 2026, time.May, 31, 9, 0),
+		},
+		{
+			name:    "monthly on the 31st without BYMONTHDAY skips short months",
+			rule:    "FREQ=MONTHLY",
+			dtstart: date(time.UTC, 2026, time.January, 31, 9, 0),
+			after:   date(time.UTC, 2026, time.January, 31, 9, 0),
+			want:    date(time.UTC, 2026, time.March, 31, 9, 0),
+		},
+		{
+			name:    "BYMONTHDAY=-1 in February",
+			rule:    "FREQ=MONTHLY;BYMONTHDAY=-1",
+			dtstart: date(time.UTC, 2026, time.January, 31, 9, 0),
+			after:   date(time.UTC, 2026, time.January, 31, 9, 0),
+			want:    date(time.UTC, 2026, time.February, 28, 9, 0),
+		},
+		{
+			name:    "BYMONTHDAY=-1 in a leap February",
+			rule:    "FREQ=MONTHLY;BYMONTHDAY=-1",
+			dtstart: date(time.UTC, 2028, time.January, 31, 9, 0),
+			after:   date(time.UTC, 2028, time.January, 31, 9, 0),
+			want:    date(time.UTC, 2028, time.February, 29, 9, 0),
+		},
+		{
+			name:    "BYMONTHDAY=-1 in April",
+			rule:    "FREQ=MONTHLY;BYMONTHDAY=-1",
+			dtstart: date(time.UTC, 2026, time.January, 31, 9, 0),
+			after:   date(time.UTC, 2026, time.March, 31, 9, 0),
+			want:    date(time.UTC, 2026, time.April, 30, 9, 0),
+		},
+		{
+			name:    "yearly on February 29 waits for the next leap year",
+			rule:    "FREQ=YEARLY",
+			dtstart: date(time.UTC, 2024, time.February, 29, 9, 0),
+			after:   date(time.UTC, 2024, time.February, 29, 9, 0),
+			want:    date(time.UTC, 2028, time.February, 29, 9, 0),
+		},
+		{
+			name:    "yearly on February 29 every other year",
+			rule:    "FREQ=YEARLY;INTERVAL=2",
+			dtstart: date(time.UTC, 2024, time.February, 29, 9, 0),
+			after:   date(time.UTC, 2024, time.February, 29, 9, 0),
+			want:    date(time.UTC, 2028, time.February, 29, 9, 0),
+		},
+		{
+			name:    "daily keeps its wall time across spring-forward",
+			rule:    "FREQ=DAILY",
+			dtstart: date(newYork, 2026, time.March, 7, 9, 0),
+			after:   date(newYork, 2026, time.March, 7, 9, 0),
+			want:    time.Date(2026, time.March, 8, 13, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "daily keeps its wall time across fall-back",
+			rule:    "FREQ=DAILY",
+			dtstart: date(newYork, 2026, time.October, 31, 9, 0),
+			after:   date(newYork, 2026, time.October, 31, 9, 0),
+			want:    time.Date(2026, time.November, 1, 14, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "daily wall time inside the DST gap moves past it",
+			rule:    "FREQ=DAILY",
+			dtstart: date(newYork, 2026, time.March, 7, 2, 30),
+			after:   date(newYork, 2026, time.March, 7, 2, 30),
+			want:    time.Date(2026, time.March, 8, 7, 30, 0, 0, time.UTC),
+		},
+		{
+			name:    "daily wall time returns after the DST gap",
+			rule:    "FREQ=DAILY",
+			dtstart: date(newYork, 2026, time.March, 7, 2, 30),
+			after:   time.Date(2026, time.March, 8, 7, 30, 0, 0, time.UTC),
+			want:    date(newYork, 2026, time.March, 9, 2, 30),
+		},
+		{
+			name:    "COUNT allows the last occurrence",
+			rule:    "FREQ=DAILY;COUNT=3",
+			dtstart: date(time.UTC, 2026, time.January, 1, 9, 0),
+			after:   date(time.UTC, 2026, time.January, 2, 9, 0),
+			want:    date(time.UTC, 2026, time.January, 3, 9, 0),
+		},
+		{
+			name:    "COUNT ends the series",
+			rule:    "FREQ=DAILY;COUNT=3",
+			dtstart: date(time.UTC, 2026, time.January, 1, 9, 0),
+			after:   date(time.UTC, 2026, time.January, 3, 9, 0),
+			ended:   true,
+		},
+		{
+			name:    "UNTIL with time ends the series",
+			rule:    "FREQ=DAILY;UNTIL=20260103T000000Z",
+			dtstart: date(time.UTC, 2026, time.January, 1, 9, 0),
+			after:   date(time.UTC, 2026, time.January, 2, 9, 0),
+			ended:   true,
+		},
+		{
+			name:    "UNTIL with time includes its instant",
+			rule:    "FREQ=DAILY;UNTIL=20260103T090000Z",
+			dtstart: date(time.UTC, 2026, time.January, 1, 9, 0),
+			after:   date(time.UTC, 2026, time.January, 2, 9, 0),
+			want:    date(time.UTC, 2026, time.January, 3, 9, 0),
+		},
+		{
+			name:    "date-only UNTIL includes the whole day in the zone of DTSTART",
+			rule:    "FREQ=DAILY;UNTIL=20260103",
+			dtstart: date(newYork, 2026, time.January, 1, 23, 0),
+			after:   date(newYork, 2026, time.January, 2, 23, 0),
+			want:    date(newYork, 2026, time.January, 3, 23, 0),
+		},
+		{
+			name:    "date-only UNTIL ends the series after that day",
+			rule:    "FREQ=DAILY;UNTIL=20260103",
+			dtstart: date(newYork, 2026, time.January, 1, 23, 0),
+			after:   date(newYork, 2026, time.January, 3, 23, 0),
+			ended:   true,
+		},
+		{
+			name:    "weekly on several days",
+			rule:    "FREQ=WEEKLY;BYDAY=MO,FR",
+			dtstart: date(time.UTC, 2026, time.January, 5, 9, 0),
+			after:   date(time.UTC, 2026, time.January, 5, 9, 0),
+			want:    date(time.UTC, 2026, time.January, 9, 9, 0),
+		},
+		{
+			name:    "last Friday of the month",
+			rule:    "FREQ=MONTHLY;BYDAY=-1FR",
+			dtstart: date(time.UTC, 2026, time.January, 30, 9, 0),
+			after:   date(time.UTC, 2026, time.January, 30, 9, 0),
+			want:    date(time.UTC, 2026, time.February, 27, 9, 0),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.rule, err)
+			}
+
+			got, ok := rule.Next(tt.dtstart, tt.after)
+			if tt.ended {
+				if ok {
+					t.Fatalf("Next = %v, want the series to have ended", got)
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("Next ended the series, want %v", tt.want)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("Next = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRejectsInvalidRules(t *testing.T) {
+	rules := []string{
+		"",
+		"RRULE:",
+		"FREQ",
+		"INTERVAL=2",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;INTERVAL=x",
+		"FREQ=DAILY;COUNT=0",
+		"FREQ=DAILY;FREQ=WEEKLY",
+		"FREQ=DAILY;COUNT=2;UNTIL=20260101",
+		"FREQ=DAILY;UNTIL=2026-01-01",
+		"FREQ=MONTHLY;BYMONTHDAY=0",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=MONTHLY;BYMONTHDAY=-32",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=MONTHLY;BYDAY=6MO",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=YEARLY;BYMONTHDAY=1",
+		"FREQ=DAILY;WKST=MO",
+	}
+
+	for _, rule := range rules {
+		if _, err := Parse(rule); !errors.Is(err, ErrInvalidRule) {
+			t.Errorf("Parse(%q) error = %v, want ErrInvalidRule", rule, err)
+		}
+	}
+}
+
+func TestStringRoundTrip(t *testing.T) {
+	rules := []string{
+		"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR",
+		"FREQ=MONTHLY;BYDAY=-1FR",
+		"FREQ=MONTHLY;BYMONTHDAY=-1;COUNT=5",
+		"FREQ=DAILY;UNTIL=20260103",
+		"FREQ=DAILY;UNTIL=20260103T090000Z",
+	}
+
+	for _, s := range rules {
+		rule, err := Parse(s)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", s, err)
+		}
+		if got := rule.String(); got != s {
+			t.Errorf("String() = %q, want %q", got, s)
+		}
+	}
+}
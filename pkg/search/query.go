@@ -0,0 +1,167 @@
+// Package search parses user search queries and renders highlighted
+// snippets of the matched text.
+//
+// The query syntax is a small subset of what search engines usually accept:
+// bare words must all match (as prefixes), "quoted phrases" must match
+// verbatim and words or phrases prefixed with - must not match.
+package search
+
+import (
+	"errors"
+	"html"
+	"strings"
+	"unicode"
+)
+
+var ErrEmptyQuery = errors.New("query has no positive terms")
+
+type Query struct {
+	Terms    []string
+	Phrases  []string
+	Excluded []string
+}
+
+// operators are stripped from terms so user input cannot inject MySQL boolean
+// mode syntax.
+const operators = `+-<>()~*"@`
+
+func clean(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(operators, r) {
+			return ' '
+		}
+		return r
+	}, s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// Parse splits raw into terms, phrases and exclusions. It returns
+// ErrEmptyQuery when nothing is left to match positively.
+func Parse(raw string) (Query, error) {
+	var q Query
+
+	for raw = strings.TrimSpace(raw); raw != ""; raw = strings.TrimSpace(raw) {
+		negated := false
+		if raw[0] == '-' {
+			negated = true
+			raw = raw[1:]
+		}
+
+		var token string
+		phrase := false
+		if strings.HasPrefix(raw, `"`) {
+			end := strings.Index(raw[1:], `"`)
+			if end < 0 {
+				token, raw = raw[1:], ""
+			} else {
+				token, raw = raw[1:end+1], raw[end+2:]
+			}
+			phrase = true
+		} else {
+			end := strings.IndexFunc(raw, unicode.IsSpace)
+			if end < 0 {
+				end = len(raw)
+			}
+			token, raw = raw[:end], raw[end:]
+		}
+
+		token = clean(token)
+		if token == "" {
+			continue
+		}
+
+		switch {
+		case negated:
+			q.Excluded = append(q.Excluded, token)
+		case phrase || strings.Contains(token, " "):
+			q.Phrases = append(q.Phrases, token)
+		default:
+			q.Terms = append(q.Terms, token)
+		}
+	}
+
+	if len(q.Terms) == 0 && len(q.Phrases) == 0 {
+		return q, ErrEmptyQuery
+	}
+
+	return q, nil
+}
+
+// BooleanMode renders the query for MySQL's MATCH ... AGAINST (... IN BOOLEAN MODE).
+func (q Query) BooleanMode() string {
+	var parts []string
+	for _, term := range q.Terms {
+		parts = append(parts, "+"+term+"*")
+	}
+	for _, phrase := range q.Phrases {
+		parts = append(parts, `+"`+phrase+`"`)
+	}
+	for _, excluded := range q.Excluded {
+		if strings.Contains(excluded, " ") {
+			excluded = `"` + excluded + `"`
+		}
+		parts = append(parts, "-"+excluded)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Snippet returns an HTML-escaped excerpt of text of about maxLen runes around
+// the first match, with every match wrapped in <mark></mark>.
+func (q Query) Snippet(text string, maxLen int) string {
+	runes := []rune(text)
+	lower := []rune(strings.ToLower(text))
+
+	// case folding can change the length of some runes; give up highlighting then
+	if len(lower) != len(runes) {
+		lower = runes
+	}
+
+	type span struct{ start, end int }
+	var spans []span
+	for _, needle := range append(append([]string{}, q.Phrases...), q.Terms...) {
+		n := []rune(strings.ToLower(needle))
+		for i := 0; i+len(n) <= len(lower); i++ {
+			if string(lower[i:i+len(n)]) == string(n) {
+				spans = append(spans, span{i, i + len(n)})
+				i += len(n) - 1
+			}
+		}
+	}
+
+	marked := make([]bool, len(runes))
+	first := len(runes)
+	for _, s := range spans {
+		for i := s.start; i < s.end; i++ {
+			marked[i] = true
+		}
+		first = min(first, s.start)
+	}
+	if first == len(runes) {
+		first = 0
+	}
+
+	start := max(0, first-maxLen/4)
+	end := min(len(runes), start+maxLen)
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	for i := start; i < end; {
+		j := i
+		for j < end && marked[j] == marked[i] {
+			j++
+		}
+		chunk := html.EscapeString(string(runes[i:j]))
+		if marked[i] {
+			chunk = "<mark>" + chunk + "</mark>"
+		}
+		b.WriteString(chunk)
+		i = j
+	}
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+
+	return b.String()
+}
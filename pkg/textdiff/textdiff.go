@@ -0,0 +1,110 @@
+// Package textdiff compares texts line by line.
+package textdiff
+
+import "strings"
+
+type Op string
+
+const (
+	Equal  Op = "equal"
+	Insert Op = "insert"
+	Delete Op = "delete"
+)
+
+// Line is a line of either text, and whether it is in both, only in the new
+// text or only in the old one.
+type Line struct {
+	Op   Op     `json:"op"`
+	Text string `json:"text"`
+}
+
+// maxCells bounds the table of the longest common subsequence. Texts that
+// would need a larger one are reported as entirely replaced.
+const maxCells = 4_000_000
+
+func split(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+// Lines returns the edit from a to b as a shortest sequence of kept, deleted
+// and inserted lines.
+func Lines(a, b string) []Line {
+	from, to := split(a), split(b)
+
+	// common ends need no table
+	prefix := 0
+	for prefix < len(from) && prefix < len(to) && from[prefix] == to[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(from)-prefix && suffix < len(to)-prefix && from[len(from)-1-suffix] == to[len(to)-1-suffix] {
+		suffix++
+	}
+
+	lines := make([]Line, 0, len(from)+len(to))
+	for _, text := range from[:prefix] {
+		lines = append(lines, Line{Equal, text})
+	}
+	lines = append(lines, middle(from[prefix:len(from)-suffix], to[prefix:len(to)-suffix])...)
+	for _, text := range from[len(from)-suffix:] {
+		lines = append(lines, Line{Equal, text})
+	}
+
+	return lines
+}
+
+func middle(from, to []string) []Line {
+	var lines []Line
+	if (len(from)+1)*(len(to)+1) > maxCells {
+		for _, text := range from {
+			lines = append(lines, Line{Delete, text})
+		}
+		for _, text := range to {
+			lines = append(lines, Line{Insert, text})
+		}
+		return lines
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of from[i:]
+	// and to[j:]
+	lcs := make([][]int, len(from)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(to)+1)
+	}
+	for i := len(from) - 1; i >= 0; i-- {
+		for j := len(to) - 1; j >= 0; j-- {
+			if from[i] == to[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(from) && j < len(to) {
+		switch {
+		case from[i] == to[j]:
+			lines = append(lines, Line{Equal, from[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, Line{Delete, from[i]})
+			i++
+		default:
+			lines = append(lines, Line{Insert, to[j]})
+			j++
+		}
+	}
+	for ; i < len(from); i++ {
+		lines = append(lines, Line{Delete, from[i]})
+	}
+	for ; j < len(to); j++ {
+		lines = append(lines, Line{Insert, to[j]})
+	}
+
+	return lines
+}
@@ -0,0 +1,98 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA1 key of the RFC 6238 test vectors,
+// "12345678901234567890", in base32.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCodeRFC6238(t *testing.T) {
+	// the RFC lists 8 digit codes; 6 digit codes are their last six digits
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		at := time.Unix(tt.unix, 0).UTC()
+
+		code, err := Code(rfcSecret, at)
+		if err != nil {
+			t.Fatalf("Code at %d: %v", tt.unix, err)
+		}
+		if code != tt.want {
+			t.Errorf("Code at %d = %s, want %s", tt.unix, code, tt.want)
+		}
+
+		step, ok := Validate(rfcSecret, tt.want, at, 0)
+		if !ok || step != Step(at) {
+			t.Errorf("Validate at %d = %d, %t, want %d, true", tt.unix, step, ok, Step(at))
+		}
+	}
+}
+
+func TestValidateSkew(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+
+	tests := []struct {
+		name   string
+		offset int
+		ok     bool
+	}{
+		{"two periods early", -2, false},
+		{"one period early", -1, true},
+		{"current period", 0, true},
+		{"one period late", 1, true},
+		{"two periods late", 2, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at := now.Add(time.Duration(tt.offset) * Period)
+			code, err := Code(rfcSecret, at)
+			if err != nil {
+				t.Fatalf("Code: %v", err)
+			}
+
+			step, ok := Validate(rfcSecret, code, now, 1)
+			if ok != tt.ok {
+				t.Fatalf("Validate = %t, want %t", ok, tt.ok)
+			}
+			if ok && step != Step(at) {
+				t.Errorf("Validate step = %d, want %d", step, Step(at))
+			}
+		})
+	}
+}
+
+func TestValidateRejectsMalformedInput(t *testing.T) {
+	now := time.Unix(59, 0)
+
+	tests := []struct {
+		name   string
+		secret string
+		code   string
+	}{
+		{"short code", rfcSecret, "28708"},
+		{"long code", rfcSecret, "2870820"},
+		{"wrong code", rfcSecret, "287083"},
+		{"invalid secret", "not base32!", "287082"},
+		{"empty secret", "", "287082"},
+	}
+
+	for _, tt := range tests {
+		if _, ok := Validate(tt.secret, tt.code, now, 1); ok {
+			t.Errorf("%s: Validate accepted %q", tt.name, tt.code)
+		}
+	}
+}
@@ -17,6 +17,7 @@ type SuccessResponse struct {
 	Success bool   `json:"success"`
 	Message string `json:"message,omitempty"`
 	Data    any    `json:"data,omitempty"`
+	Meta    any    `json:"meta,omitempty"`
 }
 
 type ErrorResponse struct {
@@ -44,6 +45,10 @@ func ReadJSONRequest(w http.ResponseWriter, r *http.Request, data any) error {
 }
 
 func RespondSuccess(w http.ResponseWriter, status int, message string, data any, headers ...http.Header) {
+	RespondSuccessWithMeta(w, status, message, data, nil, headers...)
+}
+
+func RespondSuccessWithMeta(w http.ResponseWriter, status int, message string, data any, meta any, headers ...http.Header) {
 	if len(headers) > 0 {
 		for key, val := range headers[0] {
 			w.Header()[key] = val
@@ -56,6 +61,7 @@ func RespondSuccess(w http.ResponseWriter, status int, message string, data any,
 		Success: true,
 		Message: message,
 		Data:    data,
+		Meta:    meta,
 	})
 }
 
@@ -1,34 +1,101 @@
 package utils
 
 import (
-	"fmt"
-	"os"
+	"errors"
+	"strconv"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var jwtSecret = []byte(os.Getenv("JWT_SECRET"))
+const AccessTokenTTL = 2 * time.Hour
 
-func NewToken(username string, userID int) (accessToken string, err error) {
-	accessClaims := jwt.MapClaims{
-		"userID":   userID,
-		"username": username,
-		"exp":      time.Now().Add(2 * time.Hour).Unix(),
+// jwtLeeway absorbs clock skew between this service and the ones verifying
+// its tokens.
+const jwtLeeway = 30 * time.Second
+
+var ErrInvalidToken = errors.New("invalid token")
+
+// AccessClaims are the claims of a parsed access token. ID is the jti used to
+// revoke the token before it expires. Role is the user's role and Generation
+// the user's token generation when the token was issued.
+type AccessClaims struct {
+	UserID     int
+	Role       string
+	Generation int
+	ID         string
+	ExpiresAt  time.Time
+}
+
+type accessTokenClaims struct {
+	UserID     int    `json:"userID"`
+	Username   string `json:"username"`
+	Role       string `json:"role,omitempty"`
+	Generation int    `json:"gen,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// NewToken signs an access token with the current signing key of the set.
+func (k *JWTKeySet) NewToken(username string, userID int, role string, generation int) (accessToken string, err error) {
+	jti, err := RandomString(16)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := accessTokenClaims{
+		UserID:     userID,
+		Username:   username,
+		Role:       role,
+		Generation: generation,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Subject:   strconv.Itoa(userID),
+			Issuer:    k.Issuer,
+			Audience:  jwt.ClaimStrings{k.Audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(AccessTokenTTL)),
+		},
 	}
-	at := jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims)
-	accessToken, err = at.SignedString(jwtSecret)
-	return
+
+	at := jwt.NewWithClaims(k.signing.method, claims)
+	at.Header["kid"] = k.signing.ID
+	return at.SignedString(k.signing.private)
 }
 
-func ParseJWT(tokenStr string) (int, error) {
-	token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
-	})
+// ParseJWT verifies an access token against the key named by its kid, which
+// must be in the set and match the token's alg, and checks its iss, aud and
+// exp claims.
+func (k *JWTKeySet) ParseJWT(tokenStr string) (*AccessClaims, error) {
+	var claims accessTokenClaims
+	token, err := jwt.ParseWithClaims(tokenStr, &claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, ok := k.keys[kid]
+		if !ok {
+			return nil, ErrInvalidToken
+		}
+		if t.Method.Alg() != key.method.Alg() {
+			return nil, ErrInvalidToken
+		}
+		return key.public, nil
+	},
+		jwt.WithValidMethods(supportedJWTAlgs),
+		jwt.WithIssuer(k.Issuer),
+		jwt.WithAudience(k.Audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(jwtLeeway),
+	)
 
-	if err != nil || !token.Valid {
-		return 0, fmt.Errorf("invalid token")
+	if err != nil || !token.Valid || claims.UserID == 0 {
+		return nil, ErrInvalidToken
 	}
-	claims := token.Claims.(jwt.MapClaims)
-	return int(claims["userID"].(float64)), nil
+
+	return &AccessClaims{
+		UserID:     claims.UserID,
+		Role:       claims.Role,
+		Generation: claims.Generation,
+		ID:         claims.ID,
+		ExpiresAt:  claims.ExpiresAt.Time,
+	}, nil
 }
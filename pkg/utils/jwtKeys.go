@@ -0,0 +1,199 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const minRSAKeyBits = 2048
+
+var supportedJWTAlgs = []string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}
+
+// JWTKey is one key of a JWTKeySet. Keys without a private part only verify
+// tokens, e.g. a retired key whose tokens have not expired yet.
+type JWTKey struct {
+	ID      string
+	private crypto.Signer
+	public  crypto.PublicKey
+	method  jwt.SigningMethod
+}
+
+// NewJWTKey wraps an RSA or Ed25519 key, private or public.
+func NewJWTKey(id string, key any) (*JWTKey, error) {
+	k := &JWTKey{ID: id}
+
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		k.private, k.public = key, &key.PublicKey
+	case *rsa.PublicKey:
+		k.public = key
+	case ed25519.PrivateKey:
+		k.private, k.public = key, key.Public()
+	case ed25519.PublicKey:
+		k.public = key
+	default:
+		return nil, fmt.Errorf("jwt key %q: unsupported key type %T", id, key)
+	}
+
+	switch public := k.public.(type) {
+	case *rsa.PublicKey:
+		if public.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("jwt key %q: rsa keys need at least %d bits", id, minRSAKeyBits)
+		}
+		k.method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		k.method = jwt.SigningMethodEdDSA
+	}
+
+	return k, nil
+}
+
+// GenerateJWTKey returns a new Ed25519 key.
+func GenerateJWTKey(id string) (*JWTKey, error) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return NewJWTKey(id, private)
+}
+
+// JWTKeySet signs access tokens with one key and accepts tokens signed by
+// any of its keys. To rotate, add the new key, make it the signing key and
+// drop the old one once AccessTokenTTL has passed.
+type JWTKeySet struct {
+	Issuer   string
+	Audience string
+	keys     map[string]*JWTKey
+	signing  *JWTKey
+}
+
+func NewJWTKeySet(issuer, audience, signingKID string, keys ...*JWTKey) (*JWTKeySet, error) {
+	set := &JWTKeySet{Issuer: issuer, Audience: audience, keys: map[string]*JWTKey{}}
+
+	for _, key := range keys {
+		if _, ok := set.keys[key.ID]; ok {
+			return nil, fmt.Errorf("duplicate jwt key %q", key.ID)
+		}
+		set.keys[key.ID] = key
+	}
+
+	signing, ok := set.keys[signingKID]
+	if !ok {
+		return nil, fmt.Errorf("signing jwt key %q not found", signingKID)
+	}
+	if signing.private == nil {
+		return nil, fmt.Errorf("signing jwt key %q has no private key", signingKID)
+	}
+	set.signing = signing
+
+	return set, nil
+}
+
+// LoadJWTKeys reads every .pem file in dir as a key named after the file.
+// Files may hold PKCS#8 or PKCS#1 private keys, or PKIX public keys.
+func LoadJWTKeys(dir string) ([]*JWTKey, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	keys := make([]*JWTKey, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		id := strings.TrimSuffix(filepath.Base(path), ".pem")
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("jwt key %q: no pem data", id)
+		}
+
+		var parsed any
+		switch block.Type {
+		case "PRIVATE KEY":
+			parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		case "RSA PRIVATE KEY":
+			parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "PUBLIC KEY":
+			parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+		default:
+			err = fmt.Errorf("unsupported pem block %q", block.Type)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %q: %w", id, err)
+		}
+
+		key, err := NewJWTKey(id, parsed)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// JWK is a public key in the JSON Web Key format of RFC 7517.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public part of every key, so other services can verify
+// tokens signed by any of them.
+func (k *JWTKeySet) JWKS() JWKS {
+	ids := make([]string, 0, len(k.keys))
+	for id := range k.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	jwks := JWKS{Keys: make([]JWK, 0, len(ids))}
+	for _, id := range ids {
+		key := k.keys[id]
+		jwk := JWK{Kid: id, Use: "sig", Alg: key.method.Alg()}
+
+		switch public := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		}
+
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	return jwks
+}